@@ -8,15 +8,27 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/mexirica/chi-template/internal/cache"
 	"github.com/mexirica/chi-template/internal/configs"
 	"github.com/mexirica/chi-template/internal/db"
 	"github.com/mexirica/chi-template/internal/o11y"
+	"github.com/mexirica/chi-template/internal/pagination"
+	"github.com/mexirica/chi-template/internal/ratelimit"
 	rc "github.com/mexirica/chi-template/internal/redis"
 	"github.com/mexirica/chi-template/internal/server"
+	"github.com/mexirica/chi-template/internal/session"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
 
+const (
+	// localCacheSize bounds the in-process cache.
+	localCacheSize = 10_000
+	// localCacheTTL bounds how long the in-process tier of the tiered cache serves an entry
+	// that another replica may have invalidated.
+	localCacheTTL = 30 * time.Second
+)
+
 // @title Swagger Example API
 // @version 1.0
 // @description This is a sample server.
@@ -31,6 +43,11 @@ import (
 
 // @host localhost:8080
 // @BasePath /
+
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description JWT access token, sent as "Bearer <token>".
 func main() {
 	ctx := context.Background()
 	log.Logger = zerolog.New(os.Stdout).With().Timestamp().Logger()
@@ -44,6 +61,8 @@ func main() {
 		return
 	}
 
+	pagination.Init(cfg.CURSOR_SECRET)
+
 	dburl := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable", cfg.DB_HOST, cfg.DB_PORT, cfg.DB_USER, cfg.DB_PASSWORD, cfg.DB_NAME)
 
 	dbConn, err := db.Connect(dburl)
@@ -54,15 +73,27 @@ func main() {
 
 	defer dbConn.Close()
 
-	fmt.Printf("redis %s:%s\n", cfg.REDIS_HOST, cfg.REDIS_PORT)
-	redisClient, err := rc.InitRedisClient(cfg.REDIS_HOST, cfg.REDIS_PORT)
-	if err != nil {
-		log.Error().Msg(fmt.Sprintf("Error connecting to Redis: %v", err))
+	var appCache cache.Cache = cache.NewMemory(localCacheSize)
+	var sessions session.Store = session.NewMemory()
+	var limiter ratelimit.Limiter = ratelimit.NewMemory(localCacheSize)
+	if cfg.CACHE_BACKEND != "memory" {
+		fmt.Printf("redis %s:%s\n", cfg.REDIS_HOST, cfg.REDIS_PORT)
+		redisClient, err := rc.InitRedisClient(cfg.REDIS_HOST, cfg.REDIS_PORT)
+		if err != nil {
+			log.Warn().Err(err).Msg("Redis is unavailable; it will be used once it is back")
+		}
+		store := rc.NewStore(redisClient)
+		defer store.Close()
+
+		appCache = store
+		sessions = rc.NewSessionStore(store)
+		limiter = ratelimit.WithFallback(rc.NewLimiter(store), limiter)
+		if cfg.CACHE_BACKEND != "redis" {
+			appCache = cache.NewTiered(cache.NewMemory(localCacheSize), store, localCacheTTL)
+		}
 	}
 
-	defer redisClient.Close()
-
-	app := server.New(&cfg, redisClient, dbConn)
+	app := server.New(&cfg, appCache, sessions, limiter, dbConn)
 
 	idleConnsClosed := make(chan struct{})
 	go func() {
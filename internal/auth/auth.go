@@ -0,0 +1,246 @@
+// Package auth authenticates API callers with JWT bearer tokens.
+//
+// Tokens are verified by a Verifier: RS256 and ES256 tokens against the public keys of a
+// JWKS, HS256 tokens against a shared secret. Behind an authenticating gateway, callers can
+// instead be identified by a header the gateway sets. The claims of the caller are stored in
+// the request context, where handlers read them with ClaimsFromContext.
+//
+// The package also serves the API's own user accounts: their passwords are hashed with
+// HashPassword and their access tokens issued by a Signer.
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/mexirica/chi-template/internal/errs"
+	"github.com/mexirica/chi-template/internal/helpers"
+)
+
+// Claims are the claims of a verified token.
+type Claims struct {
+	jwt.RegisteredClaims
+	// Roles are the roles the issuer granted to the subject.
+	Roles []string `json:"roles,omitempty"`
+	// Permissions are granted to the caller directly rather than through roles, as the
+	// scopes of an API key are. Tokens cannot carry them.
+	Permissions []string `json:"-"`
+	// FromGateway is set when the caller was identified by the gateway header rather than by
+	// credentials it presented itself.
+	FromGateway bool `json:"-"`
+}
+
+type claimsKey struct{}
+
+// WithClaims returns a copy of ctx carrying the claims.
+func WithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+// ClaimsFromContext returns the claims of the authenticated caller, if any.
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(*Claims)
+	return claims, ok
+}
+
+// Verifier verifies bearer tokens.
+type Verifier struct {
+	keys     *KeySet
+	secret   []byte
+	issuer   string
+	audience string
+	leeway   time.Duration
+}
+
+// Option configures a Verifier.
+type Option func(*Verifier)
+
+// WithKeySet accepts RS256 and ES256 tokens signed with a key of the set.
+func WithKeySet(keys *KeySet) Option {
+	return func(v *Verifier) {
+		v.keys = keys
+	}
+}
+
+// WithHMACSecret accepts HS256 tokens signed with the secret. An empty secret is ignored.
+func WithHMACSecret(secret []byte) Option {
+	return func(v *Verifier) {
+		if len(secret) > 0 {
+			v.secret = secret
+		}
+	}
+}
+
+// WithAudience requires tokens to be issued for the audience. An empty audience is not
+// checked.
+func WithAudience(audience string) Option {
+	return func(v *Verifier) {
+		v.audience = audience
+	}
+}
+
+// WithLeeway tolerates clock skew between the issuer and this server when checking the
+// time-based claims.
+func WithLeeway(leeway time.Duration) Option {
+	return func(v *Verifier) {
+		v.leeway = leeway
+	}
+}
+
+// NewVerifier returns a verifier of tokens issued by issuer. Without a key set or a secret,
+// every token is rejected.
+func NewVerifier(issuer string, opts ...Option) *Verifier {
+	v := &Verifier{issuer: issuer}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
+}
+
+// methods returns the signing algorithms the verifier has keys for.
+func (v *Verifier) methods() []string {
+	var methods []string
+	if v.keys != nil {
+		methods = append(methods, jwt.SigningMethodRS256.Alg(), jwt.SigningMethodES256.Alg())
+	}
+	if v.secret != nil {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	return methods
+}
+
+// Verify checks the token's signature, issuer, audience and lifetime and returns its claims.
+// Tokens must expire.
+func (v *Verifier) Verify(ctx context.Context, token string) (*Claims, error) {
+	methods := v.methods()
+	if len(methods) == 0 {
+		return nil, errors.New("no verification keys are configured")
+	}
+
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(methods),
+		jwt.WithIssuer(v.issuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(v.leeway),
+	}
+	if v.audience != "" {
+		opts = append(opts, jwt.WithAudience(v.audience))
+	}
+
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (any, error) {
+		return v.keyFor(ctx, t)
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// keyFor returns the key that must have signed the token.
+func (v *Verifier) keyFor(ctx context.Context, t *jwt.Token) (any, error) {
+	if _, ok := t.Method.(*jwt.SigningMethodHMAC); ok {
+		return v.secret, nil
+	}
+
+	kid, _ := t.Header["kid"].(string)
+	key, err := v.keys.key(ctx, kid)
+	if err != nil {
+		return nil, err
+	}
+	if key.alg != "" && key.alg != t.Method.Alg() {
+		return nil, fmt.Errorf("key %q is not meant for %s", kid, t.Method.Alg())
+	}
+	switch key.key.(type) {
+	case *rsa.PublicKey:
+		if _, ok := t.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("key %q is an RSA key", kid)
+		}
+	case *ecdsa.PublicKey:
+		if _, ok := t.Method.(*jwt.SigningMethodECDSA); !ok {
+			return nil, fmt.Errorf("key %q is an EC key", kid)
+		}
+	}
+	return key.key, nil
+}
+
+// Authenticate identifies the caller and stores its claims in the request context. Callers
+// are identified by a bearer token in the Authorization header (RFC 6750) or, when
+// trustedHeader is not empty, by the subject a gateway sets in that header. The header is
+// only read from requests sent directly by one of gatewayAddrs, so that clients reaching the
+// server by another way cannot set it. Anonymous requests go through without claims, while
+// requests with an invalid token get 401 Unauthorized.
+func Authenticate(v *Verifier, trustedHeader string, gatewayAddrs []netip.Prefix) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := bearerToken(r)
+			if !ok {
+				if subject := headerSubject(r, trustedHeader, gatewayAddrs); subject != "" {
+					claims := &Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: subject}, FromGateway: true}
+					r = r.WithContext(WithClaims(r.Context(), claims))
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			claims, err := v.Verify(r.Context(), token)
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				helpers.ErrorJSON(w, r, errs.Wrap(errs.ErrUnauthorized, err, "the bearer token is invalid"))
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(WithClaims(r.Context(), claims)))
+		})
+	}
+}
+
+// Unauthenticated answers a request that needs credentials it does not carry with 401
+// Unauthorized and a WWW-Authenticate challenge.
+func Unauthenticated(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	helpers.ErrorJSON(w, r, errs.Unauthorized("authentication is required"))
+}
+
+// headerSubject returns the subject set in header by a gateway, if the request comes from
+// one.
+func headerSubject(r *http.Request, header string, gatewayAddrs []netip.Prefix) string {
+	if header == "" || !fromAny(r, gatewayAddrs) {
+		return ""
+	}
+	return strings.TrimSpace(r.Header.Get(header))
+}
+
+// fromAny reports whether the peer of the request is in one of prefixes.
+func fromAny(r *http.Request, prefixes []netip.Prefix) bool {
+	addrPort, err := netip.ParseAddrPort(r.RemoteAddr)
+	if err != nil {
+		return false
+	}
+	addr := addrPort.Addr().Unmap()
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// bearerToken returns the token of an "Authorization: Bearer" header.
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
@@ -0,0 +1,425 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/mexirica/chi-template/internal/auth"
+)
+
+const issuer = "https://issuer.example.com"
+
+func rsaJWK(t *testing.T, kid string, key *rsa.PrivateKey) map[string]string {
+	t.Helper()
+	return map[string]string{
+		"kty": "RSA",
+		"kid": kid,
+		"use": "sig",
+		"alg": "RS256",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func ecJWK(t *testing.T, kid string, key *ecdsa.PrivateKey) map[string]string {
+	t.Helper()
+	return map[string]string{
+		"kty": "EC",
+		"kid": kid,
+		"crv": "P-256",
+		"x":   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+		"y":   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+	}
+}
+
+func jwks(t *testing.T, keys ...map[string]string) []byte {
+	t.Helper()
+	data, err := json.Marshal(map[string]any{"keys": keys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func validClaims() jwt.RegisteredClaims {
+	now := time.Now()
+	return jwt.RegisteredClaims{
+		Issuer:    issuer,
+		Subject:   "user-1",
+		Audience:  jwt.ClaimStrings{"movies-api"},
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+	}
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key any, claims jwt.Claims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+// jwksServer serves the current document and counts the downloads.
+type jwksServer struct {
+	*httptest.Server
+	mu        sync.Mutex
+	doc       []byte
+	downloads atomic.Int32
+}
+
+func newJWKSServer(t *testing.T, doc []byte) *jwksServer {
+	s := &jwksServer{doc: doc}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.downloads.Add(1)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		_, _ = w.Write(s.doc)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *jwksServer) publish(doc []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.doc = doc
+}
+
+func TestVerifier_RS256FromURL(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := newJWKSServer(t, jwks(t, rsaJWK(t, "rsa-1", key)))
+
+	v := auth.NewVerifier(issuer,
+		auth.WithKeySet(auth.NewRemoteKeySet(srv.URL, time.Hour)),
+		auth.WithAudience("movies-api"),
+	)
+
+	claims, err := v.Verify(context.Background(), sign(t, jwt.SigningMethodRS256, "rsa-1", key, validClaims()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "user-1" {
+		t.Errorf("subject = %q, want user-1", claims.Subject)
+	}
+
+	_, err = v.Verify(context.Background(), sign(t, jwt.SigningMethodRS256, "rsa-1", key, validClaims()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := srv.downloads.Load(); n != 1 {
+		t.Errorf("JWKS downloaded %d times, want 1: keys are cached", n)
+	}
+}
+
+func TestVerifier_ES256FromFile(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, jwks(t, ecJWK(t, "ec-1", key)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	v := auth.NewVerifier(issuer, auth.WithKeySet(auth.NewFileKeySet(path, time.Hour)))
+
+	claims, err := v.Verify(context.Background(), sign(t, jwt.SigningMethodES256, "ec-1", key, validClaims()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Issuer != issuer {
+		t.Errorf("issuer = %q, want %q", claims.Issuer, issuer)
+	}
+
+	// The only key of the set also verifies tokens without a key ID.
+	_, err = v.Verify(context.Background(), sign(t, jwt.SigningMethodES256, "", key, validClaims()))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerifier_SkipsUnsupportedKeys(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unsupported := ecJWK(t, "k1", ecKey)
+	unsupported["crv"] = "secp256k1"
+	srv := newJWKSServer(t, jwks(t, unsupported, rsaJWK(t, "rsa-1", key)))
+
+	v := auth.NewVerifier(issuer, auth.WithKeySet(auth.NewRemoteKeySet(srv.URL, time.Hour)))
+
+	if _, err := v.Verify(context.Background(), sign(t, jwt.SigningMethodRS256, "rsa-1", key, validClaims())); err != nil {
+		t.Errorf("expected the supported key to verify tokens, got %v", err)
+	}
+}
+
+func TestVerifier_HS256(t *testing.T) {
+	secret := []byte("a-shared-secret-of-enough-length")
+	v := auth.NewVerifier(issuer, auth.WithHMACSecret(secret))
+
+	_, err := v.Verify(context.Background(), sign(t, jwt.SigningMethodHS256, "", secret, validClaims()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = v.Verify(context.Background(), sign(t, jwt.SigningMethodHS256, "", []byte("another-secret"), validClaims()))
+	if !errors.Is(err, jwt.ErrTokenSignatureInvalid) {
+		t.Errorf("Verify() error = %v, want %v", err, jwt.ErrTokenSignatureInvalid)
+	}
+}
+
+func TestVerifier_Rejects(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, jwks(t, rsaJWK(t, "rsa-1", key)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	v := auth.NewVerifier(issuer,
+		auth.WithKeySet(auth.NewFileKeySet(path, time.Hour)),
+		auth.WithAudience("movies-api"),
+	)
+
+	tests := []struct {
+		name   string
+		token  func() string
+		target error
+	}{
+		{"wrong issuer", func() string {
+			c := validClaims()
+			c.Issuer = "https://evil.example.com"
+			return sign(t, jwt.SigningMethodRS256, "rsa-1", key, c)
+		}, jwt.ErrTokenInvalidIssuer},
+		{"wrong audience", func() string {
+			c := validClaims()
+			c.Audience = jwt.ClaimStrings{"another-api"}
+			return sign(t, jwt.SigningMethodRS256, "rsa-1", key, c)
+		}, jwt.ErrTokenInvalidAudience},
+		{"expired", func() string {
+			c := validClaims()
+			c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+			return sign(t, jwt.SigningMethodRS256, "rsa-1", key, c)
+		}, jwt.ErrTokenExpired},
+		{"without expiry", func() string {
+			c := validClaims()
+			c.ExpiresAt = nil
+			return sign(t, jwt.SigningMethodRS256, "rsa-1", key, c)
+		}, jwt.ErrTokenRequiredClaimMissing},
+		{"signed by another key", func() string {
+			return sign(t, jwt.SigningMethodRS256, "rsa-1", other, validClaims())
+		}, jwt.ErrTokenSignatureInvalid},
+		{"unknown key ID", func() string {
+			return sign(t, jwt.SigningMethodRS256, "rsa-2", key, validClaims())
+		}, auth.ErrKeyNotFound},
+		{"HS256 without a secret", func() string {
+			// Signing with the public key is the classic algorithm confusion attack.
+			return sign(t, jwt.SigningMethodHS256, "rsa-1", key.N.Bytes(), validClaims())
+		}, jwt.ErrTokenSignatureInvalid},
+		{"unsigned", func() string {
+			return sign(t, jwt.SigningMethodNone, "rsa-1", jwt.UnsafeAllowNoneSignatureType, validClaims())
+		}, jwt.ErrTokenSignatureInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := v.Verify(context.Background(), tt.token())
+			if !errors.Is(err, tt.target) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.target)
+			}
+		})
+	}
+}
+
+func TestVerifier_NoKeys(t *testing.T) {
+	v := auth.NewVerifier(issuer)
+
+	_, err := v.Verify(context.Background(), sign(t, jwt.SigningMethodHS256, "", []byte("secret"), validClaims()))
+	if err == nil {
+		t.Error("Verify() succeeded without any key configured")
+	}
+}
+
+func TestKeySet_Rotation(t *testing.T) {
+	minRefresh := auth.MinRefreshInterval
+	auth.MinRefreshInterval = 0
+	t.Cleanup(func() { auth.MinRefreshInterval = minRefresh })
+
+	oldKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := newJWKSServer(t, jwks(t, rsaJWK(t, "old", oldKey)))
+
+	v := auth.NewVerifier(issuer, auth.WithKeySet(auth.NewRemoteKeySet(srv.URL, time.Hour)))
+
+	_, err = v.Verify(context.Background(), sign(t, jwt.SigningMethodRS256, "old", oldKey, validClaims()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The issuer rotates to a new key: the unknown key ID triggers a reload.
+	srv.publish(jwks(t, ecJWK(t, "new", newKey)))
+	_, err = v.Verify(context.Background(), sign(t, jwt.SigningMethodES256, "new", newKey, validClaims()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := srv.downloads.Load(); n != 2 {
+		t.Errorf("JWKS downloaded %d times, want 2", n)
+	}
+
+	_, err = v.Verify(context.Background(), sign(t, jwt.SigningMethodRS256, "old", oldKey, validClaims()))
+	if !errors.Is(err, auth.ErrKeyNotFound) {
+		t.Errorf("Verify() error = %v, want %v: retired keys are dropped", err, auth.ErrKeyNotFound)
+	}
+}
+
+func TestKeySet_KeepsKeysWhenReloadFails(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, jwks(t, rsaJWK(t, "rsa-1", key)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	v := auth.NewVerifier(issuer, auth.WithKeySet(auth.NewFileKeySet(path, time.Nanosecond)))
+	_, err = v.Verify(context.Background(), sign(t, jwt.SigningMethodRS256, "rsa-1", key, validClaims()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	_, err = v.Verify(context.Background(), sign(t, jwt.SigningMethodRS256, "rsa-1", key, validClaims()))
+	if err != nil {
+		t.Errorf("Verify() error = %v, want the previous keys to be kept", err)
+	}
+}
+
+// gateways trusts the address httptest.NewRequest sends requests from.
+var gateways = []netip.Prefix{netip.MustParsePrefix("192.0.2.0/24")}
+
+func TestAuthenticate(t *testing.T) {
+	secret := []byte("a-shared-secret-of-enough-length")
+	v := auth.NewVerifier(issuer, auth.WithHMACSecret(secret))
+
+	var subject string
+	handler := auth.Authenticate(v, "X-Authenticated-User", gateways)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if claims, ok := auth.ClaimsFromContext(r.Context()); ok {
+			subject = claims.Subject
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		name          string
+		authorization string
+		gatewayUser   string
+		status        int
+		subject       string
+		challenge     string
+	}{
+		{"valid token", "Bearer " + sign(t, jwt.SigningMethodHS256, "", secret, validClaims()), "", http.StatusNoContent, "user-1", ""},
+		{"case-insensitive scheme", "bearer " + sign(t, jwt.SigningMethodHS256, "", secret, validClaims()), "", http.StatusNoContent, "user-1", ""},
+		{"anonymous", "", "", http.StatusNoContent, "", ""},
+		{"basic credentials", "Basic dXNlcjpwYXNz", "", http.StatusNoContent, "", ""},
+		{"gateway header", "", "gateway-user", http.StatusNoContent, "gateway-user", ""},
+		{"token over gateway header", "Bearer " + sign(t, jwt.SigningMethodHS256, "", secret, validClaims()), "gateway-user", http.StatusNoContent, "user-1", ""},
+		{"invalid token", "Bearer not-a-token", "", http.StatusUnauthorized, "", `Bearer error="invalid_token"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subject = ""
+			req := httptest.NewRequest(http.MethodPost, "/movies", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			if tt.gatewayUser != "" {
+				req.Header.Set("X-Authenticated-User", tt.gatewayUser)
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if got := rec.Header().Get("WWW-Authenticate"); got != tt.challenge {
+				t.Errorf("WWW-Authenticate = %q, want %q", got, tt.challenge)
+			}
+			if subject != tt.subject {
+				t.Errorf("subject = %q, want %q", subject, tt.subject)
+			}
+		})
+	}
+}
+
+func TestAuthenticate_IgnoresGatewayHeaderWhenNotTrusted(t *testing.T) {
+	handler := auth.Authenticate(auth.NewVerifier(issuer), "", gateways)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := auth.ClaimsFromContext(r.Context()); ok {
+			t.Error("caller identified by an untrusted header")
+		}
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/movies", nil)
+	req.Header.Set("X-Authenticated-User", "gateway-user")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+func TestAuthenticate_IgnoresGatewayHeaderFromOtherAddresses(t *testing.T) {
+	handler := auth.Authenticate(auth.NewVerifier(issuer), "X-Authenticated-User", gateways)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if claims, ok := auth.ClaimsFromContext(r.Context()); ok {
+			t.Errorf("caller identified as %q by a header it set itself", claims.Subject)
+		}
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/movies", nil)
+	req.RemoteAddr = "203.0.113.7:4321"
+	req.Header.Set("X-Authenticated-User", "user:1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+}
@@ -0,0 +1,260 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/mexirica/chi-template/internal/o11y"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/sync/singleflight"
+)
+
+var (
+	// DefaultRefreshInterval is how often a key set is reloaded when no interval is given.
+	DefaultRefreshInterval = time.Hour
+	// MinRefreshInterval bounds how often a token with an unknown key ID may force a reload,
+	// so that forged key IDs cannot hammer the JWKS endpoint.
+	MinRefreshInterval = time.Minute
+	// FetchTimeout bounds a JWKS download.
+	FetchTimeout = 5 * time.Second
+)
+
+// maxJWKSSize bounds the size of a JWKS document.
+const maxJWKSSize = 1 << 20
+
+// ErrKeyNotFound is returned for a key ID the key set does not hold, even after a reload.
+var ErrKeyNotFound = errors.New("signing key not found")
+
+// publicKey is a verification key of the key set.
+type publicKey struct {
+	// alg is the algorithm the key is restricted to, if the JWK names one.
+	alg string
+	key any
+}
+
+// KeySet holds the public keys of a JSON Web Key Set (RFC 7517), loaded from a URL or a file.
+//
+// Keys are loaded on first use and reloaded every refresh interval, so that keys rotated by
+// the issuer are picked up. A token signed with a key ID the set does not know also triggers
+// a reload, at most once per MinRefreshInterval. When a reload fails the keys already loaded
+// are kept.
+type KeySet struct {
+	source  string
+	fetch   func(ctx context.Context) ([]byte, error)
+	refresh time.Duration
+	group   singleflight.Group
+
+	mu       sync.RWMutex
+	keys     map[string]publicKey
+	loadedAt time.Time
+}
+
+// NewRemoteKeySet returns a key set downloaded from url every refresh interval.
+func NewRemoteKeySet(url string, refresh time.Duration) *KeySet {
+	client := &http.Client{Timeout: FetchTimeout}
+	return newKeySet(url, refresh, func(ctx context.Context) ([]byte, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/json")
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("unexpected status %s", resp.Status)
+		}
+		return io.ReadAll(io.LimitReader(resp.Body, maxJWKSSize))
+	})
+}
+
+// NewFileKeySet returns a key set read from the file at path every refresh interval.
+func NewFileKeySet(path string, refresh time.Duration) *KeySet {
+	return newKeySet(path, refresh, func(context.Context) ([]byte, error) {
+		return os.ReadFile(path)
+	})
+}
+
+func newKeySet(source string, refresh time.Duration, fetch func(ctx context.Context) ([]byte, error)) *KeySet {
+	if refresh <= 0 {
+		refresh = DefaultRefreshInterval
+	}
+	return &KeySet{source: source, fetch: fetch, refresh: refresh}
+}
+
+// key returns the key with the given ID. An empty ID matches the only key of a set holding
+// a single key.
+func (ks *KeySet) key(ctx context.Context, kid string) (publicKey, error) {
+	ks.mu.RLock()
+	key, ok := ks.lookup(kid)
+	age := time.Since(ks.loadedAt)
+	loaded := !ks.loadedAt.IsZero()
+	ks.mu.RUnlock()
+
+	if ok && age < ks.refresh {
+		return key, nil
+	}
+	if ok || !loaded || age >= MinRefreshInterval {
+		if err := ks.load(ctx); err != nil && !loaded {
+			return publicKey{}, err
+		}
+	}
+
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	if key, ok := ks.lookup(kid); ok {
+		return key, nil
+	}
+	return publicKey{}, ErrKeyNotFound
+}
+
+func (ks *KeySet) lookup(kid string) (publicKey, bool) {
+	if kid == "" && len(ks.keys) == 1 {
+		for _, key := range ks.keys {
+			return key, true
+		}
+	}
+	key, ok := ks.keys[kid]
+	return key, ok
+}
+
+// load reloads the key set. Concurrent reloads are coalesced into one.
+func (ks *KeySet) load(ctx context.Context) error {
+	_, err, _ := ks.group.Do("load", func() (any, error) {
+		ctx, span := o11y.Tracer().Start(context.WithoutCancel(ctx), "KeySet.load")
+		defer span.End()
+
+		keys, err := ks.read(ctx)
+		ks.mu.Lock()
+		defer ks.mu.Unlock()
+		// A failed load is not retried before MinRefreshInterval either.
+		ks.loadedAt = time.Now()
+		if err != nil {
+			log.Error().Err(err).Str("source", ks.source).Msg("Error loading JWKS; keeping the previous keys")
+			return nil, err
+		}
+		ks.keys = keys
+		return nil, nil
+	})
+	return err
+}
+
+func (ks *KeySet) read(ctx context.Context) (map[string]publicKey, error) {
+	data, err := ks.fetch(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	return parseJWKS(data)
+}
+
+// jwk is the subset of a JSON Web Key needed to verify RSA and ECDSA signatures.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// parseJWKS decodes the RSA and EC signing keys of a JWKS document. Keys of other types or
+// meant for encryption are skipped, and so are keys that cannot be used, such as keys on an
+// unsupported curve, so that a provider publishing one does not lock every caller out. It
+// fails when no usable key is left.
+func parseJWKS(data []byte) (map[string]publicKey, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to decode JWKS: %w", err)
+	}
+
+	keys := make(map[string]publicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		var (
+			key any
+			err error
+		)
+		switch k.Kty {
+		case "RSA":
+			key, err = k.rsaKey()
+		case "EC":
+			key, err = k.ecKey()
+		default:
+			continue
+		}
+		if err != nil {
+			log.Warn().Err(err).Str("kid", k.Kid).Msg("Skipping unusable JWKS key")
+			continue
+		}
+		keys[k.Kid] = publicKey{alg: k.Alg, key: key}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no usable signing key in JWKS")
+	}
+	return keys, nil
+}
+
+func (k jwk) rsaKey() (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, fmt.Errorf("invalid modulus: %w", err)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exponent: %w", err)
+	}
+	exponent := new(big.Int).SetBytes(e)
+	if len(n) == 0 || !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+		return nil, errors.New("invalid RSA key")
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+}
+
+func (k jwk) ecKey() (*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+	switch k.Crv {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+	}
+	x, err := base64.RawURLEncoding.DecodeString(k.X)
+	if err != nil {
+		return nil, fmt.Errorf("invalid x coordinate: %w", err)
+	}
+	y, err := base64.RawURLEncoding.DecodeString(k.Y)
+	if err != nil {
+		return nil, fmt.Errorf("invalid y coordinate: %w", err)
+	}
+	key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+	// The conversion validates the point.
+	if _, err := key.ECDH(); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
@@ -0,0 +1,58 @@
+package auth_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mexirica/chi-template/internal/auth"
+)
+
+func TestHashPassword(t *testing.T) {
+	hash, err := auth.HashPassword("correct horse battery staple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=19456,t=2,p=1$") {
+		t.Errorf("expected an argon2id PHC string, got %q", hash)
+	}
+
+	if ok, err := auth.CheckPassword(hash, "correct horse battery staple"); err != nil || !ok {
+		t.Errorf("CheckPassword(right password) = %v, %v; want true", ok, err)
+	}
+	if ok, err := auth.CheckPassword(hash, "Correct horse battery staple"); err != nil || ok {
+		t.Errorf("CheckPassword(wrong password) = %v, %v; want false", ok, err)
+	}
+
+	if again, _ := auth.HashPassword("correct horse battery staple"); again == hash {
+		t.Error("expected every hash to have its own salt")
+	}
+}
+
+func TestCheckPassword_KeepsParametersOfHash(t *testing.T) {
+	hash, err := auth.HashPassword("hunter22")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	params := auth.Argon2Params
+	defer func() { auth.Argon2Params = params }()
+	auth.Argon2Params.Time = 3
+	if ok, err := auth.CheckPassword(hash, "hunter22"); err != nil || !ok {
+		t.Errorf("CheckPassword() = %v, %v after a parameter change; want true", ok, err)
+	}
+}
+
+func TestCheckPassword_Malformed(t *testing.T) {
+	for _, hash := range []string{
+		"",
+		"$2a$10$abcdefghijklmnopqrstuv",
+		"$argon2id$v=19$m=19456,t=0,p=1$c2FsdA$aGFzaA",
+		"$argon2id$v=18$m=19456,t=2,p=1$c2FsdA$aGFzaA",
+		"$argon2id$v=19$m=19456,t=2,p=1$!!$aGFzaA",
+	} {
+		if _, err := auth.CheckPassword(hash, "hunter22"); !errors.Is(err, auth.ErrPasswordHash) {
+			t.Errorf("CheckPassword(%q) error = %v, want %v", hash, err, auth.ErrPasswordHash)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// DefaultTokenTTL is the lifetime of access tokens when none is configured. Access tokens
+// cannot be revoked, so it is short.
+var DefaultTokenTTL = 15 * time.Minute
+
+// Signer issues the HS256 access tokens of this API's own users. A Verifier sharing the
+// issuer, audience and secret accepts them.
+type Signer struct {
+	issuer   string
+	audience string
+	secret   []byte
+	ttl      time.Duration
+}
+
+// NewSigner returns a signer of tokens valid for ttl, or DefaultTokenTTL when ttl is zero.
+// Without a secret, Sign fails.
+func NewSigner(issuer, audience string, secret []byte, ttl time.Duration) *Signer {
+	if ttl <= 0 {
+		ttl = DefaultTokenTTL
+	}
+	return &Signer{issuer: issuer, audience: audience, secret: secret, ttl: ttl}
+}
+
+// TTL is how long the tokens of the signer are valid.
+func (s *Signer) TTL() time.Duration {
+	return s.ttl
+}
+
+// Sign returns an access token for the subject.
+func (s *Signer) Sign(subject string) (string, error) {
+	if len(s.secret) == 0 {
+		return "", errors.New("no signing secret is configured")
+	}
+
+	now := time.Now()
+	claims := Claims{RegisteredClaims: jwt.RegisteredClaims{
+		Issuer:    s.issuer,
+		Subject:   subject,
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(s.ttl)),
+	}}
+	if s.audience != "" {
+		claims.Audience = jwt.ClaimStrings{s.audience}
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.secret)
+}
@@ -0,0 +1,37 @@
+package auth_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mexirica/chi-template/internal/auth"
+)
+
+func TestSigner(t *testing.T) {
+	secret := []byte("a-shared-secret-of-enough-length")
+	signer := auth.NewSigner(issuer, "movies-api", secret, time.Minute)
+
+	token, err := signer.Sign("user:42")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := auth.NewVerifier(issuer, auth.WithHMACSecret(secret), auth.WithAudience("movies-api")).Verify(context.Background(), token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "user:42" || claims.ExpiresAt.Sub(claims.IssuedAt.Time) != time.Minute {
+		t.Errorf("unexpected claims %+v", claims.RegisteredClaims)
+	}
+
+	if _, err := auth.NewVerifier(issuer, auth.WithHMACSecret(secret), auth.WithAudience("other-api")).Verify(context.Background(), token); err == nil {
+		t.Error("expected a token for another audience to be rejected")
+	}
+}
+
+func TestSigner_NoSecret(t *testing.T) {
+	if _, err := auth.NewSigner(issuer, "", nil, 0).Sign("user:42"); err == nil {
+		t.Error("expected signing without a secret to fail")
+	}
+}
@@ -0,0 +1,160 @@
+// Package authz decides what authenticated callers are allowed to do.
+//
+// Routes declare the permission they need with Require. Callers hold roles, granted by the
+// issuer of their token or bound to their subject in the role_bindings table, and a Policy
+// maps roles to permissions. API keys are granted permissions directly, as scopes. Routes
+// without Require are open to everyone, including anonymous callers.
+package authz
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/mexirica/chi-template/internal/auth"
+	"github.com/mexirica/chi-template/internal/errs"
+	"github.com/mexirica/chi-template/internal/helpers"
+	"github.com/mexirica/chi-template/internal/o11y"
+)
+
+// Permission is an action on a resource, written resource:action.
+type Permission string
+
+const (
+	MoviesCreate  Permission = "movies:create"
+	MoviesUpdate  Permission = "movies:update"
+	MoviesDelete  Permission = "movies:delete"
+	MoviesRestore Permission = "movies:restore"
+	APIKeysManage Permission = "api_keys:manage"
+)
+
+// Permissions lists every permission.
+var Permissions = []Permission{MoviesCreate, MoviesUpdate, MoviesDelete, MoviesRestore, APIKeysManage}
+
+// KeyScopes lists the permissions an API key can be granted as scopes: every one but
+// APIKeysManage, so that a key cannot create other keys.
+var KeyScopes = []Permission{MoviesCreate, MoviesUpdate, MoviesDelete, MoviesRestore}
+
+const (
+	RoleEditor = "editor"
+	RoleAdmin  = "admin"
+)
+
+// Policy maps each role to the permissions it grants.
+type Policy map[string][]Permission
+
+// DefaultPolicy lets editors create and update movies and admins delete and restore them
+// and manage API keys. A caller who needs both holds both roles.
+var DefaultPolicy = Policy{
+	RoleEditor: {MoviesCreate, MoviesUpdate},
+	RoleAdmin:  {MoviesDelete, MoviesRestore, APIKeysManage},
+}
+
+// RoleStore returns the roles bound to a subject. It is implemented by
+// repository.RoleBindingRepository.
+type RoleStore interface {
+	Roles(ctx context.Context, subject string) ([]string, error)
+}
+
+// Authorizer resolves the permissions of callers.
+type Authorizer struct {
+	policy Policy
+	store  RoleStore
+}
+
+// NewAuthorizer returns an authorizer granting permissions according to policy. The store may
+// be nil, in which case only the roles carried by tokens count.
+func NewAuthorizer(policy Policy, store RoleStore) *Authorizer {
+	return &Authorizer{policy: policy, store: store}
+}
+
+// Roles returns the roles of the caller: those in its claims and those bound to its subject.
+func (a *Authorizer) Roles(ctx context.Context, claims *auth.Claims) ([]string, error) {
+	ctx, span := o11y.Tracer().Start(ctx, "Authorizer.Roles")
+	defer span.End()
+
+	roles := append([]string(nil), claims.Roles...)
+	if a.store == nil || claims.Subject == "" {
+		return roles, nil
+	}
+	bound, err := a.store.Roles(ctx, claims.Subject)
+	if err != nil {
+		return nil, err
+	}
+	return append(roles, bound...), nil
+}
+
+// Missing returns the permissions of perms the caller does not hold. Roles are only resolved
+// when the permissions granted to the caller directly are not enough.
+func (a *Authorizer) Missing(ctx context.Context, claims *auth.Claims, perms ...Permission) ([]Permission, error) {
+	granted := map[Permission]bool{}
+	for _, perm := range claims.Permissions {
+		granted[Permission(perm)] = true
+	}
+	missing := missingFrom(granted, perms)
+	if len(missing) == 0 {
+		return nil, nil
+	}
+
+	roles, err := a.Roles(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+	for _, role := range roles {
+		for _, perm := range a.policy[role] {
+			granted[perm] = true
+		}
+	}
+	return missingFrom(granted, missing), nil
+}
+
+func missingFrom(granted map[Permission]bool, perms []Permission) []Permission {
+	var missing []Permission
+	for _, perm := range perms {
+		if !granted[perm] {
+			missing = append(missing, perm)
+		}
+	}
+	return missing
+}
+
+type authorizerKey struct{}
+
+// Middleware makes the authorizer available to the Require middlewares of the routes below.
+func (a *Authorizer) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), authorizerKey{}, a)))
+	})
+}
+
+// Require only lets callers holding every one of perms through. Anonymous callers get 401
+// Unauthorized and callers lacking a permission 403 Forbidden naming it. It must run after
+// auth.Authenticate and Authorizer.Middleware.
+func Require(perms ...Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := auth.ClaimsFromContext(r.Context())
+			if !ok {
+				auth.Unauthenticated(w, r)
+				return
+			}
+			a, ok := r.Context().Value(authorizerKey{}).(*Authorizer)
+			if !ok {
+				helpers.ErrorJSON(w, r, errors.New("authz.Require used without an Authorizer middleware"))
+				return
+			}
+
+			missing, err := a.Missing(r.Context(), claims, perms...)
+			if err != nil {
+				helpers.ErrorJSON(w, r, err)
+				return
+			}
+			if len(missing) > 0 {
+				helpers.ErrorJSON(w, r, errs.Forbidden("the %s permission is required", missing[0]))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package authz_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/golang/mock/gomock"
+	"github.com/mexirica/chi-template/internal/auth"
+	"github.com/mexirica/chi-template/internal/authz"
+	"github.com/mexirica/chi-template/internal/db/repository"
+	"github.com/mexirica/chi-template/internal/errs"
+	"github.com/mexirica/chi-template/internal/types"
+)
+
+// serve runs the request through the authorizer and Require(perm) as the caller.
+func serve(a *authz.Authorizer, perm authz.Permission, caller *auth.Claims) *httptest.ResponseRecorder {
+	handler := a.Middleware(authz.Require(perm)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})))
+
+	req := httptest.NewRequest(http.MethodDelete, "/movies/1", nil)
+	if caller != nil {
+		req = req.WithContext(auth.WithClaims(req.Context(), caller))
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func caller(subject string, roles ...string) *auth.Claims {
+	return &auth.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: subject}, Roles: roles}
+}
+
+func TestRequire_TokenRoles(t *testing.T) {
+	a := authz.NewAuthorizer(authz.DefaultPolicy, nil)
+
+	tests := []struct {
+		name   string
+		perm   authz.Permission
+		caller *auth.Claims
+		status int
+	}{
+		{"editor creates", authz.MoviesCreate, caller("u1", authz.RoleEditor), http.StatusNoContent},
+		{"editor updates", authz.MoviesUpdate, caller("u1", authz.RoleEditor), http.StatusNoContent},
+		{"editor cannot delete", authz.MoviesDelete, caller("u1", authz.RoleEditor), http.StatusForbidden},
+		{"admin deletes", authz.MoviesDelete, caller("u2", authz.RoleAdmin), http.StatusNoContent},
+		{"admin restores", authz.MoviesRestore, caller("u2", authz.RoleAdmin), http.StatusNoContent},
+		{"admin cannot create", authz.MoviesCreate, caller("u2", authz.RoleAdmin), http.StatusForbidden},
+		{"editor and admin", authz.MoviesDelete, caller("u3", authz.RoleEditor, authz.RoleAdmin), http.StatusNoContent},
+		{"unknown role", authz.MoviesCreate, caller("u4", "viewer"), http.StatusForbidden},
+		{"anonymous", authz.MoviesCreate, nil, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(a, tt.perm, tt.caller)
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+		})
+	}
+}
+
+func TestRequire_ForbiddenNamesPermission(t *testing.T) {
+	a := authz.NewAuthorizer(authz.DefaultPolicy, nil)
+
+	rec := serve(a, authz.MoviesDelete, caller("u1", authz.RoleEditor))
+
+	var problem types.Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+		t.Fatal(err)
+	}
+	if problem.Status != http.StatusForbidden || !strings.Contains(problem.Detail, string(authz.MoviesDelete)) {
+		t.Errorf("expected a 403 problem naming %s, got %+v", authz.MoviesDelete, problem)
+	}
+}
+
+func TestRequire_Unauthenticated(t *testing.T) {
+	rec := serve(authz.NewAuthorizer(authz.DefaultPolicy, nil), authz.MoviesCreate, nil)
+
+	if got := rec.Header().Get("WWW-Authenticate"); got != "Bearer" {
+		t.Errorf("WWW-Authenticate = %q, want Bearer", got)
+	}
+}
+
+func TestRequire_RoleBindings(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := repository.NewMockRoleBindingRepository(ctrl)
+	a := authz.NewAuthorizer(authz.DefaultPolicy, store)
+
+	store.EXPECT().Roles(gomock.Any(), "gateway-user").Return([]string{authz.RoleAdmin}, nil)
+	if rec := serve(a, authz.MoviesDelete, caller("gateway-user")); rec.Code != http.StatusNoContent {
+		t.Errorf("bound admin: status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+
+	// Bound roles add to the roles of the token.
+	store.EXPECT().Roles(gomock.Any(), "u1").Return([]string{authz.RoleAdmin}, nil)
+	if rec := serve(a, authz.MoviesDelete, caller("u1", authz.RoleEditor)); rec.Code != http.StatusNoContent {
+		t.Errorf("editor bound as admin: status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+
+	store.EXPECT().Roles(gomock.Any(), "nobody").Return([]string{}, nil)
+	if rec := serve(a, authz.MoviesDelete, caller("nobody")); rec.Code != http.StatusForbidden {
+		t.Errorf("unbound subject: status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
+
+func TestRequire_RoleStoreUnavailable(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := repository.NewMockRoleBindingRepository(ctrl)
+	a := authz.NewAuthorizer(authz.DefaultPolicy, store)
+
+	store.EXPECT().Roles(gomock.Any(), "u1").Return(nil, errs.Unavailable(errors.New("dial tcp"), "database unavailable"))
+
+	if rec := serve(a, authz.MoviesDelete, caller("u1", authz.RoleAdmin)); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestAuthorizer_Missing(t *testing.T) {
+	a := authz.NewAuthorizer(authz.DefaultPolicy, nil)
+
+	missing, err := a.Missing(context.Background(), caller("u1", authz.RoleEditor), authz.MoviesCreate, authz.MoviesDelete, authz.MoviesRestore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(missing) != 2 || missing[0] != authz.MoviesDelete || missing[1] != authz.MoviesRestore {
+		t.Errorf("missing = %v, want [%s %s]", missing, authz.MoviesDelete, authz.MoviesRestore)
+	}
+}
+
+func TestRequire_DirectPermissions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// The role store is only asked when the direct permissions are not enough.
+	store := repository.NewMockRoleBindingRepository(ctrl)
+	a := authz.NewAuthorizer(authz.DefaultPolicy, store)
+	key := caller("apikey:3")
+	key.Permissions = []string{string(authz.MoviesCreate)}
+
+	if rec := serve(a, authz.MoviesCreate, key); rec.Code != http.StatusNoContent {
+		t.Errorf("granted scope: status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+
+	store.EXPECT().Roles(gomock.Any(), "apikey:3").Return([]string{}, nil)
+	if rec := serve(a, authz.MoviesDelete, key); rec.Code != http.StatusForbidden {
+		t.Errorf("missing scope: status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
@@ -0,0 +1,46 @@
+// Package cache defines the cache used for HTTP responses and query results, with an
+// in-memory implementation and a two-tier composite. The Redis implementation lives in
+// internal/redis.
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrMiss is returned by Get and TTL when the key is not cached.
+var ErrMiss = errors.New("cache miss")
+
+// DefaultTTL is used by Set when the ttl is zero.
+const DefaultTTL = 24 * time.Hour
+
+// Cache stores opaque values under string keys. Keys can be indexed under tags when they are
+// set, so that every entry derived from the same data can be evicted at once.
+type Cache interface {
+	// Get returns the value of key, or ErrMiss.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Set stores value under key for ttl and indexes the key under each tag.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration, tags ...string) error
+	// Delete removes the keys.
+	Delete(ctx context.Context, keys ...string) error
+	// DeleteByTag removes every key indexed under any of the tags.
+	DeleteByTag(ctx context.Context, tags ...string) error
+	// TTL returns the remaining lifetime of key, or ErrMiss.
+	TTL(ctx context.Context, key string) (time.Duration, error)
+}
+
+// TagReader is implemented by caches that can return the tags an entry was set with, so that a
+// Tiered cache copies the entries of its second tier into the first under the same tags.
+type TagReader interface {
+	// GetTagged returns the value of key and its tags, or ErrMiss.
+	GetTagged(ctx context.Context, key string) ([]byte, []string, error)
+}
+
+// Locker is implemented by caches shared between replicas. Its locks let a single replica
+// recompute an expired entry while the others wait for it.
+type Locker interface {
+	// Lock tries to take the lock named key for at most ttl and reports whether it got it.
+	// unlock releases the lock if the caller still holds it.
+	Lock(ctx context.Context, key string, ttl time.Duration) (unlock func(), ok bool, err error)
+}
@@ -0,0 +1,123 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mexirica/chi-template/internal/cache"
+	"github.com/sony/gobreaker/v2"
+)
+
+// failingCache is an unavailable second tier, failing every command with err.
+type failingCache struct{ err error }
+
+var errDown = errors.New("connection refused")
+
+func (c failingCache) Get(context.Context, string) ([]byte, error) { return nil, c.err }
+func (c failingCache) Set(context.Context, string, []byte, time.Duration, ...string) error {
+	return c.err
+}
+func (c failingCache) Delete(context.Context, ...string) error            { return c.err }
+func (c failingCache) DeleteByTag(context.Context, ...string) error       { return c.err }
+func (c failingCache) TTL(context.Context, string) (time.Duration, error) { return 0, c.err }
+
+func TestMemory(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewMemory(2)
+
+	_ = c.Set(ctx, "a", []byte("1"), time.Minute, "movie:1")
+	_ = c.Set(ctx, "b", []byte("2"), time.Minute, "movies:list")
+	if got, err := c.Get(ctx, "a"); err != nil || string(got) != "1" {
+		t.Errorf("Get(a) = %q, %v", got, err)
+	}
+
+	// "b" is the least recently used entry.
+	_ = c.Set(ctx, "c", []byte("3"), time.Minute, "movies:list")
+	if _, err := c.Get(ctx, "b"); !errors.Is(err, cache.ErrMiss) {
+		t.Errorf("expected b to be evicted, got %v", err)
+	}
+
+	_ = c.DeleteByTag(ctx, "movies:list")
+	if _, err := c.Get(ctx, "c"); !errors.Is(err, cache.ErrMiss) {
+		t.Errorf("expected c to be invalidated, got %v", err)
+	}
+	if _, err := c.Get(ctx, "a"); err != nil {
+		t.Errorf("expected a to survive, got %v", err)
+	}
+
+	_ = c.Set(ctx, "expired", []byte("x"), time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	if _, err := c.TTL(ctx, "expired"); !errors.Is(err, cache.ErrMiss) {
+		t.Errorf("expected expired entry to miss, got %v", err)
+	}
+}
+
+func TestTiered(t *testing.T) {
+	ctx := context.Background()
+	l2 := cache.NewMemory(10)
+	c := cache.NewTiered(cache.NewMemory(10), l2, time.Minute)
+
+	// L2 hits are copied into L1 and keep being served when L2 loses them.
+	_ = l2.Set(ctx, "shared", []byte("v"), time.Hour, "movies:list")
+	if got, err := c.Get(ctx, "shared"); err != nil || string(got) != "v" {
+		t.Fatalf("Get(shared) = %q, %v", got, err)
+	}
+	_ = l2.Delete(ctx, "shared")
+	if _, err := c.Get(ctx, "shared"); err != nil {
+		t.Errorf("expected L1 to serve the entry, got %v", err)
+	}
+
+	// Invalidation clears both tiers.
+	_ = c.Set(ctx, "tagged", []byte("v"), time.Hour, "movie:1")
+	_ = c.DeleteByTag(ctx, "movie:1")
+	if _, err := c.Get(ctx, "tagged"); !errors.Is(err, cache.ErrMiss) {
+		t.Errorf("expected ErrMiss after invalidation, got %v", err)
+	}
+}
+
+func TestTiered_InvalidatesCopiesOfSecondTier(t *testing.T) {
+	ctx := context.Background()
+	l2 := cache.NewMemory(10)
+	c := cache.NewTiered(cache.NewMemory(10), l2, time.Minute)
+
+	// The entry was stored by another replica, so this one only has it in L1 after a Get.
+	_ = l2.Set(ctx, "GET./movies/1.", []byte("v1"), time.Hour, "movie:1")
+	if _, err := c.Get(ctx, "GET./movies/1."); err != nil {
+		t.Fatalf("Get = %v", err)
+	}
+	if err := c.DeleteByTag(ctx, "movie:1"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := c.Get(ctx, "GET./movies/1."); !errors.Is(err, cache.ErrMiss) {
+		t.Errorf("expected ErrMiss after invalidation, got %q, %v", got, err)
+	}
+}
+
+func TestTiered_SecondTierDown(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewTiered(cache.NewMemory(10), failingCache{errDown}, time.Minute)
+
+	if _, err := c.Get(ctx, "key"); !errors.Is(err, cache.ErrMiss) {
+		t.Errorf("expected an unavailable L2 to read as a miss, got %v", err)
+	}
+	if err := c.Set(ctx, "key", []byte("v"), time.Hour); !errors.Is(err, errDown) {
+		t.Errorf("expected the L2 error, got %v", err)
+	}
+	if got, err := c.Get(ctx, "key"); err != nil || string(got) != "v" {
+		t.Errorf("expected L1 to keep the entry, got %q, %v", got, err)
+	}
+}
+
+func TestTiered_SecondTierBreakerOpen(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewTiered(cache.NewMemory(10), failingCache{gobreaker.ErrOpenState}, time.Minute)
+
+	if err := c.Set(ctx, "key", []byte("v"), time.Hour); err != nil {
+		t.Errorf("expected the write to succeed in L1 while the breaker is open, got %v", err)
+	}
+	if got, err := c.Get(ctx, "key"); err != nil || string(got) != "v" {
+		t.Errorf("expected L1 to keep the entry, got %q, %v", got, err)
+	}
+}
@@ -0,0 +1,129 @@
+package cache
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+)
+
+type memoryEntry struct {
+	value   []byte
+	expires time.Time
+	tags    []string
+}
+
+// Memory is an in-process Cache bounded to a number of entries, evicting the least recently
+// used. It is meant for tests, local development and as the first tier of a Tiered cache.
+type Memory struct {
+	lru *lru.Cache[string, memoryEntry]
+
+	// locks maps each held lock to its expiry. Locks live outside the LRU so that they are
+	// never evicted while held.
+	locksMu sync.Mutex
+	locks   map[string]time.Time
+}
+
+var (
+	_ Cache     = (*Memory)(nil)
+	_ TagReader = (*Memory)(nil)
+	_ Locker    = (*Memory)(nil)
+)
+
+// NewMemory returns an in-memory cache holding at most size entries.
+func NewMemory(size int) *Memory {
+	c, err := lru.New[string, memoryEntry](max(size, 1))
+	if err != nil {
+		panic(err)
+	}
+	return &Memory{lru: c, locks: map[string]time.Time{}}
+}
+
+func (m *Memory) Get(_ context.Context, key string) ([]byte, error) {
+	entry, ok := m.lookup(key)
+	RecordLookup("memory", ok)
+	if !ok {
+		return nil, ErrMiss
+	}
+	return entry.value, nil
+}
+
+func (m *Memory) GetTagged(_ context.Context, key string) ([]byte, []string, error) {
+	entry, ok := m.lookup(key)
+	RecordLookup("memory", ok)
+	if !ok {
+		return nil, nil, ErrMiss
+	}
+	return entry.value, slices.Clone(entry.tags), nil
+}
+
+func (m *Memory) Set(_ context.Context, key string, value []byte, ttl time.Duration, tags ...string) error {
+	if ttl == 0 {
+		ttl = DefaultTTL
+	}
+	m.lru.Add(key, memoryEntry{value: value, expires: time.Now().Add(ttl), tags: tags})
+	return nil
+}
+
+func (m *Memory) Delete(_ context.Context, keys ...string) error {
+	for _, key := range keys {
+		m.lru.Remove(key)
+	}
+	return nil
+}
+
+func (m *Memory) DeleteByTag(_ context.Context, tags ...string) error {
+	for _, key := range m.lru.Keys() {
+		entry, ok := m.lru.Peek(key)
+		if ok && slices.ContainsFunc(entry.tags, func(tag string) bool { return slices.Contains(tags, tag) }) {
+			m.lru.Remove(key)
+		}
+	}
+	return nil
+}
+
+func (m *Memory) TTL(_ context.Context, key string) (time.Duration, error) {
+	entry, ok := m.lookup(key)
+	if !ok {
+		return 0, ErrMiss
+	}
+	return time.Until(entry.expires), nil
+}
+
+// lookup returns the live entry of key, dropping it when it has expired.
+func (m *Memory) lookup(key string) (memoryEntry, bool) {
+	entry, ok := m.lru.Get(key)
+	if !ok {
+		return memoryEntry{}, false
+	}
+	if !time.Now().Before(entry.expires) {
+		m.lru.Remove(key)
+		return memoryEntry{}, false
+	}
+	return entry, true
+}
+
+// Lock takes a process-local lock, which is enough when the cache is not shared.
+func (m *Memory) Lock(_ context.Context, key string, ttl time.Duration) (func(), bool, error) {
+	m.locksMu.Lock()
+	defer m.locksMu.Unlock()
+
+	now := time.Now()
+	if expires, ok := m.locks[key]; ok && now.Before(expires) {
+		return func() {}, false, nil
+	}
+	expires := now.Add(ttl)
+	m.locks[key] = expires
+
+	unlock := func() {
+		m.locksMu.Lock()
+		defer m.locksMu.Unlock()
+		// Only release the lock this call took, not one taken after it expired.
+		if m.locks[key].Equal(expires) {
+			delete(m.locks, key)
+		}
+	}
+	return unlock, true, nil
+}
@@ -0,0 +1,20 @@
+package cache
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var lookups = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "cache_requests_total",
+	Help: "Cache lookups by backend (memory or redis) and result (hit or miss).",
+}, []string{"backend", "result"})
+
+// RecordLookup counts a cache lookup of the named backend.
+func RecordLookup(backend string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	lookups.WithLabelValues(backend, result).Inc()
+}
@@ -0,0 +1,104 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/sony/gobreaker/v2"
+)
+
+// Tiered is a two-tier Cache: a small, short-lived in-process first tier (L1) in front of a
+// shared second tier (L2), typically Redis. L1 keeps serving recently used entries while L2
+// is unavailable. Since writes on other replicas cannot evict L1, its TTL bounds how stale
+// an entry can get. Entries copied from L2 keep their tags when L2 is a TagReader, so that
+// invalidations on this replica evict them from L1 too.
+type Tiered struct {
+	l1    Cache
+	l2    Cache
+	l1TTL time.Duration
+}
+
+var (
+	_ Cache  = (*Tiered)(nil)
+	_ Locker = (*Tiered)(nil)
+)
+
+// NewTiered returns a two-tier cache. Entries live in l1 for at most l1TTL.
+func NewTiered(l1, l2 Cache, l1TTL time.Duration) *Tiered {
+	return &Tiered{l1: l1, l2: l2, l1TTL: l1TTL}
+}
+
+func (t *Tiered) Get(ctx context.Context, key string) ([]byte, error) {
+	if value, err := t.l1.Get(ctx, key); err == nil {
+		return value, nil
+	}
+
+	var (
+		value []byte
+		tags  []string
+		err   error
+	)
+	if tagged, ok := t.l2.(TagReader); ok {
+		value, tags, err = tagged.GetTagged(ctx, key)
+	} else {
+		value, err = t.l2.Get(ctx, key)
+	}
+	if err != nil {
+		if !errors.Is(err, ErrMiss) {
+			log.Warn().Err(err).Str("key", key).Msg("second tier cache lookup failed")
+		}
+		return nil, ErrMiss
+	}
+
+	ttl := t.l1TTL
+	if remaining, err := t.l2.TTL(ctx, key); err == nil && remaining > 0 {
+		ttl = min(ttl, remaining)
+	}
+	_ = t.l1.Set(ctx, key, value, ttl, tags...)
+	return value, nil
+}
+
+// Set writes both tiers. An L2 failure is returned, but the entry is still kept in L1. While
+// the L2 circuit breaker is open the write is skipped rather than failed, as Get serves from
+// L1 meanwhile.
+func (t *Tiered) Set(ctx context.Context, key string, value []byte, ttl time.Duration, tags ...string) error {
+	if ttl == 0 {
+		ttl = DefaultTTL
+	}
+	_ = t.l1.Set(ctx, key, value, min(ttl, t.l1TTL), tags...)
+	err := t.l2.Set(ctx, key, value, ttl, tags...)
+	if errors.Is(err, gobreaker.ErrOpenState) || errors.Is(err, gobreaker.ErrTooManyRequests) {
+		return nil
+	}
+	return err
+}
+
+func (t *Tiered) Delete(ctx context.Context, keys ...string) error {
+	_ = t.l1.Delete(ctx, keys...)
+	return t.l2.Delete(ctx, keys...)
+}
+
+func (t *Tiered) DeleteByTag(ctx context.Context, tags ...string) error {
+	_ = t.l1.DeleteByTag(ctx, tags...)
+	return t.l2.DeleteByTag(ctx, tags...)
+}
+
+func (t *Tiered) TTL(ctx context.Context, key string) (time.Duration, error) {
+	if ttl, err := t.l2.TTL(ctx, key); err == nil || errors.Is(err, ErrMiss) {
+		return ttl, err
+	}
+	return t.l1.TTL(ctx, key)
+}
+
+// Lock takes the lock in the shared L2 when it is a Locker, and in L1 otherwise. A tier that
+// cannot lock at all always grants the lock.
+func (t *Tiered) Lock(ctx context.Context, key string, ttl time.Duration) (func(), bool, error) {
+	for _, tier := range []Cache{t.l2, t.l1} {
+		if locker, ok := tier.(Locker); ok {
+			return locker.Lock(ctx, key, ttl)
+		}
+	}
+	return func() {}, true, nil
+}
@@ -3,7 +3,7 @@ package configs
 
 import (
 	"fmt"
-	"os" // Importe a biblioteca os
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -20,6 +20,73 @@ type Config struct {
 	ENVIRONMENT string `mapstructure:"ENVIRONMENT"`
 	REDIS_HOST  string `mapstructure:"REDIS_HOST"`
 	REDIS_PORT  string `mapstructure:"REDIS_PORT"`
+	// CURSOR_SECRET signs the pagination cursors; it must be shared by every replica.
+	CURSOR_SECRET string `mapstructure:"CURSOR_SECRET"`
+	// COUNT_ESTIMATE_THRESHOLD is the table size above which unfiltered listings report an
+	// estimated total instead of an exact count. Zero always counts exactly.
+	COUNT_ESTIMATE_THRESHOLD int64 `mapstructure:"COUNT_ESTIMATE_THRESHOLD"`
+	// SEARCH_LANGUAGE is the text search configuration used to parse search queries. It must
+	// be the one the movies.search_vector column is generated with, english unless a migration
+	// changed it; the server refuses to start otherwise. Defaults to english.
+	SEARCH_LANGUAGE string `mapstructure:"SEARCH_LANGUAGE"`
+	// CACHE_BACKEND selects the cache: "memory", "redis" or "tiered" (in-process in front
+	// of Redis, the default). With "memory", user sessions and rate limits are kept
+	// in-process as well.
+	CACHE_BACKEND string `mapstructure:"CACHE_BACKEND"`
+	// CACHE_STALE_WHILE_REVALIDATE is how long an expired cached response is still served
+	// while it is refreshed in the background, e.g. "1m". Zero disables it.
+	CACHE_STALE_WHILE_REVALIDATE time.Duration `mapstructure:"CACHE_STALE_WHILE_REVALIDATE"`
+	// REQUIRE_IF_MATCH makes movie updates and deletes fail with 428 Precondition Required
+	// unless they send the movie's ETag in If-Match.
+	REQUIRE_IF_MATCH bool `mapstructure:"REQUIRE_IF_MATCH"`
+	// TRASH_RETENTION is how long deleted movies stay restorable before they are purged,
+	// e.g. "720h". Zero keeps them forever.
+	TRASH_RETENTION time.Duration `mapstructure:"TRASH_RETENTION"`
+	// JWT_ISSUER is the issuer (iss claim) bearer tokens must come from, and the issuer of
+	// the access tokens of users. It is required once JWKS_URL, JWKS_FILE or JWT_HS256_SECRET
+	// is set.
+	JWT_ISSUER string `mapstructure:"JWT_ISSUER"`
+	// JWT_AUDIENCE, when set, is the audience (aud claim) bearer tokens must be issued for.
+	JWT_AUDIENCE string `mapstructure:"JWT_AUDIENCE"`
+	// JWKS_URL is where the issuer publishes the keys of its RS256 and ES256 tokens.
+	// JWKS_FILE reads them from a local file instead.
+	JWKS_URL  string `mapstructure:"JWKS_URL"`
+	JWKS_FILE string `mapstructure:"JWKS_FILE"`
+	// JWKS_REFRESH_INTERVAL is how often the JWKS is reloaded to pick up rotated keys,
+	// e.g. "1h", the default.
+	JWKS_REFRESH_INTERVAL time.Duration `mapstructure:"JWKS_REFRESH_INTERVAL"`
+	// JWT_HS256_SECRET is the shared secret of HS256 tokens. Empty rejects HS256 tokens.
+	JWT_HS256_SECRET string `mapstructure:"JWT_HS256_SECRET"`
+	// AUTH_TRUSTED_HEADER, when set, is a header identifying the caller by subject, set by an
+	// authenticating gateway, e.g. "X-Authenticated-User". It is only read from requests sent
+	// by AUTH_GATEWAY_ADDRS, the addresses and CIDR prefixes of the gateways, and ignored
+	// while that is empty.
+	AUTH_TRUSTED_HEADER string   `mapstructure:"AUTH_TRUSTED_HEADER"`
+	AUTH_GATEWAY_ADDRS  []string `mapstructure:"AUTH_GATEWAY_ADDRS"`
+	// ACCESS_TOKEN_TTL is the lifetime of the access tokens issued to users at login, signed
+	// with JWT_HS256_SECRET, e.g. "15m", the default.
+	ACCESS_TOKEN_TTL time.Duration `mapstructure:"ACCESS_TOKEN_TTL"`
+	// REFRESH_TOKEN_TTL is how long a user session lasts without being refreshed, e.g.
+	// "720h", the default.
+	REFRESH_TOKEN_TTL time.Duration `mapstructure:"REFRESH_TOKEN_TTL"`
+	// LOGIN_MAX_FAILURES is the number of failed logins from an IP address that locks it out
+	// of the account for LOGIN_LOCKOUT, counted from the first failure. Defaults to 5 and
+	// "15m".
+	LOGIN_MAX_FAILURES int           `mapstructure:"LOGIN_MAX_FAILURES"`
+	LOGIN_LOCKOUT      time.Duration `mapstructure:"LOGIN_LOCKOUT"`
+	// RATE_LIMIT limits the requests of each client to the API routes, e.g. "600/1m", the
+	// default; RATE_LIMIT_AUTH limits the /auth routes, "20/1m" by default. "off" disables
+	// a limit. Authenticated clients are limited by identity, others by IP address.
+	RATE_LIMIT      string `mapstructure:"RATE_LIMIT"`
+	RATE_LIMIT_AUTH string `mapstructure:"RATE_LIMIT_AUTH"`
+	// RATE_LIMIT_IP limits the requests of each IP address to the API routes before their
+	// credentials are checked, so that guessing tokens and API keys is limited as well,
+	// e.g. "1200/1m", the default.
+	RATE_LIMIT_IP string `mapstructure:"RATE_LIMIT_IP"`
+	// TRUSTED_PROXIES lists the addresses and CIDR prefixes of the proxies whose
+	// X-Forwarded-For header is trusted to tell the client IP address, e.g.
+	// "10.0.0.0/8,127.0.0.1". Empty trusts none.
+	TRUSTED_PROXIES []string `mapstructure:"TRUSTED_PROXIES"`
 }
 
 // LoadConfig loads environment variables using viper and .env file
@@ -41,6 +108,29 @@ func LoadConfig() (config Config, err error) {
 	viper.BindEnv("ENVIRONMENT", "ENVIRONMENT")
 	viper.BindEnv("REDIS_HOST", "REDIS_HOST")
 	viper.BindEnv("REDIS_PORT", "REDIS_PORT")
+	viper.BindEnv("CURSOR_SECRET", "CURSOR_SECRET")
+	viper.BindEnv("COUNT_ESTIMATE_THRESHOLD", "COUNT_ESTIMATE_THRESHOLD")
+	viper.BindEnv("SEARCH_LANGUAGE", "SEARCH_LANGUAGE")
+	viper.BindEnv("CACHE_BACKEND", "CACHE_BACKEND")
+	viper.BindEnv("CACHE_STALE_WHILE_REVALIDATE", "CACHE_STALE_WHILE_REVALIDATE")
+	viper.BindEnv("REQUIRE_IF_MATCH", "REQUIRE_IF_MATCH")
+	viper.BindEnv("TRASH_RETENTION", "TRASH_RETENTION")
+	viper.BindEnv("JWT_ISSUER", "JWT_ISSUER")
+	viper.BindEnv("JWT_AUDIENCE", "JWT_AUDIENCE")
+	viper.BindEnv("JWKS_URL", "JWKS_URL")
+	viper.BindEnv("JWKS_FILE", "JWKS_FILE")
+	viper.BindEnv("JWKS_REFRESH_INTERVAL", "JWKS_REFRESH_INTERVAL")
+	viper.BindEnv("JWT_HS256_SECRET", "JWT_HS256_SECRET")
+	viper.BindEnv("AUTH_TRUSTED_HEADER", "AUTH_TRUSTED_HEADER")
+	viper.BindEnv("AUTH_GATEWAY_ADDRS", "AUTH_GATEWAY_ADDRS")
+	viper.BindEnv("ACCESS_TOKEN_TTL", "ACCESS_TOKEN_TTL")
+	viper.BindEnv("REFRESH_TOKEN_TTL", "REFRESH_TOKEN_TTL")
+	viper.BindEnv("LOGIN_MAX_FAILURES", "LOGIN_MAX_FAILURES")
+	viper.BindEnv("LOGIN_LOCKOUT", "LOGIN_LOCKOUT")
+	viper.BindEnv("RATE_LIMIT", "RATE_LIMIT")
+	viper.BindEnv("RATE_LIMIT_AUTH", "RATE_LIMIT_AUTH")
+	viper.BindEnv("RATE_LIMIT_IP", "RATE_LIMIT_IP")
+	viper.BindEnv("TRUSTED_PROXIES", "TRUSTED_PROXIES")
 	
 	// O Unmarshal agora usará as ligações que você acabou de criar.
 	err = viper.Unmarshal(&config)
@@ -48,10 +138,6 @@ func LoadConfig() (config Config, err error) {
 		return
 	}
 
-	// Verifica se as variáveis de ambiente foram lidas manualmente, para debug
-	fmt.Printf("Debug: REDIS_HOST do sistema: %s\n", os.Getenv("REDIS_HOST"))
-
-	fmt.Printf("Loaded config: %+v\n", config)
 	fmt.Printf("redis %s:%s\n", config.REDIS_HOST, config.REDIS_PORT)
 	return
 }
\ No newline at end of file
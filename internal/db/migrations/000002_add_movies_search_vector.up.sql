@@ -0,0 +1,12 @@
+-- Full-text search over title, director and description, weighted in that order.
+-- SEARCH_LANGUAGE must name the same text search configuration (default: english). To search
+-- in another language, regenerate the column with it in a new migration and set SEARCH_LANGUAGE
+-- to match: the server checks that they agree at startup.
+ALTER TABLE movies
+    ADD COLUMN search_vector TSVECTOR GENERATED ALWAYS AS (
+        setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+        setweight(to_tsvector('english', coalesce(director, '')), 'B') ||
+        setweight(to_tsvector('english', coalesce(description, '')), 'C')
+    ) STORED;
+
+CREATE INDEX movies_search_vector_idx ON movies USING GIN (search_vector);
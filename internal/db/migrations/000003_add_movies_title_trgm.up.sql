@@ -0,0 +1,4 @@
+-- Trigram index for typo-tolerant title autocomplete (GET /movies/suggest).
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX movies_title_trgm_idx ON movies USING GIN (title gin_trgm_ops);
@@ -0,0 +1,4 @@
+-- Validators for HTTP conditional requests. Every update bumps version and updated_at.
+ALTER TABLE movies
+    ADD COLUMN updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
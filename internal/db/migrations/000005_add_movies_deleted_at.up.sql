@@ -0,0 +1,4 @@
+-- Soft delete: deleted movies stay in the trash until the purge job removes them.
+ALTER TABLE movies ADD COLUMN deleted_at TIMESTAMPTZ;
+
+CREATE INDEX movies_deleted_at_idx ON movies (deleted_at) WHERE deleted_at IS NOT NULL;
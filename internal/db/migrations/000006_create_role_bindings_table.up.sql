@@ -0,0 +1,7 @@
+-- Roles granted to callers, identified by the subject of their token or gateway identity.
+CREATE TABLE role_bindings (
+    subject TEXT NOT NULL,
+    role TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (subject, role)
+);
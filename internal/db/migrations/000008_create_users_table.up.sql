@@ -0,0 +1,8 @@
+-- User accounts. Emails are stored lowercased, so the unique constraint ignores case.
+-- Passwords are stored as argon2id hashes in the PHC string format.
+CREATE TABLE users (
+    id BIGSERIAL PRIMARY KEY,
+    email VARCHAR(255) NOT NULL UNIQUE,
+    password_hash TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,19 @@
+-- name: CreateAPIKey :one
+INSERT INTO api_keys (
+    name, prefix, key_hash, scopes, expires_at
+) VALUES (
+    $1, $2, $3, $4, $5
+) RETURNING *;
+
+-- name: ListAPIKeys :many
+SELECT * FROM api_keys ORDER BY id;
+
+-- name: GetAPIKeyByHash :one
+SELECT * FROM api_keys WHERE key_hash = $1;
+
+-- name: RevokeAPIKey :execrows
+-- Revoking a key again keeps its first revocation time.
+UPDATE api_keys SET revoked_at = coalesce(revoked_at, now()) WHERE id = $1;
+
+-- name: TouchAPIKey :exec
+UPDATE api_keys SET last_used_at = now() WHERE id = $1;
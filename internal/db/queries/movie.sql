@@ -3,13 +3,45 @@ INSERT INTO movies (
     title, description, release_year, genre, director, rating
 ) VALUES (
     $1, $2, $3, $4, $5, $6
-) RETURNING *;
+) RETURNING id, title, description, release_year, genre, director, rating, updated_at, version, deleted_at;
 
 -- name: GetMovieByID :one
-SELECT * FROM movies WHERE id = $1;
+SELECT id, title, description, release_year, genre, director, rating, updated_at, version, deleted_at FROM movies WHERE id = $1 AND deleted_at IS NULL;
 
--- name: ListMovies :many
-SELECT * FROM movies ORDER BY id DESC LIMIT $1 OFFSET $2;
+-- name: SearchMovies :many
+-- The description is HTML-escaped before it is highlighted, so that the headline is safe HTML
+-- whose only tags are the <mark> around the matches.
+SELECT
+    m.id, m.title, m.description, m.release_year, m.genre, m.director, m.rating, m.updated_at, m.version,
+    ts_rank(m.search_vector, q.query)::float8 AS rank,
+    ts_headline(
+        sqlc.arg(config)::text::regconfig,
+        replace(replace(replace(replace(replace(coalesce(m.description, ''),
+            '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;'), '''', '&#39;'),
+        q.query,
+        'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=20, MinWords=5'
+    ) AS headline
+FROM movies m, websearch_to_tsquery(sqlc.arg(config)::text::regconfig, sqlc.arg(query)::text) AS q(query)
+WHERE m.search_vector @@ q.query AND m.deleted_at IS NULL
+ORDER BY rank DESC, m.id DESC
+LIMIT sqlc.arg(row_limit) OFFSET sqlc.arg(row_offset);
+
+-- name: SearchVectorConfigMatches :one
+-- Reports whether movies.search_vector is generated with the text search configuration.
+SELECT strpos(
+    pg_get_expr(d.adbin, d.adrelid),
+    'to_tsvector(' || quote_literal(sqlc.arg(config)::text::regconfig::text) || '::regconfig'
+) > 0 AS matches
+FROM pg_attrdef d
+JOIN pg_attribute a ON a.attrelid = d.adrelid AND a.attnum = d.adnum
+WHERE d.adrelid = 'movies'::regclass AND a.attname = 'search_vector';
+
+-- name: SuggestMovies :many
+SELECT id, title, word_similarity(sqlc.arg(prefix)::text, title)::float8 AS similarity
+FROM movies
+WHERE sqlc.arg(prefix)::text <% title AND deleted_at IS NULL
+ORDER BY similarity DESC, title
+LIMIT sqlc.arg(row_limit);
 
 -- name: UpdateMovie :one
 UPDATE movies SET
@@ -18,9 +50,51 @@ UPDATE movies SET
     release_year = $4,
     genre = $5,
     director = $6,
-    rating = $7
+    rating = $7,
+    updated_at = now(),
+    version = version + 1
 WHERE id = $1
-RETURNING *;
+  AND deleted_at IS NULL
+  AND (sqlc.narg(expected_version)::bigint IS NULL OR version = sqlc.narg(expected_version))
+RETURNING id, title, description, release_year, genre, director, rating, updated_at, version, deleted_at;
+
+-- name: DeleteMovie :execrows
+UPDATE movies SET
+    deleted_at = now(),
+    updated_at = now(),
+    version = version + 1
+WHERE id = $1
+  AND deleted_at IS NULL
+  AND (sqlc.narg(expected_version)::bigint IS NULL OR version = sqlc.narg(expected_version));
+
+-- name: ListTrashedMovies :many
+SELECT id, title, description, release_year, genre, director, rating, updated_at, version, deleted_at FROM movies
+WHERE deleted_at IS NOT NULL
+ORDER BY deleted_at DESC, id DESC
+LIMIT sqlc.arg(row_limit) OFFSET sqlc.arg(row_offset);
+
+-- name: CountTrashedMovies :one
+SELECT count(*) FROM movies WHERE deleted_at IS NOT NULL;
 
--- name: DeleteMovie :exec
-DELETE FROM movies WHERE id = $1;
\ No newline at end of file
+-- name: RestoreMovie :one
+UPDATE movies SET
+    deleted_at = NULL,
+    updated_at = now(),
+    version = version + 1
+WHERE id = $1 AND deleted_at IS NOT NULL
+RETURNING id, title, description, release_year, genre, director, rating, updated_at, version, deleted_at;
+
+-- name: PurgeMovies :execrows
+DELETE FROM movies
+WHERE id IN (
+    SELECT id FROM movies
+    WHERE deleted_at < sqlc.arg(deleted_before)::timestamptz
+    LIMIT sqlc.arg(batch_size)
+);
+
+-- name: CopyMovies :copyfrom
+INSERT INTO movies (
+    title, description, release_year, genre, director, rating
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+);
@@ -0,0 +1,2 @@
+-- name: ListRolesBySubject :many
+SELECT role FROM role_bindings WHERE subject = $1 ORDER BY role;
@@ -0,0 +1,9 @@
+-- name: CreateUser :one
+INSERT INTO users (
+    email, password_hash
+) VALUES (
+    $1, $2
+) RETURNING *;
+
+-- name: GetUserByEmail :one
+SELECT * FROM users WHERE email = $1;
@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mexirica/chi-template/internal/db/sqlc"
+	"github.com/mexirica/chi-template/internal/errs"
+	"github.com/mexirica/chi-template/internal/models"
+	"github.com/mexirica/chi-template/internal/o11y"
+)
+
+// APIKeyRepository stores the API keys of machine clients.
+type APIKeyRepository interface {
+	Create(ctx context.Context, key models.NewAPIKey) (*models.APIKey, error)
+	List(ctx context.Context) ([]models.APIKey, error)
+	// GetByHash returns the key whose hash is given, revoked and expired keys included.
+	GetByHash(ctx context.Context, hash []byte) (*models.APIKey, error)
+	Revoke(ctx context.Context, id int64) error
+	// Touch records that the key was just used.
+	Touch(ctx context.Context, id int64) error
+}
+
+type PsqlAPIKeyRepository struct {
+	sqlc.Querier
+}
+
+func NewAPIKeyRepository(conn *pgxpool.Pool) *PsqlAPIKeyRepository {
+	return &PsqlAPIKeyRepository{Querier: sqlc.New(conn)}
+}
+
+func (r *PsqlAPIKeyRepository) Create(ctx context.Context, key models.NewAPIKey) (*models.APIKey, error) {
+	ctx, span := o11y.Tracer().Start(ctx, "PsqlAPIKeyRepository.Create")
+	defer span.End()
+
+	created, err := r.CreateAPIKey(ctx, sqlc.CreateAPIKeyParams{
+		Name:      key.Name,
+		Prefix:    key.Prefix,
+		KeyHash:   key.Hash,
+		Scopes:    key.Scopes,
+		ExpiresAt: timeToPgTimestamptz(key.ExpiresAt),
+	})
+	if err != nil {
+		return nil, mapError(err)
+	}
+	return toAPIKey(created), nil
+}
+
+func (r *PsqlAPIKeyRepository) List(ctx context.Context) ([]models.APIKey, error) {
+	ctx, span := o11y.Tracer().Start(ctx, "PsqlAPIKeyRepository.List")
+	defer span.End()
+
+	rows, err := r.ListAPIKeys(ctx)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	keys := make([]models.APIKey, 0, len(rows))
+	for _, row := range rows {
+		keys = append(keys, *toAPIKey(row))
+	}
+	return keys, nil
+}
+
+func (r *PsqlAPIKeyRepository) GetByHash(ctx context.Context, hash []byte) (*models.APIKey, error) {
+	ctx, span := o11y.Tracer().Start(ctx, "PsqlAPIKeyRepository.GetByHash")
+	defer span.End()
+
+	key, err := r.GetAPIKeyByHash(ctx, hash)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, errs.NotFound("API key not found")
+	}
+	if err != nil {
+		return nil, mapError(err)
+	}
+	return toAPIKey(key), nil
+}
+
+// Revoke revokes the key. Revoking a revoked key is a no-op.
+func (r *PsqlAPIKeyRepository) Revoke(ctx context.Context, id int64) error {
+	ctx, span := o11y.Tracer().Start(ctx, "PsqlAPIKeyRepository.Revoke")
+	defer span.End()
+
+	n, err := r.RevokeAPIKey(ctx, id)
+	if err != nil {
+		return mapError(err)
+	}
+	if n == 0 {
+		return errs.NotFound("API key %d not found", id)
+	}
+	return nil
+}
+
+func (r *PsqlAPIKeyRepository) Touch(ctx context.Context, id int64) error {
+	ctx, span := o11y.Tracer().Start(ctx, "PsqlAPIKeyRepository.Touch")
+	defer span.End()
+
+	return mapError(r.TouchAPIKey(ctx, id))
+}
+
+func toAPIKey(k sqlc.ApiKey) *models.APIKey {
+	return &models.APIKey{
+		ID:         k.ID,
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		Scopes:     k.Scopes,
+		CreatedAt:  k.CreatedAt,
+		ExpiresAt:  pgTimestamptzToTime(k.ExpiresAt),
+		LastUsedAt: pgTimestamptzToTime(k.LastUsedAt),
+		RevokedAt:  pgTimestamptzToTime(k.RevokedAt),
+	}
+}
+
+func timeToPgTimestamptz(t *time.Time) pgtype.Timestamptz {
+	if t == nil {
+		return pgtype.Timestamptz{}
+	}
+	return pgtype.Timestamptz{Time: *t, Valid: true}
+}
+
+func pgTimestamptzToTime(t pgtype.Timestamptz) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/mexirica/chi-template/internal/errs"
+)
+
+// mapError translates PostgreSQL and driver errors into domain errors from the errs package.
+// Errors that have no domain meaning are returned unchanged.
+func mapError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "23505": // unique_violation
+			return errs.Wrap(errs.ErrConflict, err, "resource already exists")
+		case "23502", "23514", "22001", "22003": // not_null, check, string too long, numeric out of range
+			return errs.Wrap(errs.ErrValidation, err, "value violates a database constraint")
+		case "40001", "40P01": // serialization_failure, deadlock_detected
+			return errs.Wrap(errs.ErrConflict, err, "concurrent update, try again")
+		case "53300", "57P01", "57P02", "57P03": // too_many_connections, admin/crash shutdown, cannot_connect_now
+			return errs.Unavailable(err, "database unavailable")
+		}
+		return err
+	}
+
+	var connectErr *pgconn.ConnectError
+	if errors.As(err, &connectErr) || pgconn.Timeout(err) || errors.Is(err, context.DeadlineExceeded) {
+		return errs.Unavailable(err, "database unavailable")
+	}
+
+	return err
+}
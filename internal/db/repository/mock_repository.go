@@ -7,6 +7,7 @@ package repository
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 	models "github.com/mexirica/chi-template/internal/models"
@@ -35,12 +36,28 @@ func (m *MockMovieRepository) EXPECT() *MockMovieRepositoryMockRecorder {
 	return m.recorder
 }
 
+// BeginImport mocks base method.
+func (m *MockMovieRepository) BeginImport(ctx context.Context) (MovieImport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BeginImport", ctx)
+	ret0, _ := ret[0].(MovieImport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BeginImport indicates an expected call of BeginImport.
+func (mr *MockMovieRepositoryMockRecorder) BeginImport(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BeginImport", reflect.TypeOf((*MockMovieRepository)(nil).BeginImport), ctx)
+}
+
 // Create mocks base method.
-func (m *MockMovieRepository) Create(ctx context.Context, movie models.CreateMovieRequest) error {
+func (m *MockMovieRepository) Create(ctx context.Context, movie models.CreateMovieRequest) (*models.Movie, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Create", ctx, movie)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret0, _ := ret[0].(*models.Movie)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // Create indicates an expected call of Create.
@@ -50,17 +67,31 @@ func (mr *MockMovieRepositoryMockRecorder) Create(ctx, movie interface{}) *gomoc
 }
 
 // Delete mocks base method.
-func (m *MockMovieRepository) Delete(ctx context.Context, id int) error {
+func (m *MockMovieRepository) Delete(ctx context.Context, id int, version int64) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Delete", ctx, id)
+	ret := m.ctrl.Call(m, "Delete", ctx, id, version)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Delete indicates an expected call of Delete.
-func (mr *MockMovieRepositoryMockRecorder) Delete(ctx, id interface{}) *gomock.Call {
+func (mr *MockMovieRepositoryMockRecorder) Delete(ctx, id, version interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockMovieRepository)(nil).Delete), ctx, id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockMovieRepository)(nil).Delete), ctx, id, version)
+}
+
+// Export mocks base method.
+func (m *MockMovieRepository) Export(ctx context.Context, fn func(models.Movie) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Export", ctx, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Export indicates an expected call of Export.
+func (mr *MockMovieRepositoryMockRecorder) Export(ctx, fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Export", reflect.TypeOf((*MockMovieRepository)(nil).Export), ctx, fn)
 }
 
 // GetById mocks base method.
@@ -79,16 +110,172 @@ func (mr *MockMovieRepositoryMockRecorder) GetById(ctx, id interface{}) *gomock.
 }
 
 // GetList mocks base method.
-func (m *MockMovieRepository) GetList(ctx context.Context, page, limit int) (*models.GetMovieList, error) {
+func (m *MockMovieRepository) GetList(ctx context.Context, filter models.MovieFilter) (*models.GetMovieList, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetList", ctx, page, limit)
+	ret := m.ctrl.Call(m, "GetList", ctx, filter)
 	ret0, _ := ret[0].(*models.GetMovieList)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetList indicates an expected call of GetList.
-func (mr *MockMovieRepositoryMockRecorder) GetList(ctx, page, limit interface{}) *gomock.Call {
+func (mr *MockMovieRepositoryMockRecorder) GetList(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetList", reflect.TypeOf((*MockMovieRepository)(nil).GetList), ctx, filter)
+}
+
+// Purge mocks base method.
+func (m *MockMovieRepository) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Purge", ctx, deletedBefore)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Purge indicates an expected call of Purge.
+func (mr *MockMovieRepositoryMockRecorder) Purge(ctx, deletedBefore interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Purge", reflect.TypeOf((*MockMovieRepository)(nil).Purge), ctx, deletedBefore)
+}
+
+// Restore mocks base method.
+func (m *MockMovieRepository) Restore(ctx context.Context, id int) (*models.Movie, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Restore", ctx, id)
+	ret0, _ := ret[0].(*models.Movie)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Restore indicates an expected call of Restore.
+func (mr *MockMovieRepositoryMockRecorder) Restore(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Restore", reflect.TypeOf((*MockMovieRepository)(nil).Restore), ctx, id)
+}
+
+// Search mocks base method.
+func (m *MockMovieRepository) Search(ctx context.Context, search models.MovieSearch) (*models.MovieSearchResults, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Search", ctx, search)
+	ret0, _ := ret[0].(*models.MovieSearchResults)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Search indicates an expected call of Search.
+func (mr *MockMovieRepositoryMockRecorder) Search(ctx, search interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Search", reflect.TypeOf((*MockMovieRepository)(nil).Search), ctx, search)
+}
+
+// Suggest mocks base method.
+func (m *MockMovieRepository) Suggest(ctx context.Context, suggest models.MovieSuggest) (*models.MovieSuggestions, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Suggest", ctx, suggest)
+	ret0, _ := ret[0].(*models.MovieSuggestions)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Suggest indicates an expected call of Suggest.
+func (mr *MockMovieRepositoryMockRecorder) Suggest(ctx, suggest interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Suggest", reflect.TypeOf((*MockMovieRepository)(nil).Suggest), ctx, suggest)
+}
+
+// Trash mocks base method.
+func (m *MockMovieRepository) Trash(ctx context.Context, page, limit int) (*models.MovieTrash, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Trash", ctx, page, limit)
+	ret0, _ := ret[0].(*models.MovieTrash)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Trash indicates an expected call of Trash.
+func (mr *MockMovieRepositoryMockRecorder) Trash(ctx, page, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Trash", reflect.TypeOf((*MockMovieRepository)(nil).Trash), ctx, page, limit)
+}
+
+// Update mocks base method.
+func (m *MockMovieRepository) Update(ctx context.Context, id int, movie models.UpdateMovieRequest, version int64) (*models.Movie, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", ctx, id, movie, version)
+	ret0, _ := ret[0].(*models.Movie)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockMovieRepositoryMockRecorder) Update(ctx, id, movie, version interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockMovieRepository)(nil).Update), ctx, id, movie, version)
+}
+
+// MockMovieImport is a mock of MovieImport interface.
+type MockMovieImport struct {
+	ctrl     *gomock.Controller
+	recorder *MockMovieImportMockRecorder
+}
+
+// MockMovieImportMockRecorder is the mock recorder for MockMovieImport.
+type MockMovieImportMockRecorder struct {
+	mock *MockMovieImport
+}
+
+// NewMockMovieImport creates a new mock instance.
+func NewMockMovieImport(ctrl *gomock.Controller) *MockMovieImport {
+	mock := &MockMovieImport{ctrl: ctrl}
+	mock.recorder = &MockMovieImportMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockMovieImport) EXPECT() *MockMovieImportMockRecorder {
+	return m.recorder
+}
+
+// Commit mocks base method.
+func (m *MockMovieImport) Commit(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Commit", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Commit indicates an expected call of Commit.
+func (mr *MockMovieImportMockRecorder) Commit(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Commit", reflect.TypeOf((*MockMovieImport)(nil).Commit), ctx)
+}
+
+// Copy mocks base method.
+func (m *MockMovieImport) Copy(ctx context.Context, movies []models.CreateMovieRequest) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Copy", ctx, movies)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Copy indicates an expected call of Copy.
+func (mr *MockMovieImportMockRecorder) Copy(ctx, movies interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Copy", reflect.TypeOf((*MockMovieImport)(nil).Copy), ctx, movies)
+}
+
+// Rollback mocks base method.
+func (m *MockMovieImport) Rollback(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Rollback", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Rollback indicates an expected call of Rollback.
+func (mr *MockMovieImportMockRecorder) Rollback(ctx interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetList", reflect.TypeOf((*MockMovieRepository)(nil).GetList), ctx, page, limit)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Rollback", reflect.TypeOf((*MockMovieImport)(nil).Rollback), ctx)
 }
@@ -0,0 +1,50 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/db/repository/role_binding_repository.go
+
+// Package repository is a generated GoMock package.
+package repository
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockRoleBindingRepository is a mock of RoleBindingRepository interface.
+type MockRoleBindingRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockRoleBindingRepositoryMockRecorder
+}
+
+// MockRoleBindingRepositoryMockRecorder is the mock recorder for MockRoleBindingRepository.
+type MockRoleBindingRepositoryMockRecorder struct {
+	mock *MockRoleBindingRepository
+}
+
+// NewMockRoleBindingRepository creates a new mock instance.
+func NewMockRoleBindingRepository(ctrl *gomock.Controller) *MockRoleBindingRepository {
+	mock := &MockRoleBindingRepository{ctrl: ctrl}
+	mock.recorder = &MockRoleBindingRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRoleBindingRepository) EXPECT() *MockRoleBindingRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Roles mocks base method.
+func (m *MockRoleBindingRepository) Roles(ctx context.Context, subject string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Roles", ctx, subject)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Roles indicates an expected call of Roles.
+func (mr *MockRoleBindingRepositoryMockRecorder) Roles(ctx, subject interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Roles", reflect.TypeOf((*MockRoleBindingRepository)(nil).Roles), ctx, subject)
+}
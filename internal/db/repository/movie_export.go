@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/mexirica/chi-template/internal/models"
+	"github.com/mexirica/chi-template/internal/o11y"
+)
+
+// exportFetchSize is the number of rows fetched from the export cursor per round trip.
+const exportFetchSize = 1000
+
+// Export calls fn for every movie outside the trash in id order. Rows are read through a server-side cursor in a
+// read-only repeatable read transaction, so memory use does not grow with the table and the
+// export is a consistent snapshot. Returning an error from fn stops the export.
+func (r *PsqlMovieRepository) Export(ctx context.Context, fn func(models.Movie) error) error {
+	ctx, span := o11y.Tracer().Start(ctx, "PsqlMovieRepository.Export")
+	defer span.End()
+
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return fmt.Errorf("failed to begin export: %w", mapError(err))
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "DECLARE movie_export NO SCROLL CURSOR FOR SELECT "+movieColumns+" FROM movies WHERE "+notDeleted+" ORDER BY id"); err != nil {
+		return fmt.Errorf("failed to declare export cursor: %w", mapError(err))
+	}
+
+	fetch := fmt.Sprintf("FETCH %d FROM movie_export", exportFetchSize)
+	for {
+		rows, err := tx.Query(ctx, fetch)
+		if err != nil {
+			return fmt.Errorf("failed to fetch movies: %w", mapError(err))
+		}
+		batch, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*models.Movie, error) {
+			m, err := scanMovie(row)
+			if err != nil {
+				return nil, err
+			}
+			return toMovie(m)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to fetch movies: %w", mapError(err))
+		}
+
+		for _, movie := range batch {
+			if err := fn(*movie); err != nil {
+				return err
+			}
+		}
+		if len(batch) < exportFetchSize {
+			return nil
+		}
+	}
+}
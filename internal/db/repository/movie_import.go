@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/mexirica/chi-template/internal/db/sqlc"
+	"github.com/mexirica/chi-template/internal/models"
+	"github.com/mexirica/chi-template/internal/o11y"
+)
+
+type psqlMovieImport struct {
+	tx pgx.Tx
+	q  *sqlc.Queries
+}
+
+// BeginImport starts a bulk import transaction.
+func (r *PsqlMovieRepository) BeginImport(ctx context.Context) (MovieImport, error) {
+	ctx, span := o11y.Tracer().Start(ctx, "PsqlMovieRepository.BeginImport")
+	defer span.End()
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin import: %w", mapError(err))
+	}
+	return &psqlMovieImport{tx: tx, q: sqlc.New(tx)}, nil
+}
+
+// Copy inserts the movies with the COPY protocol and returns the number of rows written.
+func (i *psqlMovieImport) Copy(ctx context.Context, movies []models.CreateMovieRequest) (int64, error) {
+	ctx, span := o11y.Tracer().Start(ctx, "psqlMovieImport.Copy")
+	defer span.End()
+
+	rows := make([]sqlc.CopyMoviesParams, 0, len(movies))
+	for _, movie := range movies {
+		rows = append(rows, sqlc.CopyMoviesParams{
+			Title:       movie.Title,
+			Description: pgtype.Text{String: movie.Description, Valid: true},
+			ReleaseYear: int32(movie.ReleaseYear),
+			Genre:       movie.Genre,
+			Director:    pgtype.Text{String: movie.Director, Valid: true},
+			Rating:      float64ToPgNumeric(movie.Rating),
+		})
+	}
+
+	n, err := i.q.CopyMovies(ctx, rows)
+	if err != nil {
+		return n, fmt.Errorf("failed to copy movies: %w", mapError(err))
+	}
+	return n, nil
+}
+
+func (i *psqlMovieImport) Commit(ctx context.Context) error {
+	if err := i.tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit import: %w", mapError(err))
+	}
+	return nil
+}
+
+func (i *psqlMovieImport) Rollback(ctx context.Context) error {
+	if err := i.tx.Rollback(ctx); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+		return fmt.Errorf("failed to roll back import: %w", mapError(err))
+	}
+	return nil
+}
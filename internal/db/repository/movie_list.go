@@ -0,0 +1,253 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/mexirica/chi-template/internal/errs"
+	"github.com/mexirica/chi-template/internal/models"
+	"github.com/mexirica/chi-template/internal/pagination"
+)
+
+// movieColumns is the column list scanned by scanMovie, in movieRow field order. Like the sqlc
+// queries, it leaves out search_vector, which only the database reads.
+const movieColumns = "id, title, description, release_year, genre, director, rating, updated_at, version, deleted_at"
+
+// notDeleted excludes the movies in the trash.
+const notDeleted = "deleted_at IS NULL"
+
+// sortColumns maps the allow-listed sort fields to their SQL expressions. Nullable columns are
+// coalesced so that ordering is total and deterministic.
+var sortColumns = map[string]string{
+	"id":           "id",
+	"title":        "title",
+	"release_year": "release_year",
+	"rating":       "COALESCE(rating, 0)",
+	"director":     "COALESCE(director, '')",
+}
+
+// queryBuilder accumulates WHERE conditions and their positional arguments.
+type queryBuilder struct {
+	where []string
+	args  []any
+}
+
+// arg registers a query argument and returns its placeholder.
+func (b *queryBuilder) arg(v any) string {
+	b.args = append(b.args, v)
+	return "$" + strconv.Itoa(len(b.args))
+}
+
+func (b *queryBuilder) and(condition string) {
+	b.where = append(b.where, condition)
+}
+
+func (b *queryBuilder) whereClause() string {
+	if len(b.where) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(b.where, " AND ")
+}
+
+// applyMovieFilter adds the conditions of a movie listing filter to the builder.
+func applyMovieFilter(b *queryBuilder, f models.MovieFilter) {
+	if len(f.Genres) > 0 {
+		b.and("genre @> " + b.arg(f.Genres) + "::varchar[]")
+	}
+	if f.Director != "" {
+		b.and("director ILIKE " + b.arg("%"+escapeLike(f.Director)+"%"))
+	}
+	if f.Query != "" {
+		b.and("title ILIKE " + b.arg("%"+escapeLike(f.Query)+"%"))
+	}
+	if f.YearFrom > 0 {
+		b.and("release_year >= " + b.arg(f.YearFrom))
+	}
+	if f.YearTo > 0 {
+		b.and("release_year <= " + b.arg(f.YearTo))
+	}
+	if f.MinRating > 0 {
+		b.and("rating >= " + b.arg(f.MinRating))
+	}
+}
+
+// withTiebreaker returns the sort keys with id appended, so that every ordering is total.
+// An empty sort falls back to the historical newest-first order.
+func withTiebreaker(sort []models.SortField) []models.SortField {
+	if len(sort) == 0 {
+		return []models.SortField{{Field: "id", Desc: true}}
+	}
+	for _, field := range sort {
+		if field.Field == "id" {
+			return sort
+		}
+	}
+	return append(sort[:len(sort):len(sort)], models.SortField{Field: "id"})
+}
+
+// orderByClause renders the ORDER BY clause for the sort keys, optionally reversing every key.
+func orderByClause(sort []models.SortField, reverse bool) (string, error) {
+	keys := make([]string, 0, len(sort))
+	for _, field := range sort {
+		column, ok := sortColumns[field.Field]
+		if !ok {
+			return "", errs.Validation("movies cannot be sorted by %q", field.Field)
+		}
+		if field.Desc != reverse {
+			column += " DESC"
+		}
+		keys = append(keys, column)
+	}
+	return " ORDER BY " + strings.Join(keys, ", "), nil
+}
+
+// keysetCondition renders the condition selecting the rows after (or before) the row whose sort
+// key values are given, expanded as (k1 > v1) OR (k1 = v1 AND k2 > v2) OR ... so that keys
+// with mixed directions are supported.
+func keysetCondition(b *queryBuilder, sort []models.SortField, values []any, before bool) string {
+	placeholders := make([]string, len(values))
+	for i, v := range values {
+		placeholders[i] = b.arg(v)
+	}
+
+	alternatives := make([]string, 0, len(sort))
+	for i, field := range sort {
+		terms := make([]string, 0, i+1)
+		for j := range i {
+			terms = append(terms, sortColumns[sort[j].Field]+" = "+placeholders[j])
+		}
+		op := " > "
+		if field.Desc != before {
+			op = " < "
+		}
+		terms = append(terms, sortColumns[field.Field]+op+placeholders[i])
+		alternatives = append(alternatives, "("+strings.Join(terms, " AND ")+")")
+	}
+	return "(" + strings.Join(alternatives, " OR ") + ")"
+}
+
+// buildListQuery renders the listing query for the filter. sort must already include the id
+// tiebreaker. With a cursor the page starts after (or before) the cursor row; otherwise it is
+// selected with OFFSET. One extra row is fetched to detect whether another page exists.
+func buildListQuery(f models.MovieFilter, sort []models.SortField, cursor *pagination.Cursor) (string, []any, error) {
+	b := &queryBuilder{}
+	b.and(notDeleted)
+	applyMovieFilter(b, f)
+
+	reverse := false
+	offset := (f.Page - 1) * f.Limit
+	if cursor != nil {
+		values, err := keysetArgs(sort, cursor.Values)
+		if err != nil {
+			return "", nil, err
+		}
+		b.and(keysetCondition(b, sort, values, cursor.Before))
+		reverse = cursor.Before
+		offset = 0
+	}
+
+	orderBy, err := orderByClause(sort, reverse)
+	if err != nil {
+		return "", nil, err
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM movies%s%s LIMIT %s OFFSET %s",
+		movieColumns, b.whereClause(), orderBy, b.arg(f.Limit+1), b.arg(offset))
+	return query, b.args, nil
+}
+
+// sortKey renders the canonical form of the sort keys, e.g. "title,-rating,id".
+func sortKey(sort []models.SortField) string {
+	keys := make([]string, 0, len(sort))
+	for _, field := range sort {
+		if field.Desc {
+			keys = append(keys, "-"+field.Field)
+			continue
+		}
+		keys = append(keys, field.Field)
+	}
+	return strings.Join(keys, ",")
+}
+
+// keysetValues returns the sort key values of a movie, matching the expressions in sortColumns.
+func keysetValues(m movieRow, sort []models.SortField) []any {
+	values := make([]any, 0, len(sort))
+	for _, field := range sort {
+		switch field.Field {
+		case "id":
+			values = append(values, m.ID)
+		case "title":
+			values = append(values, m.Title)
+		case "release_year":
+			values = append(values, m.ReleaseYear)
+		case "rating":
+			rating, _ := m.Rating.Float64Value()
+			values = append(values, rating.Float64)
+		case "director":
+			values = append(values, m.Director.String)
+		}
+	}
+	return values
+}
+
+// keysetArgs converts the values decoded from a cursor back into typed query arguments.
+func keysetArgs(sort []models.SortField, values []any) ([]any, error) {
+	if len(values) != len(sort) {
+		return nil, errs.Validation("invalid cursor")
+	}
+
+	args := make([]any, len(values))
+	for i, field := range sort {
+		var err error
+		switch field.Field {
+		case "id", "release_year":
+			n, ok := values[i].(json.Number)
+			if !ok {
+				return nil, errs.Validation("invalid cursor")
+			}
+			args[i], err = n.Int64()
+		case "rating":
+			n, ok := values[i].(json.Number)
+			if !ok {
+				return nil, errs.Validation("invalid cursor")
+			}
+			args[i], err = n.Float64()
+		default:
+			str, ok := values[i].(string)
+			if !ok {
+				return nil, errs.Validation("invalid cursor")
+			}
+			args[i] = str
+		}
+		if err != nil {
+			return nil, errs.Validation("invalid cursor")
+		}
+	}
+	return args, nil
+}
+
+// scanMovie scans a row selected with movieColumns.
+func scanMovie(row pgx.Row) (movieRow, error) {
+	var m movieRow
+	err := row.Scan(
+		&m.ID,
+		&m.Title,
+		&m.Description,
+		&m.ReleaseYear,
+		&m.Genre,
+		&m.Director,
+		&m.Rating,
+		&m.UpdatedAt,
+		&m.Version,
+		&m.DeletedAt,
+	)
+	return m, err
+}
+
+// escapeLike escapes the LIKE wildcards in user input.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
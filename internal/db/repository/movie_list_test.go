@@ -0,0 +1,112 @@
+// movie_list_test.go
+// Unit tests for the dynamic movie listing query builder.
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/mexirica/chi-template/internal/errs"
+	"github.com/mexirica/chi-template/internal/models"
+	"github.com/mexirica/chi-template/internal/pagination"
+)
+
+func TestBuildListQuery_Defaults(t *testing.T) {
+	filter := models.MovieFilter{Page: 1, Limit: 10}
+	query, args, err := buildListQuery(filter, withTiebreaker(filter.Sort), nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	want := "SELECT " + movieColumns + " FROM movies WHERE deleted_at IS NULL ORDER BY id DESC LIMIT $1 OFFSET $2"
+	if query != want {
+		t.Errorf("expected query %q, got %q", want, query)
+	}
+	if !reflect.DeepEqual(args, []any{11, 0}) {
+		t.Errorf("expected args [11 0], got %v", args)
+	}
+}
+
+func TestBuildListQuery_FiltersAndSort(t *testing.T) {
+	filter := models.MovieFilter{
+		Genres:    []string{"Action", "Drama"},
+		Director:  "50%_off",
+		YearFrom:  1990,
+		YearTo:    2000,
+		MinRating: 7.5,
+		Sort:      []models.SortField{{Field: "title"}, {Field: "rating", Desc: true}},
+		Page:      3,
+		Limit:     20,
+	}
+	query, args, err := buildListQuery(filter, withTiebreaker(filter.Sort), nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	want := "SELECT " + movieColumns + " FROM movies" +
+		" WHERE deleted_at IS NULL AND genre @> $1::varchar[] AND director ILIKE $2 AND release_year >= $3 AND release_year <= $4 AND rating >= $5" +
+		" ORDER BY title, COALESCE(rating, 0) DESC, id LIMIT $6 OFFSET $7"
+	if query != want {
+		t.Errorf("expected query %q, got %q", want, query)
+	}
+	wantArgs := []any{[]string{"Action", "Drama"}, `%50\%\_off%`, 1990, 2000, 7.5, 21, 40}
+	if !reflect.DeepEqual(args, wantArgs) {
+		t.Errorf("expected args %v, got %v", wantArgs, args)
+	}
+}
+
+func TestBuildListQuery_UnknownSortField(t *testing.T) {
+	sort := []models.SortField{{Field: "id; DROP TABLE movies"}}
+	_, _, err := buildListQuery(models.MovieFilter{Sort: sort, Page: 1, Limit: 10}, sort, nil)
+	if !errors.Is(err, errs.ErrValidation) {
+		t.Errorf("expected validation error, got %v", err)
+	}
+}
+
+func TestBuildListQuery_Keyset(t *testing.T) {
+	sort := withTiebreaker([]models.SortField{{Field: "title"}, {Field: "rating", Desc: true}})
+	cursor := &pagination.Cursor{
+		Sort:   sortKey(sort),
+		Values: []any{"Alien", json.Number("8.5"), json.Number("42")},
+	}
+
+	query, args, err := buildListQuery(models.MovieFilter{Page: 5, Limit: 10}, sort, cursor)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	want := "SELECT " + movieColumns + " FROM movies" +
+		" WHERE deleted_at IS NULL AND ((title > $1) OR (title = $1 AND COALESCE(rating, 0) < $2) OR (title = $1 AND COALESCE(rating, 0) = $2 AND id > $3))" +
+		" ORDER BY title, COALESCE(rating, 0) DESC, id LIMIT $4 OFFSET $5"
+	if query != want {
+		t.Errorf("expected query %q, got %q", want, query)
+	}
+	wantArgs := []any{"Alien", 8.5, int64(42), 11, 0}
+	if !reflect.DeepEqual(args, wantArgs) {
+		t.Errorf("expected args %v, got %v", wantArgs, args)
+	}
+
+	cursor.Before = true
+	query, _, err = buildListQuery(models.MovieFilter{Page: 1, Limit: 10}, sort, cursor)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	want = "SELECT " + movieColumns + " FROM movies" +
+		" WHERE deleted_at IS NULL AND ((title < $1) OR (title = $1 AND COALESCE(rating, 0) > $2) OR (title = $1 AND COALESCE(rating, 0) = $2 AND id < $3))" +
+		" ORDER BY title DESC, COALESCE(rating, 0), id DESC LIMIT $4 OFFSET $5"
+	if query != want {
+		t.Errorf("expected query %q, got %q", want, query)
+	}
+}
+
+func TestBuildListQuery_InvalidCursorValues(t *testing.T) {
+	sort := withTiebreaker(nil)
+	cursor := &pagination.Cursor{Sort: sortKey(sort), Values: []any{"not-a-number"}}
+
+	_, _, err := buildListQuery(models.MovieFilter{Page: 1, Limit: 10}, sort, cursor)
+	if !errors.Is(err, errs.ErrValidation) {
+		t.Errorf("expected validation error, got %v", err)
+	}
+}
@@ -3,36 +3,94 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/mexirica/chi-template/internal/db/sqlc"
+	"github.com/mexirica/chi-template/internal/errs"
 	"github.com/mexirica/chi-template/internal/models"
 	"github.com/mexirica/chi-template/internal/o11y"
+	"github.com/mexirica/chi-template/internal/pagination"
 )
 
 type MovieRepository interface {
-	Create(ctx context.Context, movie models.CreateMovieRequest) error
+	Create(ctx context.Context, movie models.CreateMovieRequest) (*models.Movie, error)
 	GetById(ctx context.Context, id int) (*models.Movie, error)
-	GetList(ctx context.Context, page, limit int) (*models.GetMovieList, error)
-	Delete(ctx context.Context, id int) error
+	GetList(ctx context.Context, filter models.MovieFilter) (*models.GetMovieList, error)
+	Search(ctx context.Context, search models.MovieSearch) (*models.MovieSearchResults, error)
+	Suggest(ctx context.Context, suggest models.MovieSuggest) (*models.MovieSuggestions, error)
+	// Update and Delete only apply to the given version of the movie when version is not zero,
+	// and fail with errs.ErrPreconditionFailed when the movie has moved on.
+	Update(ctx context.Context, id int, movie models.UpdateMovieRequest, version int64) (*models.Movie, error)
+	Delete(ctx context.Context, id int, version int64) error
+	Trash(ctx context.Context, page, limit int) (*models.MovieTrash, error)
+	Restore(ctx context.Context, id int) (*models.Movie, error)
+	Purge(ctx context.Context, deletedBefore time.Time) (int64, error)
+	BeginImport(ctx context.Context) (MovieImport, error)
+	Export(ctx context.Context, fn func(models.Movie) error) error
+}
+
+// MovieImport is a bulk insert running in its own transaction. Nothing it copies is visible
+// to other sessions until Commit; Rollback after Commit is a no-op.
+type MovieImport interface {
+	Copy(ctx context.Context, movies []models.CreateMovieRequest) (int64, error)
+	Commit(ctx context.Context) error
+	Rollback(ctx context.Context) error
 }
 
 type PsqlMovieRepository struct {
 	sqlc.Querier
+	db                 sqlc.DBTX
+	pool               *pgxpool.Pool
+	estimateCountAbove int64
+	searchLanguage     string
+}
+
+// Option configures a PsqlMovieRepository.
+type Option func(*PsqlMovieRepository)
+
+// WithCountEstimate makes unfiltered listings report the planner's row estimate from
+// pg_class.reltuples instead of running count(*), once the table holds at least threshold rows.
+func WithCountEstimate(threshold int64) Option {
+	return func(r *PsqlMovieRepository) {
+		r.estimateCountAbove = threshold
+	}
 }
 
-func NewMovieRepository(conn *pgxpool.Pool) *PsqlMovieRepository {
-	return &PsqlMovieRepository{
-		Querier: sqlc.New(conn),
+// WithSearchLanguage sets the text search configuration (e.g. "english", "simple") used to
+// parse search queries and build headlines. It must be the configuration of the
+// movies.search_vector column for stemming to line up; see CheckSearchLanguage.
+func WithSearchLanguage(lang string) Option {
+	return func(r *PsqlMovieRepository) {
+		if lang != "" {
+			r.searchLanguage = lang
+		}
 	}
 }
 
-func (r *PsqlMovieRepository) Create(ctx context.Context, movie models.CreateMovieRequest) error {
+func NewMovieRepository(conn *pgxpool.Pool, opts ...Option) *PsqlMovieRepository {
+	r := &PsqlMovieRepository{
+		Querier:        sqlc.New(conn),
+		db:             conn,
+		pool:           conn,
+		searchLanguage: "english",
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Create inserts the movie and returns it as stored, with its id and version.
+func (r *PsqlMovieRepository) Create(ctx context.Context, movie models.CreateMovieRequest) (*models.Movie, error) {
 	ctx, span := o11y.Tracer().Start(ctx, "PsqlMovieRepository.Create")
 	defer span.End()
-	_, err := r.CreateMovie(ctx, sqlc.CreateMovieParams{
+	created, err := r.CreateMovie(ctx, sqlc.CreateMovieParams{
 		Title:       movie.Title,
 		Description: pgtype.Text{String: movie.Description, Valid: true},
 		ReleaseYear: int32(movie.ReleaseYear),
@@ -40,49 +98,91 @@ func (r *PsqlMovieRepository) Create(ctx context.Context, movie models.CreateMov
 		Director:    pgtype.Text{String: movie.Director, Valid: true},
 		Rating:      float64ToPgNumeric(movie.Rating),
 	})
-	return err
+	if err != nil {
+		return nil, mapError(err)
+	}
+	return toMovie(movieRow(created))
 }
 
 func (r *PsqlMovieRepository) GetById(ctx context.Context, id int) (*models.Movie, error) {
 	ctx, span := o11y.Tracer().Start(ctx, "PsqlMovieRepository.GetById")
 	defer span.End()
 	movie, err := r.GetMovieByID(ctx, int64(id))
-	if err != nil {
-		return nil, err
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, errs.NotFound("movie %d not found", id)
 	}
-
-	rating, err := movie.Rating.Float64Value()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get movie rating: %w", err)
+		return nil, mapError(err)
 	}
-	return &models.Movie{
-		ID:          movie.ID,
-		Title:       movie.Title,
-		Description: movie.Description.String,
-		ReleaseYear: int(movie.ReleaseYear),
-		Genre:       movie.Genre,
-		Director:    movie.Director.String,
-		Rating:      rating.Float64,
-	}, nil
+
+	return toMovie(movie)
 }
 
-func (r *PsqlMovieRepository) GetList(ctx context.Context, page, limit int) (*models.GetMovieList, error) {
+// GetList returns one page of movies matching the filter, sorted by the filter's sort keys.
+// Pages are addressed either by page number or by an opaque cursor (keyset pagination); in both
+// modes the result carries the cursors of the adjacent pages.
+func (r *PsqlMovieRepository) GetList(ctx context.Context, filter models.MovieFilter) (*models.GetMovieList, error) {
 	ctx, span := o11y.Tracer().Start(ctx, "PsqlMovieRepository.GetList")
 	defer span.End()
 
-	offset := (page - 1) * limit
-	movies, err := r.ListMovies(ctx, sqlc.ListMoviesParams{
-		Limit:  int32(limit),
-		Offset: int32(offset),
+	sort := withTiebreaker(filter.Sort)
+	var cursor *pagination.Cursor
+	if filter.Cursor != "" {
+		c, err := pagination.Decode(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		if c.Sort != sortKey(sort) {
+			return nil, errs.Validation("cursor was issued for a different sort order")
+		}
+		cursor = &c
+	}
+
+	query, args, err := buildListQuery(filter, sort, cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	movies, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (movieRow, error) {
+		return scanMovie(row)
 	})
+	if err != nil {
+		return nil, mapError(err)
+	}
+
+	total, estimated, err := r.count(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
 
-	result := make([]models.GetMovieResponse, 0, len(movies))
+	hasMore := len(movies) > filter.Limit
+	if hasMore {
+		movies = movies[:filter.Limit]
+	}
+
+	var hasNext, hasPrev bool
+	switch {
+	case cursor == nil:
+		hasNext, hasPrev = hasMore, filter.Page > 1
+	case cursor.Before:
+		slices.Reverse(movies)
+		hasNext, hasPrev = true, hasMore
+	default:
+		hasNext, hasPrev = hasMore, true
+	}
+
+	result := &models.GetMovieList{
+		Movies:         make([]models.GetMovieResponse, 0, len(movies)),
+		Total:          total,
+		TotalEstimated: estimated,
+	}
 	for _, m := range movies {
 		rating, _ := m.Rating.Float64Value()
-		result = append(result, models.GetMovieResponse{
+		result.Movies = append(result.Movies, models.GetMovieResponse{
 			ID:          m.ID,
 			Title:       m.Title,
 			Description: m.Description.String,
@@ -90,24 +190,223 @@ func (r *PsqlMovieRepository) GetList(ctx context.Context, page, limit int) (*mo
 			Genre:       m.Genre,
 			Director:    m.Director.String,
 			Rating:      rating.Float64,
+			UpdatedAt:   m.UpdatedAt,
+			Version:     m.Version,
 		})
 	}
 
-	return &models.GetMovieList{
-		Movies: result,
-	}, nil
+	if len(movies) > 0 {
+		if hasNext {
+			if result.NextCursor, err = pagination.Encode(pagination.Cursor{Sort: sortKey(sort), Values: keysetValues(movies[len(movies)-1], sort)}); err != nil {
+				return nil, err
+			}
+		}
+		if hasPrev {
+			if result.PrevCursor, err = pagination.Encode(pagination.Cursor{Sort: sortKey(sort), Values: keysetValues(movies[0], sort), Before: true}); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// count returns the number of movies matching the filter. Unfiltered counts on large tables use
+// the planner's estimate when WithCountEstimate is configured; the boolean reports an estimate.
+// The estimate includes the movies in the trash.
+func (r *PsqlMovieRepository) count(ctx context.Context, filter models.MovieFilter) (int64, bool, error) {
+	ctx, span := o11y.Tracer().Start(ctx, "PsqlMovieRepository.count")
+	defer span.End()
+
+	b := &queryBuilder{}
+	applyMovieFilter(b, filter)
+
+	if len(b.where) == 0 && r.estimateCountAbove > 0 {
+		var estimate int64
+		err := r.db.QueryRow(ctx, "SELECT reltuples::bigint FROM pg_class WHERE oid = 'movies'::regclass").Scan(&estimate)
+		if err != nil {
+			return 0, false, mapError(err)
+		}
+		// reltuples is -1 until the table has been vacuumed or analyzed.
+		if estimate >= r.estimateCountAbove {
+			return estimate, true, nil
+		}
+	}
+
+	b.and(notDeleted)
+	var total int64
+	if err := r.db.QueryRow(ctx, "SELECT count(*) FROM movies"+b.whereClause(), b.args...).Scan(&total); err != nil {
+		return 0, false, mapError(err)
+	}
+	return total, false, nil
+}
+
+// CheckSearchLanguage verifies that the search language is a text search configuration and
+// the one movies.search_vector is generated with: queries parsed with another one would not
+// match the stored lexemes.
+func (r *PsqlMovieRepository) CheckSearchLanguage(ctx context.Context) error {
+	matches, err := r.SearchVectorConfigMatches(ctx, r.searchLanguage)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return errors.New("movies.search_vector does not exist; run the migrations")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check search language %q: %w", r.searchLanguage, mapError(err))
+	}
+	if !matches {
+		return fmt.Errorf("movies.search_vector is not generated with the %q text search configuration", r.searchLanguage)
+	}
+	return nil
+}
+
+// Search runs a full-text search over the movies, best matches first.
+func (r *PsqlMovieRepository) Search(ctx context.Context, search models.MovieSearch) (*models.MovieSearchResults, error) {
+	ctx, span := o11y.Tracer().Start(ctx, "PsqlMovieRepository.Search")
+	defer span.End()
+
+	rows, err := r.SearchMovies(ctx, sqlc.SearchMoviesParams{
+		Config:    r.searchLanguage,
+		Query:     search.Query,
+		RowLimit:  int32(search.Limit),
+		RowOffset: int32((search.Page - 1) * search.Limit),
+	})
+	if err != nil {
+		return nil, mapError(err)
+	}
+
+	result := &models.MovieSearchResults{
+		Results: make([]models.MovieSearchResult, 0, len(rows)),
+	}
+	for _, m := range rows {
+		rating, _ := m.Rating.Float64Value()
+		result.Results = append(result.Results, models.MovieSearchResult{
+			GetMovieResponse: models.GetMovieResponse{
+				ID:          m.ID,
+				Title:       m.Title,
+				Description: m.Description.String,
+				ReleaseYear: int(m.ReleaseYear),
+				Genre:       m.Genre,
+				Director:    m.Director.String,
+				Rating:      rating.Float64,
+				UpdatedAt:   m.UpdatedAt,
+				Version:     m.Version,
+			},
+			Rank:     m.Rank,
+			Headline: m.Headline,
+		})
+	}
+	return result, nil
+}
+
+// Suggest returns the titles most similar to the prefix, tolerating typos.
+func (r *PsqlMovieRepository) Suggest(ctx context.Context, suggest models.MovieSuggest) (*models.MovieSuggestions, error) {
+	ctx, span := o11y.Tracer().Start(ctx, "PsqlMovieRepository.Suggest")
+	defer span.End()
+
+	rows, err := r.SuggestMovies(ctx, sqlc.SuggestMoviesParams{
+		Prefix:   suggest.Prefix,
+		RowLimit: int32(suggest.Limit),
+	})
+	if err != nil {
+		return nil, mapError(err)
+	}
+
+	result := &models.MovieSuggestions{
+		Suggestions: make([]models.MovieSuggestion, 0, len(rows)),
+	}
+	for _, m := range rows {
+		result.Suggestions = append(result.Suggestions, models.MovieSuggestion{
+			ID:         m.ID,
+			Title:      m.Title,
+			Similarity: m.Similarity,
+		})
+	}
+	return result, nil
 }
 
-func (r *PsqlMovieRepository) Delete(ctx context.Context, id int) error {
+// Update replaces every field of the movie.
+func (r *PsqlMovieRepository) Update(ctx context.Context, id int, movie models.UpdateMovieRequest, version int64) (*models.Movie, error) {
+	ctx, span := o11y.Tracer().Start(ctx, "PsqlMovieRepository.Update")
+	defer span.End()
+	updated, err := r.UpdateMovie(ctx, sqlc.UpdateMovieParams{
+		ID:              int64(id),
+		Title:           movie.Title,
+		Description:     pgtype.Text{String: movie.Description, Valid: true},
+		ReleaseYear:     int32(movie.ReleaseYear),
+		Genre:           movie.Genre,
+		Director:        pgtype.Text{String: movie.Director, Valid: true},
+		Rating:          float64ToPgNumeric(movie.Rating),
+		ExpectedVersion: expectedVersion(version),
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, r.unmatched(ctx, id, version)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update movie with id %d: %w", id, mapError(err))
+	}
+
+	return toMovie(movieRow(updated))
+}
+
+func (r *PsqlMovieRepository) Delete(ctx context.Context, id int, version int64) error {
 	ctx, span := o11y.Tracer().Start(ctx, "PsqlMovieRepository.Delete")
 	defer span.End()
-	err := r.DeleteMovie(ctx, int64(id))
+	deleted, err := r.DeleteMovie(ctx, sqlc.DeleteMovieParams{
+		ID:              int64(id),
+		ExpectedVersion: expectedVersion(version),
+	})
 	if err != nil {
-		return fmt.Errorf("failed to delete movie with id %d: %w", id, err)
+		return fmt.Errorf("failed to delete movie with id %d: %w", id, mapError(err))
+	}
+	if deleted == 0 {
+		return r.unmatched(ctx, id, version)
 	}
 	return nil
 }
 
+// unmatched explains why a conditional write touched no row: either the movie does not exist
+// or its version is no longer the expected one.
+func (r *PsqlMovieRepository) unmatched(ctx context.Context, id int, version int64) error {
+	if version == 0 {
+		return errs.NotFound("movie %d not found", id)
+	}
+	current, err := r.GetMovieByID(ctx, int64(id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return errs.NotFound("movie %d not found", id)
+	}
+	if err != nil {
+		return mapError(err)
+	}
+	return errs.PreconditionFailed("movie %d is at version %d, not %d", id, current.Version, version)
+}
+
+// expectedVersion maps a zero version, meaning unconditional, to NULL.
+func expectedVersion(version int64) pgtype.Int8 {
+	return pgtype.Int8{Int64: version, Valid: version != 0}
+}
+
+// movieRow is a movie as the queries select it, without its search vector. sqlc generates an
+// identical row type for each query; they are converted to this one.
+type movieRow = sqlc.GetMovieByIDRow
+
+// toMovie converts a movie row into the domain model.
+func toMovie(movie movieRow) (*models.Movie, error) {
+	rating, err := movie.Rating.Float64Value()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get movie rating: %w", err)
+	}
+	return &models.Movie{
+		ID:          movie.ID,
+		Title:       movie.Title,
+		Description: movie.Description.String,
+		ReleaseYear: int(movie.ReleaseYear),
+		Genre:       movie.Genre,
+		Director:    movie.Director.String,
+		Rating:      rating.Float64,
+		UpdatedAt:   movie.UpdatedAt,
+		Version:     movie.Version,
+	}, nil
+}
+
 // float64ToPgNumeric converts a float64 to pgtype.Numeric.
 func float64ToPgNumeric(val float64) pgtype.Numeric {
 	var num pgtype.Numeric
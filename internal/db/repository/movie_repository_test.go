@@ -25,9 +25,9 @@ func TestMovieRepository_Create(t *testing.T) {
 		Director:    "John Doe",
 		Rating:      8.5,
 	}
-	mockRepo.EXPECT().Create(gomock.Any(), movie).Return(nil)
+	mockRepo.EXPECT().Create(gomock.Any(), movie).Return(&models.Movie{ID: 1, Title: movie.Title}, nil)
 
-	err := mockRepo.Create(context.Background(), movie)
+	_, err := mockRepo.Create(context.Background(), movie)
 	if err != nil {
 		t.Errorf("expected no error, got %v", err)
 	}
@@ -56,9 +56,10 @@ func TestMovieRepository_GetList(t *testing.T) {
 
 	mockRepo := mock_repository.NewMockMovieRepository(ctrl)
 	movieList := &models.GetMovieList{Movies: []models.GetMovieResponse{{ID: 1, Title: "Test Movie"}}}
-	mockRepo.EXPECT().GetList(gomock.Any(), 1, 10).Return(movieList, nil)
+	filter := models.MovieFilter{Page: 1, Limit: 10}
+	mockRepo.EXPECT().GetList(gomock.Any(), filter).Return(movieList, nil)
 
-	result, err := mockRepo.GetList(context.Background(), 1, 10)
+	result, err := mockRepo.GetList(context.Background(), filter)
 	if err != nil {
 		t.Errorf("expected no error, got %v", err)
 	}
@@ -72,9 +73,9 @@ func TestMovieRepository_Delete(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockRepo := mock_repository.NewMockMovieRepository(ctrl)
-	mockRepo.EXPECT().Delete(gomock.Any(), 1).Return(nil)
+	mockRepo.EXPECT().Delete(gomock.Any(), 1, int64(0)).Return(nil)
 
-	err := mockRepo.Delete(context.Background(), 1)
+	err := mockRepo.Delete(context.Background(), 1, 0)
 	if err != nil {
 		t.Errorf("expected no error, got %v", err)
 	}
@@ -86,9 +87,9 @@ func TestMovieRepository_Create_Error(t *testing.T) {
 
 	mockRepo := mock_repository.NewMockMovieRepository(ctrl)
 	movie := models.CreateMovieRequest{Title: "Error Movie"}
-	mockRepo.EXPECT().Create(gomock.Any(), movie).Return(errors.New("db error"))
+	mockRepo.EXPECT().Create(gomock.Any(), movie).Return(nil, errors.New("db error"))
 
-	err := mockRepo.Create(context.Background(), movie)
+	_, err := mockRepo.Create(context.Background(), movie)
 	if err == nil {
 		t.Error("expected error, got nil")
 	}
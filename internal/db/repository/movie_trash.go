@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/mexirica/chi-template/internal/db/sqlc"
+	"github.com/mexirica/chi-template/internal/errs"
+	"github.com/mexirica/chi-template/internal/models"
+	"github.com/mexirica/chi-template/internal/o11y"
+)
+
+// purgeBatchSize is the number of movies hard-deleted per statement, so that a large purge
+// never holds its locks for long.
+const purgeBatchSize = 1000
+
+// Trash returns one page of the deleted movies, most recently deleted first.
+func (r *PsqlMovieRepository) Trash(ctx context.Context, page, limit int) (*models.MovieTrash, error) {
+	ctx, span := o11y.Tracer().Start(ctx, "PsqlMovieRepository.Trash")
+	defer span.End()
+
+	rows, err := r.ListTrashedMovies(ctx, sqlc.ListTrashedMoviesParams{
+		RowLimit:  int32(limit),
+		RowOffset: int32((page - 1) * limit),
+	})
+	if err != nil {
+		return nil, mapError(err)
+	}
+	total, err := r.CountTrashedMovies(ctx)
+	if err != nil {
+		return nil, mapError(err)
+	}
+
+	result := &models.MovieTrash{
+		Movies: make([]models.TrashedMovie, 0, len(rows)),
+		Total:  total,
+	}
+	for _, m := range rows {
+		movie, err := toMovie(movieRow(m))
+		if err != nil {
+			return nil, err
+		}
+		result.Movies = append(result.Movies, models.TrashedMovie{
+			GetMovieResponse: models.GetMovieResponse(*movie),
+			DeletedAt:        m.DeletedAt.Time,
+		})
+	}
+	return result, nil
+}
+
+// Restore takes the movie out of the trash.
+func (r *PsqlMovieRepository) Restore(ctx context.Context, id int) (*models.Movie, error) {
+	ctx, span := o11y.Tracer().Start(ctx, "PsqlMovieRepository.Restore")
+	defer span.End()
+
+	restored, err := r.RestoreMovie(ctx, int64(id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Either the movie is not in the trash or it does not exist at all.
+		_, err := r.GetMovieByID(ctx, int64(id))
+		switch {
+		case err == nil:
+			return nil, errs.Conflict("movie %d is not deleted", id)
+		case errors.Is(err, pgx.ErrNoRows):
+			return nil, errs.NotFound("movie %d not found", id)
+		default:
+			return nil, mapError(err)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore movie with id %d: %w", id, mapError(err))
+	}
+	return toMovie(movieRow(restored))
+}
+
+// Purge permanently deletes the movies deleted before deletedBefore and returns how many.
+func (r *PsqlMovieRepository) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	ctx, span := o11y.Tracer().Start(ctx, "PsqlMovieRepository.Purge")
+	defer span.End()
+
+	var purged int64
+	for {
+		n, err := r.PurgeMovies(ctx, sqlc.PurgeMoviesParams{
+			DeletedBefore: deletedBefore,
+			BatchSize:     purgeBatchSize,
+		})
+		purged += n
+		if err != nil {
+			return purged, fmt.Errorf("failed to purge deleted movies: %w", mapError(err))
+		}
+		if n < purgeBatchSize {
+			return purged, nil
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mexirica/chi-template/internal/db/sqlc"
+	"github.com/mexirica/chi-template/internal/o11y"
+)
+
+// RoleBindingRepository reads the roles granted to callers in the role_bindings table.
+type RoleBindingRepository interface {
+	// Roles returns the roles bound to the subject, which has none when it is unknown.
+	Roles(ctx context.Context, subject string) ([]string, error)
+}
+
+type PsqlRoleBindingRepository struct {
+	sqlc.Querier
+}
+
+func NewRoleBindingRepository(conn *pgxpool.Pool) *PsqlRoleBindingRepository {
+	return &PsqlRoleBindingRepository{Querier: sqlc.New(conn)}
+}
+
+func (r *PsqlRoleBindingRepository) Roles(ctx context.Context, subject string) ([]string, error) {
+	ctx, span := o11y.Tracer().Start(ctx, "PsqlRoleBindingRepository.Roles")
+	defer span.End()
+	roles, err := r.ListRolesBySubject(ctx, subject)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	return roles, nil
+}
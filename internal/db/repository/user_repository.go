@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mexirica/chi-template/internal/db/sqlc"
+	"github.com/mexirica/chi-template/internal/errs"
+	"github.com/mexirica/chi-template/internal/models"
+	"github.com/mexirica/chi-template/internal/o11y"
+)
+
+// UserRepository stores the user accounts. Emails are expected lowercased.
+type UserRepository interface {
+	// Create stores the account, or returns an errs.ErrConflict error when the email is taken.
+	Create(ctx context.Context, email, passwordHash string) (*models.User, error)
+	GetByEmail(ctx context.Context, email string) (*models.UserCredentials, error)
+}
+
+type PsqlUserRepository struct {
+	sqlc.Querier
+}
+
+func NewUserRepository(conn *pgxpool.Pool) *PsqlUserRepository {
+	return &PsqlUserRepository{Querier: sqlc.New(conn)}
+}
+
+func (r *PsqlUserRepository) Create(ctx context.Context, email, passwordHash string) (*models.User, error) {
+	ctx, span := o11y.Tracer().Start(ctx, "PsqlUserRepository.Create")
+	defer span.End()
+
+	created, err := r.CreateUser(ctx, sqlc.CreateUserParams{Email: email, PasswordHash: passwordHash})
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" { // unique_violation
+		return nil, errs.Wrap(errs.ErrConflict, err, "the email is already registered")
+	}
+	if err != nil {
+		return nil, mapError(err)
+	}
+	return &toUserCredentials(created).User, nil
+}
+
+func (r *PsqlUserRepository) GetByEmail(ctx context.Context, email string) (*models.UserCredentials, error) {
+	ctx, span := o11y.Tracer().Start(ctx, "PsqlUserRepository.GetByEmail")
+	defer span.End()
+
+	user, err := r.GetUserByEmail(ctx, email)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, errs.NotFound("user not found")
+	}
+	if err != nil {
+		return nil, mapError(err)
+	}
+	return toUserCredentials(user), nil
+}
+
+func toUserCredentials(u sqlc.User) *models.UserCredentials {
+	return &models.UserCredentials{
+		User: models.User{
+			ID:        u.ID,
+			Email:     u.Email,
+			CreatedAt: u.CreatedAt,
+		},
+		PasswordHash: u.PasswordHash,
+	}
+}
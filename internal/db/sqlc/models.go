@@ -5,15 +5,46 @@
 package sqlc
 
 import (
+	"time"
+
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type ApiKey struct {
+	ID         int64              `json:"id"`
+	Name       string             `json:"name"`
+	Prefix     string             `json:"prefix"`
+	KeyHash    []byte             `json:"key_hash"`
+	Scopes     []string           `json:"scopes"`
+	CreatedAt  time.Time          `json:"created_at"`
+	ExpiresAt  pgtype.Timestamptz `json:"expires_at"`
+	LastUsedAt pgtype.Timestamptz `json:"last_used_at"`
+	RevokedAt  pgtype.Timestamptz `json:"revoked_at"`
+}
+
 type Movie struct {
-	ID          int64          `json:"id"`
-	Title       string         `json:"title"`
-	Description pgtype.Text    `json:"description"`
-	ReleaseYear int32          `json:"release_year"`
-	Genre       []string       `json:"genre"`
-	Director    pgtype.Text    `json:"director"`
-	Rating      pgtype.Numeric `json:"rating"`
+	ID           int64              `json:"id"`
+	Title        string             `json:"title"`
+	Description  pgtype.Text        `json:"description"`
+	ReleaseYear  int32              `json:"release_year"`
+	Genre        []string           `json:"genre"`
+	Director     pgtype.Text        `json:"director"`
+	Rating       pgtype.Numeric     `json:"rating"`
+	SearchVector string             `json:"search_vector"`
+	UpdatedAt    time.Time          `json:"updated_at"`
+	Version      int64              `json:"version"`
+	DeletedAt    pgtype.Timestamptz `json:"deleted_at"`
+}
+
+type RoleBinding struct {
+	Subject   string    `json:"subject"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type User struct {
+	ID           int64     `json:"id"`
+	Email        string    `json:"email"`
+	PasswordHash string    `json:"password_hash"`
+	CreatedAt    time.Time `json:"created_at"`
 }
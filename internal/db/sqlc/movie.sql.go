@@ -7,16 +7,37 @@ package sqlc
 
 import (
 	"context"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type CopyMoviesParams struct {
+	Title       string         `json:"title"`
+	Description pgtype.Text    `json:"description"`
+	ReleaseYear int32          `json:"release_year"`
+	Genre       []string       `json:"genre"`
+	Director    pgtype.Text    `json:"director"`
+	Rating      pgtype.Numeric `json:"rating"`
+}
+
+const countTrashedMovies = `-- name: CountTrashedMovies :one
+SELECT count(*) FROM movies WHERE deleted_at IS NOT NULL
+`
+
+func (q *Queries) CountTrashedMovies(ctx context.Context) (int64, error) {
+	row := q.db.QueryRow(ctx, countTrashedMovies)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createMovie = `-- name: CreateMovie :one
 INSERT INTO movies (
     title, description, release_year, genre, director, rating
 ) VALUES (
     $1, $2, $3, $4, $5, $6
-) RETURNING id, title, description, release_year, genre, director, rating
+) RETURNING id, title, description, release_year, genre, director, rating, updated_at, version, deleted_at
 `
 
 type CreateMovieParams struct {
@@ -28,7 +49,20 @@ type CreateMovieParams struct {
 	Rating      pgtype.Numeric `json:"rating"`
 }
 
-func (q *Queries) CreateMovie(ctx context.Context, arg CreateMovieParams) (Movie, error) {
+type CreateMovieRow struct {
+	ID          int64              `json:"id"`
+	Title       string             `json:"title"`
+	Description pgtype.Text        `json:"description"`
+	ReleaseYear int32              `json:"release_year"`
+	Genre       []string           `json:"genre"`
+	Director    pgtype.Text        `json:"director"`
+	Rating      pgtype.Numeric     `json:"rating"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+	Version     int64              `json:"version"`
+	DeletedAt   pgtype.Timestamptz `json:"deleted_at"`
+}
+
+func (q *Queries) CreateMovie(ctx context.Context, arg CreateMovieParams) (CreateMovieRow, error) {
 	row := q.db.QueryRow(ctx, createMovie,
 		arg.Title,
 		arg.Description,
@@ -37,7 +71,7 @@ func (q *Queries) CreateMovie(ctx context.Context, arg CreateMovieParams) (Movie
 		arg.Director,
 		arg.Rating,
 	)
-	var i Movie
+	var i CreateMovieRow
 	err := row.Scan(
 		&i.ID,
 		&i.Title,
@@ -46,26 +80,174 @@ func (q *Queries) CreateMovie(ctx context.Context, arg CreateMovieParams) (Movie
 		&i.Genre,
 		&i.Director,
 		&i.Rating,
+		&i.UpdatedAt,
+		&i.Version,
+		&i.DeletedAt,
 	)
 	return i, err
 }
 
-const deleteMovie = `-- name: DeleteMovie :exec
-DELETE FROM movies WHERE id = $1
+const deleteMovie = `-- name: DeleteMovie :execrows
+UPDATE movies SET
+    deleted_at = now(),
+    updated_at = now(),
+    version = version + 1
+WHERE id = $1
+  AND deleted_at IS NULL
+  AND ($2::bigint IS NULL OR version = $2)
 `
 
-func (q *Queries) DeleteMovie(ctx context.Context, id int64) error {
-	_, err := q.db.Exec(ctx, deleteMovie, id)
-	return err
+type DeleteMovieParams struct {
+	ID              int64       `json:"id"`
+	ExpectedVersion pgtype.Int8 `json:"expected_version"`
+}
+
+func (q *Queries) DeleteMovie(ctx context.Context, arg DeleteMovieParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteMovie, arg.ID, arg.ExpectedVersion)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
 }
 
 const getMovieByID = `-- name: GetMovieByID :one
-SELECT id, title, description, release_year, genre, director, rating FROM movies WHERE id = $1
+SELECT id, title, description, release_year, genre, director, rating, updated_at, version, deleted_at FROM movies WHERE id = $1 AND deleted_at IS NULL
 `
 
-func (q *Queries) GetMovieByID(ctx context.Context, id int64) (Movie, error) {
+type GetMovieByIDRow struct {
+	ID          int64              `json:"id"`
+	Title       string             `json:"title"`
+	Description pgtype.Text        `json:"description"`
+	ReleaseYear int32              `json:"release_year"`
+	Genre       []string           `json:"genre"`
+	Director    pgtype.Text        `json:"director"`
+	Rating      pgtype.Numeric     `json:"rating"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+	Version     int64              `json:"version"`
+	DeletedAt   pgtype.Timestamptz `json:"deleted_at"`
+}
+
+func (q *Queries) GetMovieByID(ctx context.Context, id int64) (GetMovieByIDRow, error) {
 	row := q.db.QueryRow(ctx, getMovieByID, id)
-	var i Movie
+	var i GetMovieByIDRow
+	err := row.Scan(
+		&i.ID,
+		&i.Title,
+		&i.Description,
+		&i.ReleaseYear,
+		&i.Genre,
+		&i.Director,
+		&i.Rating,
+		&i.UpdatedAt,
+		&i.Version,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const listTrashedMovies = `-- name: ListTrashedMovies :many
+SELECT id, title, description, release_year, genre, director, rating, updated_at, version, deleted_at FROM movies
+WHERE deleted_at IS NOT NULL
+ORDER BY deleted_at DESC, id DESC
+LIMIT $1 OFFSET $2
+`
+
+type ListTrashedMoviesParams struct {
+	RowLimit  int32 `json:"row_limit"`
+	RowOffset int32 `json:"row_offset"`
+}
+
+type ListTrashedMoviesRow struct {
+	ID          int64              `json:"id"`
+	Title       string             `json:"title"`
+	Description pgtype.Text        `json:"description"`
+	ReleaseYear int32              `json:"release_year"`
+	Genre       []string           `json:"genre"`
+	Director    pgtype.Text        `json:"director"`
+	Rating      pgtype.Numeric     `json:"rating"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+	Version     int64              `json:"version"`
+	DeletedAt   pgtype.Timestamptz `json:"deleted_at"`
+}
+
+func (q *Queries) ListTrashedMovies(ctx context.Context, arg ListTrashedMoviesParams) ([]ListTrashedMoviesRow, error) {
+	rows, err := q.db.Query(ctx, listTrashedMovies, arg.RowLimit, arg.RowOffset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListTrashedMoviesRow{}
+	for rows.Next() {
+		var i ListTrashedMoviesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Description,
+			&i.ReleaseYear,
+			&i.Genre,
+			&i.Director,
+			&i.Rating,
+			&i.UpdatedAt,
+			&i.Version,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const purgeMovies = `-- name: PurgeMovies :execrows
+DELETE FROM movies
+WHERE id IN (
+    SELECT id FROM movies
+    WHERE deleted_at < $1::timestamptz
+    LIMIT $2
+)
+`
+
+type PurgeMoviesParams struct {
+	DeletedBefore time.Time `json:"deleted_before"`
+	BatchSize     int32     `json:"batch_size"`
+}
+
+func (q *Queries) PurgeMovies(ctx context.Context, arg PurgeMoviesParams) (int64, error) {
+	result, err := q.db.Exec(ctx, purgeMovies, arg.DeletedBefore, arg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const restoreMovie = `-- name: RestoreMovie :one
+UPDATE movies SET
+    deleted_at = NULL,
+    updated_at = now(),
+    version = version + 1
+WHERE id = $1 AND deleted_at IS NOT NULL
+RETURNING id, title, description, release_year, genre, director, rating, updated_at, version, deleted_at
+`
+
+type RestoreMovieRow struct {
+	ID          int64              `json:"id"`
+	Title       string             `json:"title"`
+	Description pgtype.Text        `json:"description"`
+	ReleaseYear int32              `json:"release_year"`
+	Genre       []string           `json:"genre"`
+	Director    pgtype.Text        `json:"director"`
+	Rating      pgtype.Numeric     `json:"rating"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+	Version     int64              `json:"version"`
+	DeletedAt   pgtype.Timestamptz `json:"deleted_at"`
+}
+
+func (q *Queries) RestoreMovie(ctx context.Context, id int64) (RestoreMovieRow, error) {
+	row := q.db.QueryRow(ctx, restoreMovie, id)
+	var i RestoreMovieRow
 	err := row.Scan(
 		&i.ID,
 		&i.Title,
@@ -74,28 +256,67 @@ func (q *Queries) GetMovieByID(ctx context.Context, id int64) (Movie, error) {
 		&i.Genre,
 		&i.Director,
 		&i.Rating,
+		&i.UpdatedAt,
+		&i.Version,
+		&i.DeletedAt,
 	)
 	return i, err
 }
 
-const listMovies = `-- name: ListMovies :many
-SELECT id, title, description, release_year, genre, director, rating FROM movies ORDER BY id DESC LIMIT $1 OFFSET $2
+const searchMovies = `-- name: SearchMovies :many
+SELECT
+    m.id, m.title, m.description, m.release_year, m.genre, m.director, m.rating, m.updated_at, m.version,
+    ts_rank(m.search_vector, q.query)::float8 AS rank,
+    ts_headline(
+        $1::text::regconfig,
+        replace(replace(replace(replace(replace(coalesce(m.description, ''),
+            '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;'), '''', '&#39;'),
+        q.query,
+        'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=20, MinWords=5'
+    ) AS headline
+FROM movies m, websearch_to_tsquery($1::text::regconfig, $2::text) AS q(query)
+WHERE m.search_vector @@ q.query AND m.deleted_at IS NULL
+ORDER BY rank DESC, m.id DESC
+LIMIT $3 OFFSET $4
 `
 
-type ListMoviesParams struct {
-	Limit  int32 `json:"limit"`
-	Offset int32 `json:"offset"`
+type SearchMoviesParams struct {
+	Config    string `json:"config"`
+	Query     string `json:"query"`
+	RowLimit  int32  `json:"row_limit"`
+	RowOffset int32  `json:"row_offset"`
 }
 
-func (q *Queries) ListMovies(ctx context.Context, arg ListMoviesParams) ([]Movie, error) {
-	rows, err := q.db.Query(ctx, listMovies, arg.Limit, arg.Offset)
+type SearchMoviesRow struct {
+	ID          int64          `json:"id"`
+	Title       string         `json:"title"`
+	Description pgtype.Text    `json:"description"`
+	ReleaseYear int32          `json:"release_year"`
+	Genre       []string       `json:"genre"`
+	Director    pgtype.Text    `json:"director"`
+	Rating      pgtype.Numeric `json:"rating"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	Version     int64          `json:"version"`
+	Rank        float64        `json:"rank"`
+	Headline    string         `json:"headline"`
+}
+
+// The description is HTML-escaped before it is highlighted, so that the headline is safe HTML
+// whose only tags are the <mark> around the matches.
+func (q *Queries) SearchMovies(ctx context.Context, arg SearchMoviesParams) ([]SearchMoviesRow, error) {
+	rows, err := q.db.Query(ctx, searchMovies,
+		arg.Config,
+		arg.Query,
+		arg.RowLimit,
+		arg.RowOffset,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	items := []Movie{}
+	items := []SearchMoviesRow{}
 	for rows.Next() {
-		var i Movie
+		var i SearchMoviesRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.Title,
@@ -104,6 +325,10 @@ func (q *Queries) ListMovies(ctx context.Context, arg ListMoviesParams) ([]Movie
 			&i.Genre,
 			&i.Director,
 			&i.Rating,
+			&i.UpdatedAt,
+			&i.Version,
+			&i.Rank,
+			&i.Headline,
 		); err != nil {
 			return nil, err
 		}
@@ -115,6 +340,63 @@ func (q *Queries) ListMovies(ctx context.Context, arg ListMoviesParams) ([]Movie
 	return items, nil
 }
 
+const searchVectorConfigMatches = `-- name: SearchVectorConfigMatches :one
+SELECT strpos(
+    pg_get_expr(d.adbin, d.adrelid),
+    'to_tsvector(' || quote_literal($1::text::regconfig::text) || '::regconfig'
+) > 0 AS matches
+FROM pg_attrdef d
+JOIN pg_attribute a ON a.attrelid = d.adrelid AND a.attnum = d.adnum
+WHERE d.adrelid = 'movies'::regclass AND a.attname = 'search_vector'
+`
+
+// Reports whether movies.search_vector is generated with the text search configuration.
+func (q *Queries) SearchVectorConfigMatches(ctx context.Context, config string) (bool, error) {
+	row := q.db.QueryRow(ctx, searchVectorConfigMatches, config)
+	var matches bool
+	err := row.Scan(&matches)
+	return matches, err
+}
+
+const suggestMovies = `-- name: SuggestMovies :many
+SELECT id, title, word_similarity($1::text, title)::float8 AS similarity
+FROM movies
+WHERE $1::text <% title AND deleted_at IS NULL
+ORDER BY similarity DESC, title
+LIMIT $2
+`
+
+type SuggestMoviesParams struct {
+	Prefix   string `json:"prefix"`
+	RowLimit int32  `json:"row_limit"`
+}
+
+type SuggestMoviesRow struct {
+	ID         int64   `json:"id"`
+	Title      string  `json:"title"`
+	Similarity float64 `json:"similarity"`
+}
+
+func (q *Queries) SuggestMovies(ctx context.Context, arg SuggestMoviesParams) ([]SuggestMoviesRow, error) {
+	rows, err := q.db.Query(ctx, suggestMovies, arg.Prefix, arg.RowLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []SuggestMoviesRow{}
+	for rows.Next() {
+		var i SuggestMoviesRow
+		if err := rows.Scan(&i.ID, &i.Title, &i.Similarity); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const updateMovie = `-- name: UpdateMovie :one
 UPDATE movies SET
     title = $2,
@@ -122,22 +404,40 @@ UPDATE movies SET
     release_year = $4,
     genre = $5,
     director = $6,
-    rating = $7
+    rating = $7,
+    updated_at = now(),
+    version = version + 1
 WHERE id = $1
-RETURNING id, title, description, release_year, genre, director, rating
+  AND deleted_at IS NULL
+  AND ($8::bigint IS NULL OR version = $8)
+RETURNING id, title, description, release_year, genre, director, rating, updated_at, version, deleted_at
 `
 
 type UpdateMovieParams struct {
-	ID          int64          `json:"id"`
-	Title       string         `json:"title"`
-	Description pgtype.Text    `json:"description"`
-	ReleaseYear int32          `json:"release_year"`
-	Genre       []string       `json:"genre"`
-	Director    pgtype.Text    `json:"director"`
-	Rating      pgtype.Numeric `json:"rating"`
+	ID              int64          `json:"id"`
+	Title           string         `json:"title"`
+	Description     pgtype.Text    `json:"description"`
+	ReleaseYear     int32          `json:"release_year"`
+	Genre           []string       `json:"genre"`
+	Director        pgtype.Text    `json:"director"`
+	Rating          pgtype.Numeric `json:"rating"`
+	ExpectedVersion pgtype.Int8    `json:"expected_version"`
+}
+
+type UpdateMovieRow struct {
+	ID          int64              `json:"id"`
+	Title       string             `json:"title"`
+	Description pgtype.Text        `json:"description"`
+	ReleaseYear int32              `json:"release_year"`
+	Genre       []string           `json:"genre"`
+	Director    pgtype.Text        `json:"director"`
+	Rating      pgtype.Numeric     `json:"rating"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+	Version     int64              `json:"version"`
+	DeletedAt   pgtype.Timestamptz `json:"deleted_at"`
 }
 
-func (q *Queries) UpdateMovie(ctx context.Context, arg UpdateMovieParams) (Movie, error) {
+func (q *Queries) UpdateMovie(ctx context.Context, arg UpdateMovieParams) (UpdateMovieRow, error) {
 	row := q.db.QueryRow(ctx, updateMovie,
 		arg.ID,
 		arg.Title,
@@ -146,8 +446,9 @@ func (q *Queries) UpdateMovie(ctx context.Context, arg UpdateMovieParams) (Movie
 		arg.Genre,
 		arg.Director,
 		arg.Rating,
+		arg.ExpectedVersion,
 	)
-	var i Movie
+	var i UpdateMovieRow
 	err := row.Scan(
 		&i.ID,
 		&i.Title,
@@ -156,6 +457,9 @@ func (q *Queries) UpdateMovie(ctx context.Context, arg UpdateMovieParams) (Movie
 		&i.Genre,
 		&i.Director,
 		&i.Rating,
+		&i.UpdatedAt,
+		&i.Version,
+		&i.DeletedAt,
 	)
 	return i, err
 }
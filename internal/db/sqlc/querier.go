@@ -9,11 +9,30 @@ import (
 )
 
 type Querier interface {
-	CreateMovie(ctx context.Context, arg CreateMovieParams) (Movie, error)
-	DeleteMovie(ctx context.Context, id int64) error
-	GetMovieByID(ctx context.Context, id int64) (Movie, error)
-	ListMovies(ctx context.Context, arg ListMoviesParams) ([]Movie, error)
-	UpdateMovie(ctx context.Context, arg UpdateMovieParams) (Movie, error)
+	CopyMovies(ctx context.Context, arg []CopyMoviesParams) (int64, error)
+	CountTrashedMovies(ctx context.Context) (int64, error)
+	CreateAPIKey(ctx context.Context, arg CreateAPIKeyParams) (ApiKey, error)
+	CreateMovie(ctx context.Context, arg CreateMovieParams) (CreateMovieRow, error)
+	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	DeleteMovie(ctx context.Context, arg DeleteMovieParams) (int64, error)
+	GetAPIKeyByHash(ctx context.Context, keyHash []byte) (ApiKey, error)
+	GetMovieByID(ctx context.Context, id int64) (GetMovieByIDRow, error)
+	GetUserByEmail(ctx context.Context, email string) (User, error)
+	ListAPIKeys(ctx context.Context) ([]ApiKey, error)
+	ListRolesBySubject(ctx context.Context, subject string) ([]string, error)
+	ListTrashedMovies(ctx context.Context, arg ListTrashedMoviesParams) ([]ListTrashedMoviesRow, error)
+	PurgeMovies(ctx context.Context, arg PurgeMoviesParams) (int64, error)
+	RestoreMovie(ctx context.Context, id int64) (RestoreMovieRow, error)
+	// Revoking a key again keeps its first revocation time.
+	RevokeAPIKey(ctx context.Context, id int64) (int64, error)
+	// The description is HTML-escaped before it is highlighted, so that the headline is safe HTML
+	// whose only tags are the <mark> around the matches.
+	SearchMovies(ctx context.Context, arg SearchMoviesParams) ([]SearchMoviesRow, error)
+	// Reports whether movies.search_vector is generated with the text search configuration.
+	SearchVectorConfigMatches(ctx context.Context, config string) (bool, error)
+	SuggestMovies(ctx context.Context, arg SuggestMoviesParams) ([]SuggestMoviesRow, error)
+	TouchAPIKey(ctx context.Context, id int64) error
+	UpdateMovie(ctx context.Context, arg UpdateMovieParams) (UpdateMovieRow, error)
 }
 
 var _ Querier = (*Queries)(nil)
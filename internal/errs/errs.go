@@ -0,0 +1,118 @@
+// Package errs defines the domain errors emitted by repositories and propagated by services.
+//
+// Every error carries a kind (one of the Err* sentinels) so that callers can branch with
+// errors.Is, and a message that is safe to show to API clients.
+package errs
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrNotFound             = errors.New("not found")
+	ErrConflict             = errors.New("conflict")
+	ErrValidation           = errors.New("validation failed")
+	ErrPreconditionFailed   = errors.New("precondition failed")
+	ErrPreconditionRequired = errors.New("precondition required")
+	ErrUnavailable          = errors.New("service unavailable")
+	ErrUnauthorized         = errors.New("unauthorized")
+	ErrForbidden            = errors.New("forbidden")
+	ErrTooManyRequests      = errors.New("too many requests")
+)
+
+// Error is a domain error of a given kind, optionally wrapping the error that caused it.
+type Error struct {
+	Kind    error
+	Message string
+	Err     error
+	// RetryAfter, when set, is how long the client should wait before trying again.
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+// Unwrap exposes both the kind and the cause to errors.Is and errors.As.
+func (e *Error) Unwrap() []error {
+	if e.Err != nil {
+		return []error{e.Kind, e.Err}
+	}
+	return []error{e.Kind}
+}
+
+// Wrap returns a domain error of the given kind caused by err.
+func Wrap(kind, err error, format string, args ...any) error {
+	return &Error{Kind: kind, Message: fmt.Sprintf(format, args...), Err: err}
+}
+
+// NotFound returns an ErrNotFound domain error.
+func NotFound(format string, args ...any) error {
+	return Wrap(ErrNotFound, nil, format, args...)
+}
+
+// Conflict returns an ErrConflict domain error.
+func Conflict(format string, args ...any) error {
+	return Wrap(ErrConflict, nil, format, args...)
+}
+
+// Validation returns an ErrValidation domain error.
+func Validation(format string, args ...any) error {
+	return Wrap(ErrValidation, nil, format, args...)
+}
+
+// PreconditionFailed returns an ErrPreconditionFailed domain error.
+func PreconditionFailed(format string, args ...any) error {
+	return Wrap(ErrPreconditionFailed, nil, format, args...)
+}
+
+// PreconditionRequired returns an ErrPreconditionRequired domain error.
+func PreconditionRequired(format string, args ...any) error {
+	return Wrap(ErrPreconditionRequired, nil, format, args...)
+}
+
+// Unavailable returns an ErrUnavailable domain error caused by err.
+func Unavailable(err error, format string, args ...any) error {
+	return Wrap(ErrUnavailable, err, format, args...)
+}
+
+// Unauthorized returns an ErrUnauthorized domain error.
+func Unauthorized(format string, args ...any) error {
+	return Wrap(ErrUnauthorized, nil, format, args...)
+}
+
+// Forbidden returns an ErrForbidden domain error.
+func Forbidden(format string, args ...any) error {
+	return Wrap(ErrForbidden, nil, format, args...)
+}
+
+// TooManyRequests returns an ErrTooManyRequests domain error telling the client to retry
+// after retryAfter.
+func TooManyRequests(retryAfter time.Duration, format string, args ...any) error {
+	return &Error{Kind: ErrTooManyRequests, Message: fmt.Sprintf(format, args...), RetryAfter: retryAfter}
+}
+
+// RetryAfter returns how long the first domain error in err's chain asks the client to wait,
+// or zero.
+func RetryAfter(err error) time.Duration {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.RetryAfter
+	}
+	return 0
+}
+
+// Message returns the client-safe message of the first domain error in err's chain.
+// It returns an empty string when err is not a domain error.
+func Message(err error) string {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Message
+	}
+	return ""
+}
@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"net/http"
+	"path"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mexirica/chi-template/internal/errs"
+	"github.com/mexirica/chi-template/internal/helpers"
+	"github.com/mexirica/chi-template/internal/models"
+	"github.com/mexirica/chi-template/internal/o11y"
+	"github.com/mexirica/chi-template/internal/service"
+	"github.com/mexirica/chi-template/internal/validation"
+)
+
+type APIKeyHandler struct {
+	s service.APIKeys
+}
+
+func NewAPIKeyHandler(service service.APIKeys) *APIKeyHandler {
+	return &APIKeyHandler{s: service}
+}
+
+// CreateAPIKey godoc
+// @Summary Create an API key
+// @Description Create an API key for a machine client, sent in the X-API-Key header. The key is only returned
+// @Description by this request: store it safely, it cannot be retrieved again. A key can only be granted scopes
+// @Description the caller holds, and never api_keys:manage.
+// @Tags api-keys
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param key body models.CreateAPIKeyRequest true "API key to create"
+// @Success 201 {object} models.CreatedAPIKey
+// @Header 201 {string} Location "URL of the created API key"
+// @Failure 400 {object} types.Problem
+// @Failure 401 {object} types.Problem
+// @Failure 403 {object} types.Problem "The caller lacks the permission of the route or a requested scope"
+// @Router /admin/api-keys [post]
+func (h *APIKeyHandler) Create(w http.ResponseWriter, r *http.Request) {
+	ctx, span := o11y.Tracer().Start(r.Context(), "APIKeyHandler.Create")
+	defer span.End()
+
+	var payload models.CreateAPIKeyRequest
+	if _, err := validation.BindAndValidate(r, &payload); err != nil {
+		helpers.ErrorJSON(w, r, err)
+		return
+	}
+
+	created, err := h.s.Create(ctx, payload)
+	if err != nil {
+		helpers.ErrorJSON(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", path.Join(r.URL.Path, strconv.FormatInt(created.ID, 10)))
+	helpers.WriteJSON(w, http.StatusCreated, created)
+}
+
+// ListAPIKeys godoc
+// @Summary List API keys
+// @Description List every API key, revoked and expired ones included. The keys themselves are not shown.
+// @Tags api-keys
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} models.APIKey
+// @Failure 401 {object} types.Problem
+// @Failure 403 {object} types.Problem "The caller lacks the permission of the route"
+// @Router /admin/api-keys [get]
+func (h *APIKeyHandler) List(w http.ResponseWriter, r *http.Request) {
+	ctx, span := o11y.Tracer().Start(r.Context(), "APIKeyHandler.List")
+	defer span.End()
+
+	keys, err := h.s.List(ctx)
+	if err != nil {
+		helpers.ErrorJSON(w, r, err)
+		return
+	}
+
+	helpers.WriteJSON(w, http.StatusOK, keys)
+}
+
+// RevokeAPIKey godoc
+// @Summary Revoke an API key
+// @Description Revoke an API key; requests sent with it are rejected from now on. Revoking a revoked key is a no-op.
+// @Tags api-keys
+// @Security BearerAuth
+// @Param id path int true "API key ID"
+// @Success 204
+// @Failure 400 {object} types.Problem
+// @Failure 401 {object} types.Problem
+// @Failure 403 {object} types.Problem "The caller lacks the permission of the route"
+// @Failure 404 {object} types.Problem
+// @Router /admin/api-keys/{id} [delete]
+func (h *APIKeyHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	ctx, span := o11y.Tracer().Start(r.Context(), "APIKeyHandler.Revoke")
+	defer span.End()
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		helpers.ErrorJSON(w, r, errs.Wrap(errs.ErrValidation, err, "invalid API key id"))
+		return
+	}
+
+	if err := h.s.Revoke(ctx, id); err != nil {
+		helpers.ErrorJSON(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
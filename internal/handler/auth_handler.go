@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"net/http"
+	"net/netip"
+
+	"github.com/mexirica/chi-template/internal/helpers"
+	"github.com/mexirica/chi-template/internal/middleware"
+	"github.com/mexirica/chi-template/internal/models"
+	"github.com/mexirica/chi-template/internal/o11y"
+	"github.com/mexirica/chi-template/internal/service"
+	"github.com/mexirica/chi-template/internal/validation"
+)
+
+type AuthHandler struct {
+	s service.Accounts
+	// trustedProxies are trusted to tell the client IP address, which failed logins are
+	// counted by.
+	trustedProxies []netip.Prefix
+}
+
+func NewAuthHandler(service service.Accounts, trustedProxies []netip.Prefix) *AuthHandler {
+	return &AuthHandler{s: service, trustedProxies: trustedProxies}
+}
+
+// Register godoc
+// @Summary Register a user
+// @Description Create a user account. Emails are case-insensitive.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param user body models.RegisterRequest true "Account to create"
+// @Success 201 {object} models.User
+// @Failure 400 {object} types.Problem
+// @Failure 409 {object} types.Problem "The email is already registered"
+// @Router /auth/register [post]
+func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
+	ctx, span := o11y.Tracer().Start(r.Context(), "AuthHandler.Register")
+	defer span.End()
+
+	var payload models.RegisterRequest
+	if _, err := validation.BindAndValidate(r, &payload); err != nil {
+		helpers.ErrorJSON(w, r, err)
+		return
+	}
+
+	user, err := h.s.Register(ctx, payload)
+	if err != nil {
+		helpers.ErrorJSON(w, r, err)
+		return
+	}
+
+	helpers.WriteJSON(w, http.StatusCreated, user)
+}
+
+// Login godoc
+// @Summary Log in
+// @Description Start a session: returns a short-lived access token, sent as a bearer token, and a refresh token
+// @Description to obtain the next one. Too many failed logins lock the client out of the account for a while.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param credentials body models.LoginRequest true "Credentials"
+// @Success 200 {object} models.TokenResponse
+// @Failure 400 {object} types.Problem
+// @Failure 401 {object} types.Problem "The email or password is incorrect"
+// @Failure 429 {object} types.Problem "The account is locked out"
+// @Header 429 {integer} Retry-After "Seconds until the lockout ends"
+// @Router /auth/login [post]
+func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
+	ctx, span := o11y.Tracer().Start(r.Context(), "AuthHandler.Login")
+	defer span.End()
+
+	var payload models.LoginRequest
+	if _, err := validation.BindAndValidate(r, &payload); err != nil {
+		helpers.ErrorJSON(w, r, err)
+		return
+	}
+
+	tokens, err := h.s.Login(ctx, payload, middleware.ClientIP(r, h.trustedProxies).String())
+	if err != nil {
+		helpers.ErrorJSON(w, r, err)
+		return
+	}
+
+	writeTokens(w, tokens)
+}
+
+// Refresh godoc
+// @Summary Refresh a session
+// @Description Exchange a refresh token for a new access token and refresh token. Each refresh token works once:
+// @Description presenting it again ends the session.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param token body models.RefreshRequest true "Refresh token"
+// @Success 200 {object} models.TokenResponse
+// @Failure 400 {object} types.Problem
+// @Failure 401 {object} types.Problem "The refresh token is invalid, expired or already used"
+// @Router /auth/refresh [post]
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	ctx, span := o11y.Tracer().Start(r.Context(), "AuthHandler.Refresh")
+	defer span.End()
+
+	var payload models.RefreshRequest
+	if _, err := validation.BindAndValidate(r, &payload); err != nil {
+		helpers.ErrorJSON(w, r, err)
+		return
+	}
+
+	tokens, err := h.s.Refresh(ctx, payload.RefreshToken)
+	if err != nil {
+		helpers.ErrorJSON(w, r, err)
+		return
+	}
+
+	writeTokens(w, tokens)
+}
+
+// Logout godoc
+// @Summary Log out
+// @Description End the session of the refresh token. Access tokens already issued stay valid until they expire.
+// @Tags auth
+// @Accept json
+// @Param token body models.RefreshRequest true "Refresh token"
+// @Success 204
+// @Failure 400 {object} types.Problem
+// @Router /auth/logout [post]
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	ctx, span := o11y.Tracer().Start(r.Context(), "AuthHandler.Logout")
+	defer span.End()
+
+	var payload models.RefreshRequest
+	if _, err := validation.BindAndValidate(r, &payload); err != nil {
+		helpers.ErrorJSON(w, r, err)
+		return
+	}
+
+	if err := h.s.Logout(ctx, payload.RefreshToken); err != nil {
+		helpers.ErrorJSON(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeTokens answers with the tokens, which must not be cached (RFC 6749, section 5.1).
+func writeTokens(w http.ResponseWriter, tokens *models.TokenResponse) {
+	w.Header().Set("Cache-Control", "no-store")
+	helpers.WriteJSON(w, http.StatusOK, tokens)
+}
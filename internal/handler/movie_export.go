@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/mexirica/chi-template/internal/helpers"
+	"github.com/mexirica/chi-template/internal/models"
+)
+
+const jsonContentType = "application/json"
+
+// exportFormats are the representations of GET /movies/export, in order of preference.
+var exportFormats = []string{ndjsonContentType, csvContentType, jsonContentType}
+
+// exportExtensions name the downloaded file for each format.
+var exportExtensions = map[string]string{
+	ndjsonContentType: "ndjson",
+	csvContentType:    "csv",
+	jsonContentType:   "json",
+}
+
+// recordWriter streams records in one export format. Close finishes the document.
+type recordWriter interface {
+	Write(v any) error
+	Close() error
+}
+
+func newRecordWriter(format string, w io.Writer) recordWriter {
+	switch format {
+	case csvContentType:
+		return newCSVMovieWriter(w)
+	case jsonContentType:
+		return helpers.NewJSONArrayWriter(w)
+	default:
+		return helpers.NewNDJSONWriter(w)
+	}
+}
+
+// csvMovieWriter writes movies as CSV with an id column followed by the importColumns, so an
+// export can be edited and imported again.
+type csvMovieWriter struct {
+	w      *csv.Writer
+	record []string
+	header bool
+}
+
+func newCSVMovieWriter(w io.Writer) *csvMovieWriter {
+	return &csvMovieWriter{w: csv.NewWriter(w), record: make([]string, 0, len(importColumns)+1)}
+}
+
+func (c *csvMovieWriter) Write(v any) error {
+	movie, ok := v.(models.Movie)
+	if !ok {
+		return fmt.Errorf("csv export: unexpected record %T", v)
+	}
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+
+	c.record = append(c.record[:0],
+		strconv.FormatInt(movie.ID, 10),
+		movie.Title,
+		movie.Description,
+		strconv.Itoa(movie.ReleaseYear),
+		strings.Join(movie.Genre, genreSeparator),
+		movie.Director,
+		strconv.FormatFloat(movie.Rating, 'f', -1, 64),
+	)
+	return c.w.Write(c.record)
+}
+
+func (c *csvMovieWriter) Close() error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// writeHeader writes the header row once, so empty exports still name their columns.
+func (c *csvMovieWriter) writeHeader() error {
+	if c.header {
+		return nil
+	}
+	c.header = true
+	return c.w.Write(append([]string{"id"}, importColumns...))
+}
+
+// countingWriter counts the bytes that reached the underlying writer.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -1,58 +1,230 @@
-// Package handler provides HTTP handlers for movie-related endpoints.
+// Package handler provides HTTP handlers for the movie and API key endpoints.
 package handler
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"path"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/mexirica/chi-template/internal/errs"
 	"github.com/mexirica/chi-template/internal/helpers"
 	"github.com/mexirica/chi-template/internal/models"
 	"github.com/mexirica/chi-template/internal/o11y"
 	"github.com/mexirica/chi-template/internal/service"
 	"github.com/mexirica/chi-template/internal/validation"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// maxImportBytes bounds the body of POST /movies/import.
+	maxImportBytes = 256 << 20
+	// importTimeout replaces the server read/write timeouts for imports, which take
+	// longer than ordinary requests.
+	importTimeout = 10 * time.Minute
+	// exportTimeout replaces the server write timeout for exports.
+	exportTimeout = 30 * time.Minute
+	// exportFlushEvery is the number of exported movies between flushes to the client.
+	exportFlushEvery = 1000
 )
 
 type MovieHandler struct {
-	s service.Service
+	s              service.Service
+	requireIfMatch bool
 }
 
-func NewMovieHandler(service service.Service) *MovieHandler {
-	return &MovieHandler{
+// Option configures a MovieHandler.
+type Option func(*MovieHandler)
+
+// WithRequireIfMatch makes PUT, PATCH and DELETE on a movie answer 428 Precondition Required
+// unless they carry an If-Match header, so that clients cannot overwrite changes they have
+// not seen.
+func WithRequireIfMatch(require bool) Option {
+	return func(h *MovieHandler) {
+		h.requireIfMatch = require
+	}
+}
+
+func NewMovieHandler(service service.Service, opts ...Option) *MovieHandler {
+	h := &MovieHandler{
 		s: service,
 	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
 }
 
 // CreateMovie godoc
 // @Summary Create a new movie
 // @Description Create a new movie with the provided details
 // @Tags movies
+// @Security BearerAuth
 // @Accept json
 // @Produce json
 // @Param movie body models.CreateMovieRequest true "Movie to create"
+// @Param Idempotency-Key header string false "Unique key making retries of this request safe"
 // @Success 201 {object} models.GetMovieResponse
-// @Failure 400 {object} types.JsonResponse
+// @Header 201 {string} Location "URL of the created movie"
+// @Header 201 {string} ETag "Strong entity tag of the created movie"
+// @Failure 400 {object} types.Problem
+// @Failure 401 {object} types.Problem
+// @Failure 403 {object} types.Problem "The caller lacks the permission of the route"
+// @Failure 409 {object} types.Problem "A request with the same Idempotency-Key is in progress"
+// @Failure 422 {object} types.Problem "The Idempotency-Key was used for a different request"
 // @Router /movies [post]
 func (h *MovieHandler) Create(w http.ResponseWriter, r *http.Request) {
 	ctx, span := o11y.Tracer().Start(r.Context(), "MovieHandler.Create")
 	defer span.End()
 	var payload models.CreateMovieRequest
 
-	errList, err := validation.BindAndValidate(r, payload)
+	if _, err := validation.BindAndValidate(r, &payload); err != nil {
+		helpers.ErrorJSON(w, r, err)
+		return
+	}
+
+	movie, err := h.s.Create(ctx, payload)
 	if err != nil {
-		if errList != nil {
-			helpers.WriteJSON(w, http.StatusBadRequest, errList)
+		helpers.ErrorJSON(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", path.Join(r.URL.Path, strconv.FormatInt(movie.ID, 10)))
+	setValidators(w, movie)
+	helpers.WriteJSON(w, http.StatusCreated, movie)
+}
+
+// ImportMovies godoc
+// @Summary Bulk import movies
+// @Description Import movies from CSV (header row with title, description, release_year, genre, director, rating;
+// @Description genres separated by |) or NDJSON (one movie object per line). Valid rows are inserted in one
+// @Description transaction; invalid rows are skipped and listed in the report. With dry_run nothing is stored.
+// @Tags movies
+// @Security BearerAuth
+// @Accept text/csv
+// @Accept application/x-ndjson
+// @Produce json
+// @Param dry_run query bool false "Validate and report without storing anything"
+// @Param movies body string true "CSV or NDJSON document"
+// @Success 200 {object} models.ImportReport
+// @Failure 400 {object} types.Problem
+// @Failure 401 {object} types.Problem
+// @Failure 403 {object} types.Problem "The caller lacks the permission of the route"
+// @Failure 413 {object} types.Problem
+// @Failure 415 {object} types.Problem
+// @Router /movies/import [post]
+func (h *MovieHandler) Import(w http.ResponseWriter, r *http.Request) {
+	ctx, span := o11y.Tracer().Start(r.Context(), "MovieHandler.Import")
+	defer span.End()
+
+	var dryRun bool
+	if v := r.URL.Query().Get("dry_run"); v != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(v); err != nil {
+			helpers.ErrorJSON(w, r, errs.Validation("dry_run must be a boolean"))
 			return
 		}
 	}
 
-	err = h.s.Create(ctx, payload)
+	rc := http.NewResponseController(w)
+	_ = rc.SetReadDeadline(time.Now().Add(importTimeout))
+	_ = rc.SetWriteDeadline(time.Now().Add(importTimeout))
+
+	src, err := newMovieSource(r.Header.Get("Content-Type"), http.MaxBytesReader(w, r.Body, maxImportBytes))
+	if errors.Is(err, errUnsupportedImportType) {
+		helpers.ErrorJSON(w, r, err, http.StatusUnsupportedMediaType)
+		return
+	}
 	if err != nil {
-		helpers.ErrorJSON(w, err, http.StatusInternalServerError)
+		helpers.ErrorJSON(w, r, err)
 		return
 	}
 
-	helpers.WriteJSON(w, http.StatusCreated, nil)
+	report, err := h.s.Import(ctx, src, dryRun)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		helpers.ErrorJSON(w, r, errs.Wrap(errs.ErrValidation, err, "import exceeds %d bytes", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		helpers.ErrorJSON(w, r, err)
+		return
+	}
+
+	helpers.WriteJSON(w, http.StatusOK, report)
+}
+
+// ExportMovies godoc
+// @Summary Export the movie catalogue
+// @Description Stream every movie in id order as NDJSON (default), CSV or a JSON array, chosen by the Accept header.
+// @Description The CSV export can be imported again through POST /movies/import.
+// @Tags movies
+// @Produce application/x-ndjson
+// @Produce text/csv
+// @Produce json
+// @Success 200 {array} models.Movie
+// @Failure 406 {object} types.Problem
+// @Router /movies/export [get]
+func (h *MovieHandler) Export(w http.ResponseWriter, r *http.Request) {
+	ctx, span := o11y.Tracer().Start(r.Context(), "MovieHandler.Export")
+	defer span.End()
+
+	format := helpers.NegotiateContentType(r, exportFormats...)
+	if format == "" {
+		helpers.ErrorJSON(w, r, errs.Validation("acceptable formats are %s, %s and %s", ndjsonContentType, csvContentType, jsonContentType), http.StatusNotAcceptable)
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Now().Add(exportTimeout))
+
+	// Nothing reaches the client until the buffer first fills, so errors raised before that
+	// can still be reported as a problem response.
+	sent := &countingWriter{w: w}
+	buf := bufio.NewWriterSize(sent, 64*1024)
+	out := newRecordWriter(format, buf)
+
+	w.Header().Set("Content-Type", format)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="movies.%s"`, exportExtensions[format]))
+
+	count := 0
+	err := h.s.Export(ctx, func(movie models.Movie) error {
+		if err := out.Write(movie); err != nil {
+			return err
+		}
+		if count++; count%exportFlushEvery == 0 {
+			if err := buf.Flush(); err != nil {
+				return err
+			}
+			return rc.Flush()
+		}
+		return nil
+	})
+	if err == nil {
+		if err = out.Close(); err == nil {
+			err = buf.Flush()
+		}
+	}
+	if err != nil {
+		if sent.n == 0 {
+			w.Header().Del("Content-Disposition")
+			helpers.ErrorJSON(w, r, err)
+			return
+		}
+		// The status line is gone; all we can do is cut the stream short.
+		log.Error().Err(err).Int("exported", count).Msg("movie export aborted")
+		panic(http.ErrAbortHandler)
+	}
 }
 
 // GetMovie godoc
@@ -61,94 +233,439 @@ func (h *MovieHandler) Create(w http.ResponseWriter, r *http.Request) {
 // @Tags movies
 // @Produce json
 // @Param id path int true "Movie ID"
+// @Param If-None-Match header string false "ETag of a cached copy"
+// @Param If-Modified-Since header string false "Last-Modified of a cached copy"
 // @Success 200 {object} models.GetMovieResponse
-// @Failure 404 {object} types.JsonResponse
+// @Success 304 "The cached copy is still current"
+// @Header 200 {string} ETag "Strong entity tag, changes on every update"
+// @Header 200 {string} Last-Modified "Time of the last update"
+// @Failure 400 {object} types.Problem
+// @Failure 404 {object} types.Problem
 // @Router /movies/{id} [get]
 func (h *MovieHandler) GetById(w http.ResponseWriter, r *http.Request) {
 	ctx, span := o11y.Tracer().Start(r.Context(), "MovieHandler.GetById")
 	defer span.End()
 
-	idStr := chi.URLParam(r, "id")
-	id, err := strconv.Atoi(idStr)
+	id, err := parseID(r)
 	if err != nil {
-		helpers.ErrorJSON(w, err, http.StatusBadRequest)
+		helpers.ErrorJSON(w, r, err)
 		return
 	}
 
 	movie, err := h.s.GetById(ctx, id)
 	if err != nil {
-		helpers.ErrorJSON(w, err, http.StatusInternalServerError)
+		helpers.ErrorJSON(w, r, err)
 		return
 	}
 
-	if movie == nil {
-		helpers.WriteJSON(w, http.StatusNotFound, nil)
+	if helpers.CheckNotModified(w, r, helpers.VersionETag(movie.ID, movie.Version), movie.UpdatedAt) {
 		return
 	}
-
 	helpers.WriteJSON(w, http.StatusOK, movie)
 }
 
 // ListMovies godoc
 // @Summary List movies
-// @Description Get a paginated list of movies
+// @Description Get a paginated list of movies, optionally filtered and sorted.
+// @Description Pages are addressed by page number or, preferably, by the next_cursor/prev_cursor of a previous response.
 // @Tags movies
 // @Produce json
-// @Param limit query int false "Limit"
-// @Param offset query int false "Offset"
+// @Param cursor query string false "Opaque cursor from a previous response; takes precedence over page"
+// @Param page query int false "Page number, starting at 1"
+// @Param limit query int false "Page size (max 100)"
+// @Param genre query []string false "Genres the movie must have (all of them)" collectionFormat(multi)
+// @Param director query string false "Case-insensitive substring of the director"
+// @Param q query string false "Case-insensitive substring of the title"
+// @Param year_from query int false "Minimum release year"
+// @Param year_to query int false "Maximum release year"
+// @Param min_rating query number false "Minimum rating"
+// @Param sort query string false "Comma separated sort keys (id, title, release_year, rating, director); prefix with - for descending" default(-id)
+// @Param If-None-Match header string false "ETag of a cached copy"
 // @Success 200 {object} models.GetMovieList
-// @Router /movies [get]
+// @Success 304 "The cached copy is still current"
+// @Header 200 {integer} X-Total-Count "Number of movies matching the filter"
+// @Header 200 {string} Link "RFC 8288 first, prev, next and last page links"
+// @Header 200 {string} ETag "Strong entity tag of the page"
+// @Failure 400 {object} types.Problem
+// @Router /movies/list [get]
 func (h *MovieHandler) GetList(w http.ResponseWriter, r *http.Request) {
 	ctx, span := o11y.Tracer().Start(r.Context(), "MovieHandler.GetList")
 	defer span.End()
 
-	pageStr := r.URL.Query().Get("page")
-	limitStr := r.URL.Query().Get("limit")
+	filter, err := parseMovieFilter(r)
+	if err != nil {
+		helpers.ErrorJSON(w, r, err)
+		return
+	}
 
-	page, err := strconv.Atoi(pageStr)
-	if err != nil || page < 1 {
-		page = 1
+	movies, err := h.s.GetList(ctx, filter)
+	if err != nil {
+		helpers.ErrorJSON(w, r, err)
+		return
 	}
 
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil || limit < 1 {
-		limit = 10
+	w.Header().Set("X-Total-Count", strconv.FormatInt(movies.Total, 10))
+	w.Header().Set("Link", helpers.LinkHeader(r, paginationLinks(r, filter, movies)...))
+
+	// The listing changes whenever a movie on the page is updated or the page's composition or
+	// total changes, so its ETag is a digest of the whole page. It has no Last-Modified: the
+	// latest update of the listed movies misses deletions and movies leaving the page.
+	etag, err := helpers.JSONETag(movies)
+	if err != nil {
+		helpers.ErrorJSON(w, r, err)
+		return
+	}
+	if helpers.CheckNotModified(w, r, etag, time.Time{}) {
+		return
 	}
+	helpers.WriteJSON(w, http.StatusOK, movies)
+}
 
-	movies, err := h.s.GetList(ctx, page, limit)
+// SearchMovies godoc
+// @Summary Search movies
+// @Description Full-text search over title, director and description, best matches first.
+// @Description The query accepts web search syntax: "quoted phrases", or, and -excluded words.
+// @Tags movies
+// @Produce json
+// @Param q query string true "Search query"
+// @Param page query int false "Page number, starting at 1"
+// @Param limit query int false "Page size (max 100)"
+// @Success 200 {object} models.MovieSearchResults
+// @Failure 400 {object} types.Problem
+// @Router /movies/search [get]
+func (h *MovieHandler) Search(w http.ResponseWriter, r *http.Request) {
+	ctx, span := o11y.Tracer().Start(r.Context(), "MovieHandler.Search")
+	defer span.End()
+
+	search, err := parseMovieSearch(r)
 	if err != nil {
-		helpers.ErrorJSON(w, err, http.StatusInternalServerError)
+		helpers.ErrorJSON(w, r, err)
 		return
 	}
 
-	helpers.WriteJSON(w, http.StatusOK, movies)
+	results, err := h.s.Search(ctx, search)
+	if err != nil {
+		helpers.ErrorJSON(w, r, err)
+		return
+	}
+
+	helpers.WriteJSON(w, http.StatusOK, results)
+}
+
+// SuggestMovies godoc
+// @Summary Autocomplete movie titles
+// @Description Typo-tolerant title suggestions for a partially typed prefix, ranked by trigram similarity.
+// @Tags movies
+// @Produce json
+// @Param prefix query string true "Partially typed title"
+// @Param limit query int false "Number of suggestions (max 20)" default(5)
+// @Success 200 {object} models.MovieSuggestions
+// @Failure 400 {object} types.Problem
+// @Router /movies/suggest [get]
+func (h *MovieHandler) Suggest(w http.ResponseWriter, r *http.Request) {
+	ctx, span := o11y.Tracer().Start(r.Context(), "MovieHandler.Suggest")
+	defer span.End()
+
+	suggest, err := parseMovieSuggest(r)
+	if err != nil {
+		helpers.ErrorJSON(w, r, err)
+		return
+	}
+
+	suggestions, err := h.s.Suggest(ctx, suggest)
+	if err != nil {
+		helpers.ErrorJSON(w, r, err)
+		return
+	}
+
+	helpers.WriteJSON(w, http.StatusOK, suggestions)
+}
+
+// UpdateMovie godoc
+// @Summary Replace a movie
+// @Description Replace every field of a movie by ID
+// @Tags movies
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Movie ID"
+// @Param If-Match header string false "ETag of the movie the change is based on"
+// @Param movie body models.UpdateMovieRequest true "Movie to store"
+// @Success 200 {object} models.GetMovieResponse
+// @Failure 400 {object} types.Problem
+// @Failure 401 {object} types.Problem
+// @Failure 403 {object} types.Problem "The caller lacks the permission of the route"
+// @Failure 404 {object} types.Problem
+// @Failure 412 {object} types.Problem
+// @Failure 428 {object} types.Problem
+// @Router /movies/{id} [put]
+func (h *MovieHandler) Update(w http.ResponseWriter, r *http.Request) {
+	ctx, span := o11y.Tracer().Start(r.Context(), "MovieHandler.Update")
+	defer span.End()
+
+	id, err := parseID(r)
+	if err != nil {
+		helpers.ErrorJSON(w, r, err)
+		return
+	}
+
+	version, err := h.ifMatch(r, id)
+	if err != nil {
+		helpers.ErrorJSON(w, r, err)
+		return
+	}
+
+	var payload models.UpdateMovieRequest
+	if _, err := validation.BindAndValidate(r, &payload); err != nil {
+		helpers.ErrorJSON(w, r, err)
+		return
+	}
+
+	movie, err := h.s.Update(ctx, id, payload, version)
+	if err != nil {
+		helpers.ErrorJSON(w, r, err)
+		return
+	}
+
+	setValidators(w, movie)
+	helpers.WriteJSON(w, http.StatusOK, movie)
+}
+
+// PatchMovie godoc
+// @Summary Partially update a movie
+// @Description Apply a JSON Merge Patch (RFC 7396) to a movie by ID. Fields set to null are reset.
+// @Tags movies
+// @Security BearerAuth
+// @Accept application/merge-patch+json
+// @Produce json
+// @Param id path int true "Movie ID"
+// @Param If-Match header string false "ETag of the movie the change is based on"
+// @Param patch body object true "JSON Merge Patch document"
+// @Success 200 {object} models.GetMovieResponse
+// @Failure 400 {object} types.Problem
+// @Failure 401 {object} types.Problem
+// @Failure 403 {object} types.Problem "The caller lacks the permission of the route"
+// @Failure 404 {object} types.Problem
+// @Failure 409 {object} types.Problem "The movie kept changing while it was patched without If-Match"
+// @Failure 415 {object} types.Problem
+// @Failure 412 {object} types.Problem
+// @Failure 428 {object} types.Problem
+// @Router /movies/{id} [patch]
+func (h *MovieHandler) Patch(w http.ResponseWriter, r *http.Request) {
+	ctx, span := o11y.Tracer().Start(r.Context(), "MovieHandler.Patch")
+	defer span.End()
+
+	id, err := parseID(r)
+	if err != nil {
+		helpers.ErrorJSON(w, r, err)
+		return
+	}
+
+	version, err := h.ifMatch(r, id)
+	if err != nil {
+		helpers.ErrorJSON(w, r, err)
+		return
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != helpers.MergePatchContentType && mediaType != "application/json" {
+		helpers.ErrorJSON(w, r, errs.Validation("content type must be %s", helpers.MergePatchContentType), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	patch, err := io.ReadAll(r.Body)
+	if err != nil {
+		helpers.ErrorJSON(w, r, errs.Wrap(errs.ErrValidation, err, "failed to read request body"))
+		return
+	}
+	if patch = bytes.TrimSpace(patch); len(patch) == 0 || patch[0] != '{' || !json.Valid(patch) {
+		helpers.ErrorJSON(w, r, errs.Validation("patch must be a JSON object"))
+		return
+	}
+
+	movie, err := h.s.Patch(ctx, id, patch, version)
+	if err != nil {
+		helpers.ErrorJSON(w, r, err)
+		return
+	}
+
+	setValidators(w, movie)
+	helpers.WriteJSON(w, http.StatusOK, movie)
 }
 
 // DeleteMovie godoc
 // @Summary Delete a movie
-// @Description Delete a movie by ID
+// @Description Move a movie to the trash. It can be restored until it is purged.
 // @Tags movies
+// @Security BearerAuth
 // @Produce json
 // @Param id path int true "Movie ID"
+// @Param If-Match header string false "ETag of the movie the change is based on"
 // @Success 204 {object} nil
-// @Failure 404 {object} types.JsonResponse
+// @Failure 400 {object} types.Problem
+// @Failure 401 {object} types.Problem
+// @Failure 403 {object} types.Problem "The caller lacks the permission of the route"
+// @Failure 404 {object} types.Problem
+// @Failure 412 {object} types.Problem
+// @Failure 428 {object} types.Problem
 // @Router /movies/{id} [delete]
 func (h *MovieHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	ctx, span := o11y.Tracer().Start(r.Context(), "MovieHandler.Delete")
 	defer span.End()
 
-	idStr := chi.URLParam(r, "id")
-	id, err := strconv.Atoi(idStr)
+	id, err := parseID(r)
+	if err != nil {
+		helpers.ErrorJSON(w, r, err)
+		return
+	}
+
+	version, err := h.ifMatch(r, id)
 	if err != nil {
-		helpers.ErrorJSON(w, err, http.StatusBadRequest)
+		helpers.ErrorJSON(w, r, err)
 		return
 	}
 
-	err = h.s.Delete(ctx, id)
+	err = h.s.Delete(ctx, id, version)
 	if err != nil {
-		helpers.ErrorJSON(w, err, http.StatusInternalServerError)
+		helpers.ErrorJSON(w, r, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// ListTrash godoc
+// @Summary List deleted movies
+// @Description List the movies in the trash, most recently deleted first. Only callers who can restore them can list them.
+// @Tags movies
+// @Security BearerAuth
+// @Produce json
+// @Param page query int false "Page number, starting at 1"
+// @Param limit query int false "Page size (max 100)"
+// @Success 200 {object} models.MovieTrash
+// @Header 200 {integer} X-Total-Count "Number of deleted movies"
+// @Failure 401 {object} types.Problem
+// @Failure 403 {object} types.Problem "The caller lacks the movies:restore permission"
+// @Router /movies/trash [get]
+func (h *MovieHandler) Trash(w http.ResponseWriter, r *http.Request) {
+	ctx, span := o11y.Tracer().Start(r.Context(), "MovieHandler.Trash")
+	defer span.End()
+
+	page, limit := parsePaging(r.URL.Query())
+	trash, err := h.s.Trash(ctx, page, limit)
+	if err != nil {
+		helpers.ErrorJSON(w, r, err)
+		return
+	}
+
+	w.Header().Set("X-Total-Count", strconv.FormatInt(trash.Total, 10))
+	helpers.WriteJSON(w, http.StatusOK, trash)
+}
+
+// RestoreMovie godoc
+// @Summary Restore a deleted movie
+// @Description Take a movie out of the trash.
+// @Tags movies
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Movie ID"
+// @Success 200 {object} models.GetMovieResponse
+// @Failure 400 {object} types.Problem
+// @Failure 401 {object} types.Problem
+// @Failure 403 {object} types.Problem "The caller lacks the permission of the route"
+// @Failure 404 {object} types.Problem
+// @Failure 409 {object} types.Problem "The movie is not deleted"
+// @Router /movies/{id}/restore [post]
+func (h *MovieHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	ctx, span := o11y.Tracer().Start(r.Context(), "MovieHandler.Restore")
+	defer span.End()
+
+	id, err := parseID(r)
+	if err != nil {
+		helpers.ErrorJSON(w, r, err)
+		return
+	}
+
+	movie, err := h.s.Restore(ctx, id)
+	if err != nil {
+		helpers.ErrorJSON(w, r, err)
+		return
+	}
+
+	setValidators(w, movie)
+	helpers.WriteJSON(w, http.StatusOK, movie)
+}
+
+// MovieCacheTags tags cached responses of /movies/{id} with the movie they show.
+func MovieCacheTags(r *http.Request) []string {
+	id, err := parseID(r)
+	if err != nil {
+		return nil
+	}
+	return []string{models.MovieCacheTag(int64(id))}
+}
+
+// ifMatch returns the movie version required by the If-Match header, or zero when any version
+// will do. The entity tags are those of helpers.VersionETag; If-Match uses strong comparison,
+// so weak tags and tags of other movies never match. A list naming several versions of this
+// movie matches whichever of them is current, which is then the version required.
+func (h *MovieHandler) ifMatch(r *http.Request, id int) (int64, error) {
+	header := strings.Join(r.Header.Values("If-Match"), ",")
+	if header == "" {
+		if h.requireIfMatch {
+			return 0, errs.PreconditionRequired("this request must be conditional: send the movie's ETag in If-Match")
+		}
+		return 0, nil
+	}
+	if strings.TrimSpace(header) == "*" {
+		return 0, nil
+	}
+
+	prefix := `"` + strconv.Itoa(id) + "-"
+	var versions []int64
+	for _, tag := range strings.Split(header, ",") {
+		rest, ok := strings.CutPrefix(strings.TrimSpace(tag), prefix)
+		if !ok {
+			continue
+		}
+		digits, ok := strings.CutSuffix(rest, `"`)
+		if !ok {
+			continue
+		}
+		if version, err := strconv.ParseInt(digits, 10, 64); err == nil && version > 0 && !slices.Contains(versions, version) {
+			versions = append(versions, version)
+		}
+	}
+	switch len(versions) {
+	case 0:
+		return 0, errs.PreconditionFailed("If-Match does not match the current ETag of movie %d", id)
+	case 1:
+		return versions[0], nil
+	}
+
+	// The write stays conditional on the version found here, so a change made in between
+	// still fails it.
+	current, err := h.s.GetById(r.Context(), id)
+	if err != nil {
+		return 0, err
+	}
+	if !slices.Contains(versions, current.Version) {
+		return 0, errs.PreconditionFailed("If-Match does not match the current ETag of movie %d", id)
+	}
+	return current.Version, nil
+}
+
+// setValidators sets the ETag and Last-Modified of a movie on the response.
+func setValidators(w http.ResponseWriter, movie *models.Movie) {
+	w.Header().Set("ETag", helpers.VersionETag(movie.ID, movie.Version))
+	w.Header().Set("Last-Modified", movie.UpdatedAt.UTC().Format(http.TimeFormat))
+}
+
+// parseID reads the movie id from the URL path.
+func parseID(r *http.Request) (int, error) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		return 0, errs.Wrap(errs.ErrValidation, err, "invalid movie id")
+	}
+	return id, nil
+}
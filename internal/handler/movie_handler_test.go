@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/mexirica/chi-template/internal/errs"
+	"github.com/mexirica/chi-template/internal/models"
+	"github.com/mexirica/chi-template/internal/service"
+)
+
+func TestMovieHandler_Create(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	svc := service.NewMockService(ctrl)
+	h := NewMovieHandler(svc)
+
+	payload := models.CreateMovieRequest{
+		Title:       "Alien",
+		Description: "In space no one can hear you scream",
+		ReleaseYear: 1979,
+		Genre:       []string{"Horror"},
+		Director:    "Ridley Scott",
+		Rating:      8.5,
+	}
+	svc.EXPECT().Create(gomock.Any(), payload).Return(&models.Movie{ID: 42, Title: "Alien", Version: 1}, nil)
+
+	body, _ := json.Marshal(payload)
+	r := httptest.NewRequest(http.MethodPost, "/movies", strings.NewReader(string(body)))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	h.Create(w, r)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body)
+	}
+	if got := w.Header().Get("Location"); got != "/movies/42" {
+		t.Errorf("expected Location /movies/42, got %q", got)
+	}
+	var created models.GetMovieResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil || created.ID != 42 || created.Title != "Alien" {
+		t.Errorf("expected the created movie in the body, got %s (%v)", w.Body, err)
+	}
+}
+
+func TestMovieHandler_GetList_IgnoresIfModifiedSince(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	svc := service.NewMockService(ctrl)
+	h := NewMovieHandler(svc)
+
+	updated := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	svc.EXPECT().GetList(gomock.Any(), gomock.Any()).Return(&models.GetMovieList{
+		Movies: []models.GetMovieResponse{{ID: 1, Title: "Alien", UpdatedAt: updated, Version: 1}},
+		Total:  1,
+	}, nil)
+
+	// A movie deleted since the copy was cached leaves the latest update unchanged.
+	r := httptest.NewRequest(http.MethodGet, "/movies/list", nil)
+	r.Header.Set("If-Modified-Since", updated.Format(http.TimeFormat))
+	w := httptest.NewRecorder()
+	h.GetList(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+	if w.Header().Get("Last-Modified") != "" || w.Header().Get("ETag") == "" {
+		t.Errorf("expected an ETag and no Last-Modified, got %v", w.Header())
+	}
+}
+
+func TestIfMatch_SeveralVersions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	svc := service.NewMockService(ctrl)
+	h := NewMovieHandler(svc)
+	svc.EXPECT().GetById(gomock.Any(), 5).Return(&models.Movie{ID: 5, Version: 4}, nil).Times(3)
+
+	tests := []struct {
+		name    string
+		header  []string
+		want    int64
+		wantErr error
+	}{
+		{"current tag listed second", []string{`"5-3", "5-4"`}, 4, nil},
+		{"tags on several lines", []string{`"5-3"`, `"5-4"`}, 4, nil},
+		{"no current tag", []string{`"5-3"`, `"5-2"`}, 0, errs.ErrPreconditionFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPut, "/movies/5", nil)
+			r.Header["If-Match"] = tt.header
+
+			version, err := h.ifMatch(r, 5)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("ifMatch error = %v, want %v", err, tt.wantErr)
+			}
+			if version != tt.want {
+				t.Errorf("ifMatch = %d, want %d", version, tt.want)
+			}
+		})
+	}
+}
+
+func TestIfMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		require bool
+		header  string
+		want    int64
+		wantErr error
+	}{
+		{"absent", false, "", 0, nil},
+		{"absent but required", true, "", 0, errs.ErrPreconditionRequired},
+		{"wildcard", true, "*", 0, nil},
+		{"current tag", true, `"7-3"`, 3, nil},
+		{"tag in a list", false, `"8-1", "7-2"`, 2, nil},
+		{"weak tag", false, `W/"7-3"`, 0, errs.ErrPreconditionFailed},
+		{"other movie", false, `"70-3"`, 0, errs.ErrPreconditionFailed},
+		{"malformed", false, `"7-x"`, 0, errs.ErrPreconditionFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewMovieHandler(nil, WithRequireIfMatch(tt.require))
+			r := httptest.NewRequest(http.MethodPut, "/movies/7", nil)
+			if tt.header != "" {
+				r.Header.Set("If-Match", tt.header)
+			}
+
+			version, err := h.ifMatch(r, 7)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("ifMatch error = %v, want %v", err, tt.wantErr)
+			}
+			if version != tt.want {
+				t.Errorf("ifMatch = %d, want %d", version, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"mime"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/mexirica/chi-template/internal/errs"
+	"github.com/mexirica/chi-template/internal/models"
+	"github.com/mexirica/chi-template/internal/service"
+	"github.com/mexirica/chi-template/internal/validation"
+)
+
+const (
+	csvContentType    = "text/csv"
+	ndjsonContentType = "application/x-ndjson"
+
+	// maxImportLine bounds a single NDJSON line.
+	maxImportLine = 1 << 20
+	// genreSeparator separates the genres of a CSV genre column.
+	genreSeparator = "|"
+)
+
+// importColumns are the CSV columns accepted by POST /movies/import, matching the JSON fields
+// of models.CreateMovieRequest. An id column, as written by GET /movies/export, is ignored.
+var importColumns = []string{"title", "description", "release_year", "genre", "director", "rating"}
+
+// errUnsupportedImportType is returned for import bodies that are neither CSV nor NDJSON.
+var errUnsupportedImportType = errs.Validation("content type must be %s or %s", csvContentType, ndjsonContentType)
+
+// newMovieSource returns a streaming reader of the import body for its content type.
+func newMovieSource(contentType string, body io.Reader) (service.MovieSource, error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case csvContentType:
+		return newCSVMovieSource(body)
+	case ndjsonContentType:
+		sc := bufio.NewScanner(body)
+		sc.Buffer(make([]byte, 0, 64*1024), maxImportLine)
+		return &ndjsonMovieSource{sc: sc}, nil
+	default:
+		return nil, errUnsupportedImportType
+	}
+}
+
+// csvMovieSource reads movies from CSV with a header row naming the importColumns in any order.
+// Genres are separated by genreSeparator.
+type csvMovieSource struct {
+	r       *csv.Reader
+	columns map[string]int
+}
+
+func newCSVMovieSource(body io.Reader) (*csvMovieSource, error) {
+	r := csv.NewReader(body)
+	r.ReuseRecord = true
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, errs.Validation("CSV import must start with a header row")
+	}
+	if err != nil {
+		return nil, errs.Wrap(errs.ErrValidation, err, "malformed CSV header")
+	}
+
+	var errList validation.Errors
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "id" {
+			continue
+		}
+		if !slices.Contains(importColumns, name) {
+			errList = append(errList, validation.ValidationErrorResponse{FailedField: name, Tag: "unknown"})
+			continue
+		}
+		columns[name] = i
+	}
+	if len(errList) > 0 {
+		return nil, errList
+	}
+	return &csvMovieSource{r: r, columns: columns}, nil
+}
+
+func (s *csvMovieSource) Next() (models.CreateMovieRequest, error) {
+	record, err := s.r.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return models.CreateMovieRequest{}, errs.Wrap(errs.ErrValidation, err, "malformed CSV row: %v", parseErr.Err)
+		}
+		return models.CreateMovieRequest{}, err
+	}
+
+	field := func(name string) string {
+		if i, ok := s.columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var errList validation.Errors
+	movie := models.CreateMovieRequest{
+		Title:       field("title"),
+		Description: field("description"),
+		Genre:       splitGenres(field("genre")),
+		Director:    field("director"),
+	}
+	if v := field("release_year"); v != "" {
+		if movie.ReleaseYear, err = strconv.Atoi(v); err != nil {
+			errList = append(errList, validation.ValidationErrorResponse{FailedField: "release_year", Tag: "number"})
+		}
+	}
+	if v := field("rating"); v != "" {
+		if movie.Rating, err = strconv.ParseFloat(v, 64); err != nil {
+			errList = append(errList, validation.ValidationErrorResponse{FailedField: "rating", Tag: "number"})
+		}
+	}
+	if len(errList) > 0 {
+		return movie, errList
+	}
+	return movie, nil
+}
+
+// splitGenres splits a CSV genre column, dropping empty entries.
+func splitGenres(v string) []string {
+	var genres []string
+	for _, genre := range strings.Split(v, genreSeparator) {
+		if genre = strings.TrimSpace(genre); genre != "" {
+			genres = append(genres, genre)
+		}
+	}
+	return genres
+}
+
+// ndjsonMovieSource reads one JSON movie per line. Blank lines are skipped.
+type ndjsonMovieSource struct {
+	sc   *bufio.Scanner
+	done bool
+}
+
+func (s *ndjsonMovieSource) Next() (models.CreateMovieRequest, error) {
+	var movie models.CreateMovieRequest
+	if s.done {
+		return movie, io.EOF
+	}
+	for s.sc.Scan() {
+		line := bytes.TrimSpace(s.sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		_, err := validation.Decode(line, &movie)
+		return movie, err
+	}
+
+	s.done = true
+	err := s.sc.Err()
+	if errors.Is(err, bufio.ErrTooLong) {
+		return movie, errs.Validation("line exceeds %d bytes; the rest of the import was skipped", maxImportLine)
+	}
+	if err != nil {
+		return movie, err
+	}
+	return movie, io.EOF
+}
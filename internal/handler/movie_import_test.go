@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/mexirica/chi-template/internal/errs"
+	"github.com/mexirica/chi-template/internal/models"
+	"github.com/mexirica/chi-template/internal/service"
+)
+
+// drain reads every movie of the source, keeping the per-row errors.
+func drain(t *testing.T, src service.MovieSource) ([]models.CreateMovieRequest, []error) {
+	t.Helper()
+	var (
+		movies []models.CreateMovieRequest
+		rowErr []error
+	)
+	for {
+		movie, err := src.Next()
+		if errors.Is(err, io.EOF) {
+			return movies, rowErr
+		}
+		if err != nil && !errors.Is(err, errs.ErrValidation) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		movies = append(movies, movie)
+		rowErr = append(rowErr, err)
+	}
+}
+
+func TestCSVMovieSource(t *testing.T) {
+	body := "Title,release_year,genre,rating,director,description\n" +
+		"Alien,1979,Horror|Sci-Fi,8.5,Ridley Scott,In space\n" +
+		"Heat,nineteen,Crime,8.3,Michael Mann,Heist\n" +
+		"Short,1999\n"
+
+	src, err := newMovieSource("text/csv; charset=utf-8", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	movies, rowErr := drain(t, src)
+	if len(movies) != 3 {
+		t.Fatalf("expected 3 rows, got %d", len(movies))
+	}
+	if rowErr[0] != nil || movies[0].ReleaseYear != 1979 || len(movies[0].Genre) != 2 || movies[0].Director != "Ridley Scott" {
+		t.Errorf("unexpected first row %+v (%v)", movies[0], rowErr[0])
+	}
+	if rowErr[1] == nil || rowErr[2] == nil {
+		t.Errorf("expected errors for rows 2 and 3, got %v", rowErr[1:])
+	}
+}
+
+func TestCSVMovieSource_UnknownColumn(t *testing.T) {
+	_, err := newMovieSource("text/csv", strings.NewReader("title,budget\n"))
+	if !errors.Is(err, errs.ErrValidation) {
+		t.Errorf("expected a validation error, got %v", err)
+	}
+}
+
+func TestNDJSONMovieSource(t *testing.T) {
+	body := `{"title":"Alien","release_year":1979}` + "\n\n" +
+		`{"title":"Heat","budget":1}` + "\n" +
+		`not json` + "\n"
+
+	src, err := newMovieSource("application/x-ndjson", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	movies, rowErr := drain(t, src)
+	if len(movies) != 3 || movies[0].Title != "Alien" {
+		t.Fatalf("unexpected rows %+v", movies)
+	}
+	if rowErr[1] == nil || rowErr[2] == nil {
+		t.Errorf("expected errors for rows 2 and 3, got %v", rowErr[1:])
+	}
+}
+
+func TestNewMovieSource_UnsupportedType(t *testing.T) {
+	if _, err := newMovieSource("application/json", strings.NewReader("[]")); !errors.Is(err, errUnsupportedImportType) {
+		t.Errorf("expected errUnsupportedImportType, got %v", err)
+	}
+}
+
+func TestCSVExportRoundTrip(t *testing.T) {
+	movie := models.Movie{ID: 7, Title: "Alien, the \"original\"", Description: "In space", ReleaseYear: 1979, Genre: []string{"Horror", "Sci-Fi"}, Director: "Ridley Scott", Rating: 8.5}
+
+	var buf strings.Builder
+	w := newCSVMovieWriter(&buf)
+	if err := w.Write(movie); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := newMovieSource(csvContentType, strings.NewReader(buf.String()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	movies, rowErr := drain(t, src)
+	if len(movies) != 1 || rowErr[0] != nil {
+		t.Fatalf("unexpected rows %+v (%v)", movies, rowErr)
+	}
+	got := movies[0]
+	if got.Title != movie.Title || got.ReleaseYear != movie.ReleaseYear || len(got.Genre) != 2 || got.Rating != movie.Rating {
+		t.Errorf("round trip changed the movie: %+v", got)
+	}
+}
@@ -0,0 +1,191 @@
+package handler
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/mexirica/chi-template/internal/helpers"
+	"github.com/mexirica/chi-template/internal/models"
+	"github.com/mexirica/chi-template/internal/validation"
+)
+
+const (
+	defaultPage  = 1
+	defaultLimit = 10
+	maxLimit     = 100
+
+	defaultSuggestLimit = 5
+	maxSuggestLimit     = 20
+)
+
+// parseMovieFilter reads the filters, sort order and pagination of GET /movies/list from the query string.
+// Malformed values are reported as validation.Errors.
+func parseMovieFilter(r *http.Request) (models.MovieFilter, error) {
+	query := r.URL.Query()
+	var errList validation.Errors
+
+	filter := models.MovieFilter{
+		Genres:   splitList(query["genre"]),
+		Director: strings.TrimSpace(query.Get("director")),
+		Query:    strings.TrimSpace(query.Get("q")),
+		Cursor:   query.Get("cursor"),
+	}
+	filter.Page, filter.Limit = parsePaging(query)
+
+	filter.YearFrom = parseInt(query, "year_from", &errList)
+	filter.YearTo = parseInt(query, "year_to", &errList)
+	if v := query.Get("min_rating"); v != "" {
+		rating, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			errList = append(errList, validation.ValidationErrorResponse{FailedField: "min_rating", Tag: "number"})
+		}
+		filter.MinRating = rating
+	}
+
+	sort, sortErrs := parseSort(query.Get("sort"))
+	filter.Sort = sort
+	errList = append(errList, sortErrs...)
+
+	if len(errList) > 0 {
+		return filter, errList
+	}
+	if _, err := validation.Validate(&filter); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
+
+// parseMovieSearch reads the query and pagination of GET /movies/search from the query string.
+func parseMovieSearch(r *http.Request) (models.MovieSearch, error) {
+	query := r.URL.Query()
+	search := models.MovieSearch{Query: strings.TrimSpace(query.Get("q"))}
+	search.Page, search.Limit = parsePaging(query)
+
+	if _, err := validation.Validate(&search); err != nil {
+		return search, err
+	}
+	return search, nil
+}
+
+// parseMovieSuggest reads the prefix and limit of GET /movies/suggest from the query string.
+func parseMovieSuggest(r *http.Request) (models.MovieSuggest, error) {
+	query := r.URL.Query()
+	suggest := models.MovieSuggest{
+		Prefix: strings.TrimSpace(query.Get("prefix")),
+		Limit:  defaultSuggestLimit,
+	}
+	if v, err := strconv.Atoi(query.Get("limit")); err == nil && v >= 1 {
+		suggest.Limit = min(v, maxSuggestLimit)
+	}
+
+	if _, err := validation.Validate(&suggest); err != nil {
+		return suggest, err
+	}
+	return suggest, nil
+}
+
+// parsePaging reads the page and limit query parameters. They have always been lenient:
+// invalid values fall back to the defaults and the limit is capped at maxLimit.
+func parsePaging(query url.Values) (page, limit int) {
+	page, limit = defaultPage, defaultLimit
+	if v, err := strconv.Atoi(query.Get("page")); err == nil && v >= 1 {
+		page = v
+	}
+	if v, err := strconv.Atoi(query.Get("limit")); err == nil && v >= 1 {
+		limit = min(v, maxLimit)
+	}
+	return page, limit
+}
+
+// parseSort parses a sort expression such as "title,-rating". A leading "-" sorts the key in
+// descending order. Keys outside models.MovieSortFields are rejected.
+func parseSort(raw string) ([]models.SortField, validation.Errors) {
+	var (
+		fields  []models.SortField
+		errList validation.Errors
+	)
+	for _, key := range splitList([]string{raw}) {
+		field := models.SortField{Field: key}
+		if name, ok := strings.CutPrefix(key, "-"); ok {
+			field = models.SortField{Field: name, Desc: true}
+		}
+		if !slices.Contains(models.MovieSortFields, field.Field) {
+			errList = append(errList, validation.ValidationErrorResponse{
+				FailedField: "sort",
+				Tag:         "oneof",
+				Value:       strings.Join(models.MovieSortFields, " "),
+			})
+			continue
+		}
+		if slices.ContainsFunc(fields, func(f models.SortField) bool { return f.Field == field.Field }) {
+			continue
+		}
+		fields = append(fields, field)
+	}
+	return fields, errList
+}
+
+// parseInt reads an optional integer query parameter, recording a validation error when it is malformed.
+func parseInt(query url.Values, name string, errList *validation.Errors) int {
+	v := query.Get(name)
+	if v == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		*errList = append(*errList, validation.ValidationErrorResponse{FailedField: name, Tag: "number"})
+	}
+	return n
+}
+
+// splitList accepts both repeated parameters (genre=a&genre=b) and comma separated values (genre=a,b).
+func splitList(values []string) []string {
+	var out []string
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				out = append(out, item)
+			}
+		}
+	}
+	return out
+}
+
+// paginationLinks returns the first/prev/next/last links of a movie listing. Page-number requests
+// get page-number links; cursor requests follow the cursors of the response. The last page is
+// always addressed by number since keyset pagination cannot jump to it.
+func paginationLinks(r *http.Request, filter models.MovieFilter, list *models.GetMovieList) []helpers.Link {
+	withQuery := func(set map[string]string) url.Values {
+		query := r.URL.Query()
+		query.Del("cursor")
+		query.Del("page")
+		for key, value := range set {
+			query.Set(key, value)
+		}
+		return query
+	}
+
+	lastPage := max(1, int((list.Total+int64(filter.Limit)-1)/int64(filter.Limit)))
+	links := []helpers.Link{{Rel: "first", Query: withQuery(map[string]string{"page": "1"})}}
+
+	if filter.Cursor != "" {
+		if list.PrevCursor != "" {
+			links = append(links, helpers.Link{Rel: "prev", Query: withQuery(map[string]string{"cursor": list.PrevCursor})})
+		}
+		if list.NextCursor != "" {
+			links = append(links, helpers.Link{Rel: "next", Query: withQuery(map[string]string{"cursor": list.NextCursor})})
+		}
+	} else {
+		if filter.Page > 1 {
+			links = append(links, helpers.Link{Rel: "prev", Query: withQuery(map[string]string{"page": strconv.Itoa(min(filter.Page-1, lastPage))})})
+		}
+		if list.NextCursor != "" {
+			links = append(links, helpers.Link{Rel: "next", Query: withQuery(map[string]string{"page": strconv.Itoa(filter.Page + 1)})})
+		}
+	}
+
+	return append(links, helpers.Link{Rel: "last", Query: withQuery(map[string]string{"page": strconv.Itoa(lastPage)})})
+}
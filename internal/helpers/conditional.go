@@ -0,0 +1,80 @@
+package helpers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// VersionETag returns the strong entity tag of a resource that carries a version number.
+func VersionETag(id, version int64) string {
+	return `"` + strconv.FormatInt(id, 10) + "-" + strconv.FormatInt(version, 10) + `"`
+}
+
+// JSONETag returns a strong entity tag derived from the JSON encoding of v, for
+// representations that have no version of their own such as listings.
+func JSONETag(v any) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`, nil
+}
+
+// NotModified reports whether a GET or HEAD request is conditional on validators that still
+// match, so that it can be answered with 304 (RFC 9110 section 13.2.2). If-None-Match takes
+// precedence over If-Modified-Since, which only has a one second resolution.
+func NotModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return etag != "" && matchETag(inm, etag)
+	}
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" && !lastModified.IsZero() {
+		since, err := http.ParseTime(ims)
+		return err == nil && !lastModified.Truncate(time.Second).After(since)
+	}
+	return false
+}
+
+// CheckNotModified sets the ETag and Last-Modified headers of a representation. When the
+// request's validators still match, it writes 304 Not Modified and returns true; the handler
+// must then not write a body.
+func CheckNotModified(w http.ResponseWriter, r *http.Request, etag string, lastModified time.Time) bool {
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+	if !lastModified.IsZero() {
+		w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+	if !NotModified(r, etag, lastModified) {
+		return false
+	}
+	WriteNotModified(w)
+	return true
+}
+
+// WriteNotModified writes a 304 response, dropping the headers that describe a body.
+func WriteNotModified(w http.ResponseWriter) {
+	w.Header().Del("Content-Type")
+	w.Header().Del("Content-Length")
+	w.WriteHeader(http.StatusNotModified)
+}
+
+// matchETag reports whether the If-None-Match list contains etag, with weak comparison.
+func matchETag(list, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(list, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
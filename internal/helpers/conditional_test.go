@@ -0,0 +1,71 @@
+package helpers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mexirica/chi-template/internal/helpers"
+)
+
+func TestNotModified(t *testing.T) {
+	etag := helpers.VersionETag(1, 3)
+	modified := time.Date(2024, 5, 1, 12, 0, 0, 500, time.UTC)
+
+	tests := []struct {
+		name   string
+		method string
+		header http.Header
+		want   bool
+	}{
+		{"unconditional", http.MethodGet, nil, false},
+		{"matching etag", http.MethodGet, http.Header{"If-None-Match": {`"1-2", "1-3"`}}, true},
+		{"weak etag", http.MethodHead, http.Header{"If-None-Match": {`W/"1-3"`}}, true},
+		{"wildcard", http.MethodGet, http.Header{"If-None-Match": {"*"}}, true},
+		{"stale etag", http.MethodGet, http.Header{"If-None-Match": {`"1-2"`}}, false},
+		{"not modified since", http.MethodGet, http.Header{"If-Modified-Since": {"Wed, 01 May 2024 12:00:00 GMT"}}, true},
+		{"modified since", http.MethodGet, http.Header{"If-Modified-Since": {"Wed, 01 May 2024 11:59:59 GMT"}}, false},
+		{"etag takes precedence", http.MethodGet, http.Header{
+			"If-None-Match":     {`"1-2"`},
+			"If-Modified-Since": {"Wed, 01 May 2024 12:00:00 GMT"},
+		}, false},
+		{"unsafe method", http.MethodPut, http.Header{"If-None-Match": {`"1-3"`}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/movies/1", nil)
+			for key, values := range tt.header {
+				r.Header[key] = values
+			}
+			if got := helpers.NotModified(r, etag, modified); got != tt.want {
+				t.Errorf("NotModified = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckNotModified(t *testing.T) {
+	modified := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	r := httptest.NewRequest(http.MethodGet, "/movies/1", nil)
+	r.Header.Set("If-None-Match", `"1-3"`)
+	w := httptest.NewRecorder()
+
+	if !helpers.CheckNotModified(w, r, `"1-3"`, modified) {
+		t.Fatal("expected a matching If-None-Match to be answered with 304")
+	}
+	if w.Code != http.StatusNotModified || w.Header().Get("ETag") != `"1-3"` ||
+		w.Header().Get("Last-Modified") != "Wed, 01 May 2024 12:00:00 GMT" {
+		t.Errorf("unexpected response %d %v", w.Code, w.Header())
+	}
+}
+
+func TestJSONETag(t *testing.T) {
+	a, _ := helpers.JSONETag(map[string]int{"version": 1})
+	b, _ := helpers.JSONETag(map[string]int{"version": 1})
+	c, _ := helpers.JSONETag(map[string]int{"version": 2})
+	if a != b || a == c {
+		t.Errorf("expected equal values to share an ETag: %s %s %s", a, b, c)
+	}
+}
@@ -4,8 +4,6 @@ package helpers
 import (
 	"encoding/json"
 	"net/http"
-
-	"github.com/mexirica/chi-template/internal/types"
 )
 
 
@@ -28,15 +26,3 @@ func WriteJSON(w http.ResponseWriter, status int, data any, headers ...http.Head
 	}
 	return nil
 }
-
-// WriteJSON writes the data interface to the response writer
-func ErrorJSON(w http.ResponseWriter, err error, status ...int) {
-	statusCode := http.StatusBadRequest
-	if len(status) > 0 {
-		statusCode = status[0]
-	}
-	var payload types.JsonResponse
-	payload.Error = true
-	payload.Message = err.Error()
-	WriteJSON(w, statusCode, payload)
-}
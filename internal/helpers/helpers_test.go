@@ -0,0 +1,112 @@
+// helpers_test.go
+// Unit tests for the domain error to HTTP status mapping and Problem Details responses.
+package helpers_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/mexirica/chi-template/internal/errs"
+	"github.com/mexirica/chi-template/internal/helpers"
+	"github.com/mexirica/chi-template/internal/types"
+	"github.com/mexirica/chi-template/internal/validation"
+)
+
+func TestStatusCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{errs.NotFound("movie %d not found", 1), http.StatusNotFound},
+		{fmt.Errorf("failed to get movie by id: %w", errs.NotFound("movie %d not found", 1)), http.StatusNotFound},
+		{errs.Conflict("movie already exists"), http.StatusConflict},
+		{errs.Validation("invalid movie id"), http.StatusBadRequest},
+		{validation.Errors{{FailedField: "title", Tag: "required"}}, http.StatusBadRequest},
+		{errs.PreconditionFailed("version mismatch"), http.StatusPreconditionFailed},
+		{errs.PreconditionRequired("If-Match is required"), http.StatusPreconditionRequired},
+		{errs.Unauthorized("missing bearer token"), http.StatusUnauthorized},
+		{errs.Forbidden("permission denied"), http.StatusForbidden},
+		{errs.TooManyRequests(time.Minute, "slow down"), http.StatusTooManyRequests},
+		{errs.Unavailable(errors.New("dial tcp"), "database unavailable"), http.StatusServiceUnavailable},
+		{errors.New("boom"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		if got := helpers.StatusCode(tt.err); got != tt.want {
+			t.Errorf("StatusCode(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestErrorJSON_HidesInternalErrors(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/movies/1?x=y", nil)
+
+	helpers.ErrorJSON(w, r, errors.New(`pq: relation "movies" does not exist`))
+
+	if got := w.Header().Get("Content-Type"); got != helpers.ProblemContentType {
+		t.Errorf("expected content type %s, got %s", helpers.ProblemContentType, got)
+	}
+	var problem types.Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("invalid problem body: %v", err)
+	}
+	if problem.Status != http.StatusInternalServerError || w.Code != http.StatusInternalServerError {
+		t.Errorf("expected status 500, got %d (body %d)", w.Code, problem.Status)
+	}
+	if problem.Detail != "An unexpected error occurred." {
+		t.Errorf("expected generic detail, got %q", problem.Detail)
+	}
+	if problem.Instance != "/movies/1?x=y" {
+		t.Errorf("expected instance /movies/1?x=y, got %q", problem.Instance)
+	}
+}
+
+func TestErrorJSON_ValidationErrors(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/movies", nil)
+
+	helpers.ErrorJSON(w, r, validation.Errors{{FailedField: "CreateMovieRequest.Title", Tag: "required"}})
+
+	var problem types.Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("invalid problem body: %v", err)
+	}
+	if problem.Status != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", problem.Status)
+	}
+	if len(problem.Errors) != 1 || problem.Errors[0].Tag != "required" {
+		t.Errorf("expected one required error, got %+v", problem.Errors)
+	}
+}
+
+func TestErrorJSON_RetryAfter(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/auth/login", nil)
+
+	helpers.ErrorJSON(w, r, fmt.Errorf("failed to log in: %w", errs.TooManyRequests(1500*time.Millisecond, "too many failed logins")))
+
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "2" {
+		t.Errorf("expected 429 with Retry-After 2, got %d %q", w.Code, w.Header().Get("Retry-After"))
+	}
+}
+
+func TestLinkHeader(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/movies/list?page=2&sort=-rating", nil)
+
+	got := helpers.LinkHeader(r,
+		helpers.Link{Rel: "first", Query: url.Values{"page": {"1"}, "sort": {"-rating"}}},
+		helpers.Link{Rel: "next", Query: url.Values{"cursor": {"abc.def"}}},
+	)
+
+	want := `</movies/list?page=1&sort=-rating>; rel="first", </movies/list?cursor=abc.def>; rel="next"`
+	if got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+}
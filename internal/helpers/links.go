@@ -0,0 +1,24 @@
+package helpers
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Link is one relation of an RFC 8288 Link header, pointing at the request URL with a different query.
+type Link struct {
+	Rel   string
+	Query url.Values
+}
+
+// LinkHeader formats the links as an RFC 8288 Link header value. Targets are relative
+// references built from the request path, so they stay valid behind path-preserving proxies.
+func LinkHeader(r *http.Request, links ...Link) string {
+	values := make([]string, 0, len(links))
+	for _, link := range links {
+		target := url.URL{Path: r.URL.Path, RawQuery: link.Query.Encode()}
+		values = append(values, "<"+target.String()+`>; rel="`+link.Rel+`"`)
+	}
+	return strings.Join(values, ", ")
+}
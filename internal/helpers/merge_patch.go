@@ -0,0 +1,54 @@
+package helpers
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// MergePatchContentType is the media type of a JSON Merge Patch document (RFC 7396).
+const MergePatchContentType = "application/merge-patch+json"
+
+// MergePatch applies a JSON Merge Patch (RFC 7396) to the target document and returns the result.
+func MergePatch(target, patch []byte) ([]byte, error) {
+	var t any
+	if len(bytes.TrimSpace(target)) > 0 {
+		if err := decodeJSON(target, &t); err != nil {
+			return nil, err
+		}
+	}
+
+	var p any
+	if err := decodeJSON(patch, &p); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(mergePatch(t, p))
+}
+
+func mergePatch(target, patch any) any {
+	p, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	t, ok := target.(map[string]any)
+	if !ok {
+		t = map[string]any{}
+	}
+
+	for key, value := range p {
+		if value == nil {
+			delete(t, key)
+			continue
+		}
+		t[key] = mergePatch(t[key], value)
+	}
+	return t
+}
+
+// decodeJSON decodes numbers as json.Number so patching never changes their precision.
+func decodeJSON(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
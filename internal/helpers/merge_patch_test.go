@@ -0,0 +1,58 @@
+// merge_patch_test.go
+// Unit tests for MergePatch using the examples from RFC 7396, Appendix A.
+package helpers_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/mexirica/chi-template/internal/helpers"
+)
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		target, patch, want string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+		{`{"rating":8.15}`, `{"title":"x"}`, `{"rating":8.15,"title":"x"}`},
+	}
+
+	for _, tt := range tests {
+		got, err := helpers.MergePatch([]byte(tt.target), []byte(tt.patch))
+		if err != nil {
+			t.Fatalf("MergePatch(%s, %s) returned error: %v", tt.target, tt.patch, err)
+		}
+
+		var gotValue, wantValue any
+		if err := json.Unmarshal(got, &gotValue); err != nil {
+			t.Fatalf("invalid JSON result %s: %v", got, err)
+		}
+		if err := json.Unmarshal([]byte(tt.want), &wantValue); err != nil {
+			t.Fatalf("invalid JSON expectation %s: %v", tt.want, err)
+		}
+		if !reflect.DeepEqual(gotValue, wantValue) {
+			t.Errorf("MergePatch(%s, %s) = %s, want %s", tt.target, tt.patch, got, tt.want)
+		}
+	}
+}
+
+func TestMergePatch_InvalidPatch(t *testing.T) {
+	if _, err := helpers.MergePatch([]byte(`{"a":"b"}`), []byte(`{"a":`)); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
@@ -0,0 +1,105 @@
+package helpers
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/mexirica/chi-template/internal/errs"
+	"github.com/mexirica/chi-template/internal/types"
+	"github.com/mexirica/chi-template/internal/validation"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ProblemContentType is the media type of an RFC 9457 Problem Details response.
+const ProblemContentType = "application/problem+json"
+
+// ErrorJSON writes err as an RFC 9457 Problem Details response. The status code is derived
+// from the error's domain kind (see StatusCode) unless one is given explicitly.
+//
+// Domain errors carrying a retry delay set the Retry-After header.
+//
+// Only the client-safe message of domain errors is exposed as the detail; any other error is
+// logged and reported with a generic detail so internal messages never reach the client.
+func ErrorJSON(w http.ResponseWriter, r *http.Request, err error, status ...int) {
+	statusCode := StatusCode(err)
+	if len(status) > 0 {
+		statusCode = status[0]
+	}
+
+	problem := NewProblem(r, statusCode, errs.Message(err))
+	var errList validation.Errors
+	if errors.As(err, &errList) {
+		problem.Detail = "The request payload is invalid."
+		problem.Errors = errList
+	}
+	if problem.Detail == "" && statusCode >= http.StatusInternalServerError {
+		problem.Detail = "An unexpected error occurred."
+	}
+	if retryAfter := errs.RetryAfter(err); retryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	}
+	if statusCode >= http.StatusInternalServerError {
+		log.Error().Err(err).Str("trace_id", problem.TraceID).Str("instance", problem.Instance).Msg("request failed")
+	}
+
+	WriteProblem(w, problem)
+}
+
+// NewProblem builds a Problem Details object for the request, taking the trace ID from the
+// active OpenTelemetry span in the request context.
+func NewProblem(r *http.Request, status int, detail string) types.Problem {
+	problem := types.Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: r.URL.RequestURI(),
+	}
+	if sc := trace.SpanContextFromContext(r.Context()); sc.HasTraceID() {
+		problem.TraceID = sc.TraceID().String()
+	}
+	return problem
+}
+
+// WriteProblem writes the Problem Details object to the response writer.
+func WriteProblem(w http.ResponseWriter, problem types.Problem) error {
+	out, err := json.MarshalIndent(problem, "", "\t")
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(problem.Status)
+	_, err = w.Write(out)
+	return err
+}
+
+// StatusCode maps a domain error from the errs package to its HTTP status code.
+// Errors without a domain kind map to 500 Internal Server Error.
+func StatusCode(err error) int {
+	switch {
+	case errors.Is(err, errs.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, errs.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, errs.ErrValidation):
+		return http.StatusBadRequest
+	case errors.Is(err, errs.ErrPreconditionFailed):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, errs.ErrPreconditionRequired):
+		return http.StatusPreconditionRequired
+	case errors.Is(err, errs.ErrUnauthorized):
+		return http.StatusUnauthorized
+	case errors.Is(err, errs.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, errs.ErrTooManyRequests):
+		return http.StatusTooManyRequests
+	case errors.Is(err, errs.ErrUnavailable):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,125 @@
+package helpers
+
+import (
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// NDJSONWriter streams values as newline-delimited JSON, one value per line.
+type NDJSONWriter struct {
+	enc *json.Encoder
+}
+
+func NewNDJSONWriter(w io.Writer) *NDJSONWriter {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return &NDJSONWriter{enc: enc}
+}
+
+func (n *NDJSONWriter) Write(v any) error {
+	return n.enc.Encode(v)
+}
+
+func (n *NDJSONWriter) Close() error {
+	return nil
+}
+
+// JSONArrayWriter streams values as the elements of a single JSON array. Close writes the
+// closing bracket and must be called even when nothing was written.
+type JSONArrayWriter struct {
+	w     io.Writer
+	enc   *json.Encoder
+	count int
+}
+
+func NewJSONArrayWriter(w io.Writer) *JSONArrayWriter {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return &JSONArrayWriter{w: w, enc: enc}
+}
+
+func (a *JSONArrayWriter) Write(v any) error {
+	sep := ",\n"
+	if a.count == 0 {
+		sep = "[\n"
+	}
+	if _, err := io.WriteString(a.w, sep); err != nil {
+		return err
+	}
+	a.count++
+	// Encode terminates the value with a newline, which is valid whitespace in an array.
+	return a.enc.Encode(v)
+}
+
+func (a *JSONArrayWriter) Close() error {
+	end := "]\n"
+	if a.count == 0 {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(a.w, end)
+	return err
+}
+
+// NegotiateContentType picks the offer that best matches the Accept header of the request.
+// Each offer takes the q-value of the most specific media range matching it, so
+// "*/*, text/csv;q=0" excludes CSV. Offers are listed in order of server preference, which breaks
+// ties; the first one is returned when the request has no Accept header. It returns "" when
+// none is acceptable.
+func NegotiateContentType(r *http.Request, offers ...string) string {
+	accept := r.Header.Values("Accept")
+	if len(accept) == 0 {
+		return offers[0]
+	}
+
+	type mediaRange struct {
+		mediaType string
+		q         float64
+	}
+	var ranges []mediaRange
+	for _, rng := range strings.Split(strings.Join(accept, ","), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(rng))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		ranges = append(ranges, mediaRange{mediaType: mediaType, q: q})
+	}
+
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		q, specificity := 0.0, -1
+		for _, rng := range ranges {
+			if s := mediaRangeMatch(rng.mediaType, offer); s > specificity {
+				q, specificity = rng.q, s
+			}
+		}
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// mediaRangeMatch reports how specifically the media range matches the offer: 2 for an exact
+// match, 1 for type/*, 0 for */* and -1 when it does not match.
+func mediaRangeMatch(mediaRange, offer string) int {
+	switch {
+	case mediaRange == offer:
+		return 2
+	case mediaRange == "*/*":
+		return 0
+	case strings.HasSuffix(mediaRange, "/*") && strings.HasPrefix(offer, strings.TrimSuffix(mediaRange, "*")):
+		return 1
+	default:
+		return -1
+	}
+}
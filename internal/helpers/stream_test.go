@@ -0,0 +1,61 @@
+package helpers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mexirica/chi-template/internal/helpers"
+)
+
+func TestNegotiateContentType(t *testing.T) {
+	offers := []string{"application/x-ndjson", "text/csv", "application/json"}
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"", "application/x-ndjson"},
+		{"*/*", "application/x-ndjson"},
+		{"text/csv", "text/csv"},
+		{"text/*", "text/csv"},
+		{"application/json;q=0.9, text/csv;q=0.5", "application/json"},
+		{"*/*;q=0.1, application/json", "application/json"},
+		{"*/*, application/x-ndjson;q=0", "text/csv"},
+		{"application/xml", ""},
+		{"text/csv;q=0", ""},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/movies/export", nil)
+		if tt.accept != "" {
+			r.Header.Set("Accept", tt.accept)
+		}
+		if got := helpers.NegotiateContentType(r, offers...); got != tt.want {
+			t.Errorf("NegotiateContentType(%q) = %q, want %q", tt.accept, got, tt.want)
+		}
+	}
+}
+
+func TestJSONArrayWriter(t *testing.T) {
+	for _, n := range []int{0, 1, 3} {
+		var buf bytes.Buffer
+		w := helpers.NewJSONArrayWriter(&buf)
+		for i := range n {
+			if err := w.Write(map[string]int{"id": i}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		var got []map[string]int
+		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Fatalf("%d values: invalid JSON %q: %v", n, buf.String(), err)
+		}
+		if len(got) != n {
+			t.Errorf("%d values: decoded %d", n, len(got))
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/mexirica/chi-template/internal/auth"
+	"github.com/mexirica/chi-template/internal/errs"
+	"github.com/mexirica/chi-template/internal/middleware"
+	"github.com/mexirica/chi-template/internal/models"
+	"github.com/mexirica/chi-template/internal/service"
+)
+
+func TestAPIKey(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	keys := service.NewMockAPIKeys(ctrl)
+	var caller *auth.Claims
+	handler := middleware.APIKey(keys)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		caller, _ = auth.ClaimsFromContext(r.Context())
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	serve := func(key string) *httptest.ResponseRecorder {
+		caller = nil
+		req := httptest.NewRequest(http.MethodPost, "/movies", nil)
+		if key != "" {
+			req.Header.Set(middleware.APIKeyHeader, key)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	keys.EXPECT().Authenticate(gomock.Any(), "ck_valid").Return(&models.APIKey{ID: 3, Scopes: []string{"movies:create"}}, nil)
+	if rec := serve("ck_valid"); rec.Code != http.StatusNoContent {
+		t.Fatalf("valid key: status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+	if caller == nil || caller.Subject != "apikey:3" || !slices.Equal(caller.Permissions, []string{"movies:create"}) {
+		t.Errorf("expected the key's caller with its scopes, got %+v", caller)
+	}
+
+	keys.EXPECT().Authenticate(gomock.Any(), "ck_revoked").Return(nil, errs.Unauthorized("the API key is invalid"))
+	if rec := serve("ck_revoked"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("invalid key: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+
+	if rec := serve(""); rec.Code != http.StatusNoContent || caller != nil {
+		t.Errorf("without a key: status = %d, caller = %+v; want an anonymous pass-through", rec.Code, caller)
+	}
+}
@@ -2,120 +2,398 @@
 package middleware
 
 import (
-	"encoding/base64"
+	"bytes"
+	"context"
 	"encoding/json"
-	"io"
+	"errors"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/mexirica/chi-template/internal/cache"
 	"github.com/mexirica/chi-template/internal/helpers"
-	"github.com/mexirica/chi-template/internal/redis"
 	"github.com/rs/zerolog/log"
+	"golang.org/x/sync/singleflight"
 )
 
-// PrepareRouteKey generates a unique key for the request route, method, and query.
-func PrepareRouteKey(r *http.Request) (string, error) {
-	return r.Method + "." + r.URL.Path + "." + r.URL.RawQuery, nil
-}
+// maxCachedBody bounds the size of a response body stored in the cache; larger responses are
+// served but not cached.
+const maxCachedBody = 1 << 20
 
-// PrepareCacheKey returns a base64 encoded string of the payload with the route and method prepended.
-func PrepareCacheKey(payload any, routeKey string) (string, error) {
-	stringPayload := ""
-	byteData, err := io.ReadAll(payload.(io.Reader))
-	if err != nil {
-		log.Fatal().Err(err).Msg("Error reading payload")
-		return "", err
+const (
+	// fillLockTTL bounds how long a replica holds the lock on recomputing a key, and so how long
+	// the other replicas wait for its entry before computing the response themselves.
+	fillLockTTL = 5 * time.Second
+	// fillPollInterval is how often a replica waiting on another one checks the cache.
+	fillPollInterval = 50 * time.Millisecond
+	// lockPrefix namespaces the fill locks of cached responses.
+	lockPrefix = "lock:"
+)
+
+// TagFunc returns the cache tags of the response to a request. Writes evict cached responses
+// by tag, see cache.Cache.DeleteByTag.
+type TagFunc func(r *http.Request) []string
+
+// StaticTags tags every response with the same tags.
+func StaticTags(tags ...string) TagFunc {
+	return func(*http.Request) []string {
+		return tags
 	}
-	stringPayload = string(byteData)
-	encodedPayload := base64.StdEncoding.EncodeToString([]byte(stringPayload))
-	encodedPayload = routeKey + "." + encodedPayload
-	return encodedPayload, nil
 }
 
-// StringifyResponse returns a stringified version of the response object.
-func StringifyResponse(response any) (string, error) {
-	stringResponse, err := json.Marshal(response)
-	if err != nil {
-		log.Fatal().Err(err).Msg("Error marshalling response")
-		return "", err
+// requestTags collects the tags of every TagFunc for the request.
+func requestTags(r *http.Request, tagFuncs []TagFunc) []string {
+	var tags []string
+	for _, fn := range tagFuncs {
+		tags = append(tags, fn(r)...)
 	}
-	return string(stringResponse), nil
+	return tags
 }
 
-// SaveToCache saves the response to cache after stringifying it and returns the cache key.
-func SaveToCache(r *http.Request, response any) (string, error) {
-	routeKey, err := PrepareRouteKey(r)
-	if err != nil {
-		log.Error().Err(err).Msg("Error preparing route key")
-		return "", err
-	}
-	cacheKey, err := PrepareCacheKey(r.Body, routeKey)
-	if err != nil {
-		log.Error().Err(err).Msg("Error preparing cache key")
-		return "", err
-	}
-	stringResponse, err := StringifyResponse(response)
-	if err != nil {
-		log.Error().Err(err).Msg("Error stringifying response")
-		return "", err
-	}
-	err = redis.SetCache(cacheKey, stringResponse, 0)
-	if err != nil {
-		log.Error().Err(err).Msg("Error saving to cache")
-		return "", err
+// CachedResponse is a handler response stored verbatim in the cache.
+type CachedResponse struct {
+	Status   int         `json:"status"`
+	Header   http.Header `json:"header"`
+	Body     []byte      `json:"body"`
+	StoredAt time.Time   `json:"stored_at"`
+	// ExpiresAt is the end of the freshness lifetime. Past it, the entry is only served while
+	// it is being revalidated.
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// fresh reports whether the response can be served without revalidation. Entries stored
+// without an expiry are fresh for as long as the cache keeps them.
+func (c *CachedResponse) fresh() bool {
+	return c.ExpiresAt.IsZero() || time.Now().Before(c.ExpiresAt)
+}
+
+// PrepareRouteKey generates a unique key for the request route and query. HEAD requests share
+// the key of GET so they can be answered from cached GET responses.
+func PrepareRouteKey(r *http.Request) string {
+	method := r.Method
+	if method == http.MethodHead {
+		method = http.MethodGet
 	}
-	return cacheKey, nil
+	return "cache:" + method + "." + r.URL.Path + "." + r.URL.RawQuery
 }
 
-// CachedResponseToJSON retrieves the cached response and converts it to JSON.
-func CachedResponseToJSON(cacheKey string) ([]map[string]any, error) {
-	cachedResponse, err := redis.GetCache(cacheKey)
+// SaveToCache stores the response under the given tags.
+func SaveToCache(ctx context.Context, c cache.Cache, key string, response CachedResponse, ttl time.Duration, tags ...string) error {
+	data, err := json.Marshal(response)
 	if err != nil {
-		log.Error().Err(err).Msg("Error getting from cache")
-		return nil, nil
+		return err
 	}
-	if cachedResponse == "" {
-		log.Info().Msgf("No cached response found for %s", cacheKey)
+	return c.Set(ctx, key, data, ttl, tags...)
+}
+
+// LoadFromCache returns the cached response for the key, or nil on a miss.
+func LoadFromCache(ctx context.Context, c cache.Cache, key string) (*CachedResponse, error) {
+	data, err := c.Get(ctx, key)
+	if errors.Is(err, cache.ErrMiss) {
 		return nil, nil
 	}
-	var cachedResponseJSON []map[string]any
-	err = json.Unmarshal([]byte(cachedResponse), &cachedResponseJSON)
 	if err != nil {
-		log.Error().Err(err).Msg("Error unmarshalling cached response")
 		return nil, err
 	}
-	return cachedResponseJSON, nil
+	var response CachedResponse
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
 }
 
-// CacheMiddleware is a Redis cache middleware for HTTP handlers with a configurable TTL.
-func CacheMiddleware(ttl time.Duration) func(http.Handler) http.Handler {
+// CacheMiddleware is a response cache middleware for HTTP handlers with a configurable TTL.
+// Successful GET responses are stored with their status, headers and body, indexed under the
+// tags returned by tagFuncs, and replayed for later GET and HEAD requests. Requests sent with
+// Cache-Control: no-cache skip the lookup but refresh the entry; no-store bypasses the cache.
+// Cached responses keep their ETag and Last-Modified, so conditional requests are answered with
+// 304 straight from the cache.
+//
+// Concurrent misses for a key run the handler once: within the process through singleflight,
+// and across replicas through a short lock when the cache is a cache.Locker. For staleTTL after
+// an entry expires, it is still served while one request refreshes it in the background
+// (stale-while-revalidate). A zero staleTTL disables this.
+func CacheMiddleware(c cache.Cache, ttl, staleTTL time.Duration, tagFuncs ...TagFunc) func(http.Handler) http.Handler {
+	if ttl == 0 {
+		ttl = cache.DefaultTTL
+	}
 	return func(next http.Handler) http.Handler {
+		f := &filler{cache: c, next: next, ttl: ttl, staleTTL: staleTTL, tagFuncs: tagFuncs}
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			if ttl == 0 {
-				ttl = redis.DefaultTTL
-			}
-			routeKey, err := PrepareRouteKey(r)
-			if err != nil {
-				log.Fatal().Err(err).Msg("Error preparing route key")
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				next.ServeHTTP(w, r)
 				return
 			}
-			cacheKey, err := PrepareCacheKey(r.Body, routeKey)
-			if err != nil {
-				log.Fatal().Err(err).Msg("Error preparing cache key")
+
+			directives := cacheControl(r.Header)
+			if _, ok := directives["no-store"]; ok {
+				next.ServeHTTP(w, r)
 				return
 			}
-			cachedResponseJSON, err := CachedResponseToJSON(cacheKey)
-			if err != nil {
-				log.Fatal().Err(err).Msg("Error getting cached response")
-				return
+
+			key := PrepareRouteKey(r)
+			if _, ok := directives["no-cache"]; !ok {
+				cached, err := LoadFromCache(r.Context(), c, key)
+				if err != nil {
+					log.Error().Err(err).Str("key", key).Msg("Error reading cached response")
+				}
+				if cached != nil {
+					if cached.fresh() {
+						writeCached(w, r, cached, "HIT")
+						return
+					}
+					writeCached(w, r, cached, "STALE")
+					f.revalidate(r, key)
+					return
+				}
 			}
-			if cachedResponseJSON == nil {
+
+			if r.Method == http.MethodHead {
 				next.ServeHTTP(w, r)
-			}
-			if len(cachedResponseJSON) > 0 {
-				helpers.WriteJSON(w, http.StatusOK, cachedResponseJSON)
 				return
 			}
+
+			f.serve(w, r, key)
 		})
 	}
 }
+
+// filler computes the responses of a CacheMiddleware on cache misses.
+type filler struct {
+	cache    cache.Cache
+	next     http.Handler
+	ttl      time.Duration
+	staleTTL time.Duration
+	tagFuncs []TagFunc
+	group    singleflight.Group
+}
+
+// serve answers a cache miss. The first request for the key runs the handler, streaming its
+// response; the requests that arrive meanwhile replay it if it is cacheable and run the
+// handler themselves otherwise.
+func (f *filler) serve(w http.ResponseWriter, r *http.Request, key string) {
+	leader := false
+	result, _, _ := f.group.Do(key, func() (any, error) {
+		leader = true
+		return f.lead(w, r, key), nil
+	})
+	if leader {
+		return
+	}
+	if response, _ := result.(*CachedResponse); response != nil {
+		writeCached(w, r, response, "HIT")
+		return
+	}
+	f.record(w, r, key)
+}
+
+// lead computes the response for the first request of a miss. While another replica holds the
+// fill lock of the key, it waits for that replica's entry, up to fillLockTTL.
+func (f *filler) lead(w http.ResponseWriter, r *http.Request, key string) *CachedResponse {
+	locker, ok := f.cache.(cache.Locker)
+	if !ok {
+		return f.record(w, r, key)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), fillLockTTL)
+	defer cancel()
+	for {
+		unlock, acquired, err := locker.Lock(ctx, lockPrefix+key, fillLockTTL)
+		if err != nil {
+			log.Warn().Err(err).Str("key", key).Msg("Error taking the cache fill lock")
+			return f.record(w, r, key)
+		}
+		if acquired {
+			defer unlock()
+			return f.record(w, r, key)
+		}
+
+		select {
+		case <-ctx.Done():
+			return f.record(w, r, key)
+		case <-time.After(fillPollInterval):
+		}
+		if cached, _ := LoadFromCache(ctx, f.cache, key); cached != nil {
+			writeCached(w, r, cached, "HIT")
+			return cached
+		}
+	}
+}
+
+// revalidate refreshes a stale entry in the background. Only one refresh per key runs at a
+// time, and none when another replica holds the fill lock.
+func (f *filler) revalidate(r *http.Request, key string) {
+	// The refresh must produce a full response, not a 304 for this client.
+	r = r.Clone(context.WithoutCancel(r.Context()))
+	r.Header.Del("If-None-Match")
+	r.Header.Del("If-Modified-Since")
+	f.group.DoChan(key, func() (any, error) {
+		defer func() {
+			if p := recover(); p != nil {
+				log.Error().Interface("panic", p).Str("key", key).Msg("Panic while revalidating cached response")
+			}
+		}()
+
+		ctx, cancel := context.WithTimeout(r.Context(), fillLockTTL)
+		defer cancel()
+		r := r.WithContext(ctx)
+
+		if locker, ok := f.cache.(cache.Locker); ok {
+			unlock, acquired, err := locker.Lock(ctx, lockPrefix+key, fillLockTTL)
+			if err != nil || !acquired {
+				return nil, nil
+			}
+			defer unlock()
+		}
+		return f.record(&discardWriter{header: http.Header{}}, r, key), nil
+	})
+}
+
+// record runs the handler, streaming its response to w, and caches the response if it is
+// cacheable. It returns the cached response, or nil.
+func (f *filler) record(w http.ResponseWriter, r *http.Request, key string) *CachedResponse {
+	w.Header().Set("X-Cache", "MISS")
+	rec := newResponseRecorder(w)
+	f.next.ServeHTTP(rec, r)
+
+	if !rec.cacheable() {
+		return nil
+	}
+	now := time.Now()
+	response := &CachedResponse{
+		Status:    rec.status,
+		Header:    rec.header,
+		Body:      rec.body.Bytes(),
+		StoredAt:  now,
+		ExpiresAt: now.Add(f.ttl),
+	}
+	if err := SaveToCache(r.Context(), f.cache, key, *response, f.ttl+f.staleTTL, requestTags(r, f.tagFuncs)...); err != nil {
+		log.Error().Err(err).Str("key", key).Msg("Error saving response to cache")
+	}
+	return response
+}
+
+// writeCached replays a cached response, adding its age. status is reported in X-Cache.
+// Conditional requests are answered with 304 when the cached ETag or Last-Modified match.
+// Headers already set for this request, such as CORS or rate limit headers, are kept.
+func writeCached(w http.ResponseWriter, r *http.Request, cached *CachedResponse, status string) {
+	replayHeader(w.Header(), cached.Header)
+	header := w.Header()
+	header.Set("X-Cache", status)
+	header.Set("Age", strconv.Itoa(int(time.Since(cached.StoredAt).Seconds())))
+
+	lastModified, _ := http.ParseTime(cached.Header.Get("Last-Modified"))
+	if cached.Status == http.StatusOK && helpers.NotModified(r, cached.Header.Get("ETag"), lastModified) {
+		helpers.WriteNotModified(w)
+		return
+	}
+
+	header.Set("Content-Length", strconv.Itoa(len(cached.Body)))
+	w.WriteHeader(cached.Status)
+	if r.Method != http.MethodHead {
+		_, _ = w.Write(cached.Body)
+	}
+}
+
+// cacheControl parses the Cache-Control directives of a header. Directive names are lower-cased.
+func cacheControl(h http.Header) map[string]string {
+	directives := map[string]string{}
+	for _, value := range h.Values("Cache-Control") {
+		for _, directive := range strings.Split(value, ",") {
+			name, arg, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			if name != "" {
+				directives[strings.ToLower(name)] = strings.Trim(arg, `"`)
+			}
+		}
+	}
+	return directives
+}
+
+// replayHeader copies the stored headers of a response into header, skipping those already set.
+func replayHeader(header, stored http.Header) {
+	for key, values := range stored {
+		if _, ok := header[key]; !ok {
+			header[key] = values
+		}
+	}
+}
+
+// responseRecorder passes a response through to the client while keeping a copy of it.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	// before is the header set by the outer middleware, which is specific to the request.
+	before      http.Header
+	header      http.Header
+	body        bytes.Buffer
+	wroteHeader bool
+	tooLarge    bool
+}
+
+// newResponseRecorder returns a recorder of the response written to w. It records only the
+// headers the handler adds or changes, not those the outer middleware already set.
+func newResponseRecorder(w http.ResponseWriter) *responseRecorder {
+	return &responseRecorder{ResponseWriter: w, status: http.StatusOK, before: w.Header().Clone()}
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	if rec.wroteHeader {
+		return
+	}
+	rec.wroteHeader = true
+	rec.status = status
+	rec.header = http.Header{}
+	for key, values := range rec.ResponseWriter.Header() {
+		if !slices.Equal(rec.before[key], values) {
+			rec.header[key] = slices.Clone(values)
+		}
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(p []byte) (int, error) {
+	if !rec.wroteHeader {
+		rec.WriteHeader(http.StatusOK)
+	}
+	if !rec.tooLarge {
+		if rec.body.Len()+len(p) > maxCachedBody {
+			rec.tooLarge = true
+			rec.body = bytes.Buffer{}
+		} else {
+			rec.body.Write(p)
+		}
+	}
+	return rec.ResponseWriter.Write(p)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (rec *responseRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// cacheable reports whether the recorded response may be stored: a complete 2xx response
+// that does not opt out of shared caching.
+func (rec *responseRecorder) cacheable() bool {
+	if !rec.wroteHeader || rec.tooLarge || rec.status < 200 || rec.status >= 300 {
+		return false
+	}
+	directives := cacheControl(rec.header)
+	for _, name := range []string{"no-store", "private"} {
+		if _, ok := directives[name]; ok {
+			return false
+		}
+	}
+	return rec.header.Get("Set-Cookie") == ""
+}
+
+// discardWriter is the ResponseWriter of background revalidations, whose response only goes
+// to the cache.
+type discardWriter struct {
+	header http.Header
+}
+
+func (d *discardWriter) Header() http.Header         { return d.header }
+func (d *discardWriter) Write(p []byte) (int, error) { return len(p), nil }
+func (d *discardWriter) WriteHeader(int)             {}
@@ -0,0 +1,240 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mexirica/chi-template/internal/cache"
+	"github.com/mexirica/chi-template/internal/helpers"
+	"github.com/mexirica/chi-template/internal/middleware"
+)
+
+// countingHandler answers with a fixed status and JSON object and counts its calls.
+type countingHandler struct {
+	status int
+	calls  int
+}
+
+func (h *countingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.calls++
+	w.Header().Set("X-Movie", "1")
+	w.Header().Set("ETag", `"1-1"`)
+	helpers.WriteJSON(w, h.status, map[string]any{"id": 1, "title": "Alien"})
+}
+
+func setup(t *testing.T, status int) (http.Handler, *countingHandler) {
+	t.Helper()
+	h := &countingHandler{status: status}
+	return middleware.CacheMiddleware(cache.NewMemory(100), time.Minute, 0)(h), h
+}
+
+func serve(h http.Handler, method string, header http.Header) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, "/movies/1", nil)
+	for key, values := range header {
+		r.Header[key] = values
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestCacheMiddleware_ReplaysResponse(t *testing.T) {
+	h, next := setup(t, http.StatusOK)
+
+	first := serve(h, http.MethodGet, nil)
+	second := serve(h, http.MethodGet, nil)
+
+	if next.calls != 1 {
+		t.Fatalf("expected the handler to run once, ran %d times", next.calls)
+	}
+	if first.Header().Get("X-Cache") != "MISS" || second.Header().Get("X-Cache") != "HIT" {
+		t.Errorf("unexpected X-Cache headers %q, %q", first.Header().Get("X-Cache"), second.Header().Get("X-Cache"))
+	}
+	if second.Code != http.StatusOK || second.Body.String() != first.Body.String() {
+		t.Errorf("cached response differs: %d %q, want %d %q", second.Code, second.Body, first.Code, first.Body)
+	}
+	if second.Header().Get("X-Movie") != "1" || second.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("cached headers were not replayed: %v", second.Header())
+	}
+
+	head := serve(h, http.MethodHead, nil)
+	if head.Header().Get("X-Cache") != "HIT" || head.Body.Len() != 0 {
+		t.Errorf("expected a bodiless cache hit for HEAD, got %v %q", head.Header(), head.Body)
+	}
+}
+
+func TestCacheMiddleware_KeepsRequestHeaders(t *testing.T) {
+	h, next := setup(t, http.StatusOK)
+	// cors echoes the origin of each request, as the CORS middleware does for credentialed
+	// requests, and numbers the requests like a rate limit budget.
+	var requests atomic.Int32
+	cors := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", r.Header.Get("Origin"))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(int(100-requests.Add(1))))
+		h.ServeHTTP(w, r)
+	})
+
+	serve(cors, http.MethodGet, http.Header{"Origin": {"https://a.example"}})
+	w := serve(cors, http.MethodGet, http.Header{"Origin": {"https://b.example"}})
+
+	if next.calls != 1 || w.Header().Get("X-Cache") != "HIT" {
+		t.Fatalf("expected a cache hit, got %v after %d calls", w.Header(), next.calls)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://b.example" {
+		t.Errorf("expected the origin of the second request, got %q", got)
+	}
+	if got := w.Header().Get("RateLimit-Remaining"); got != "98" {
+		t.Errorf("expected the rate limit of the second request, got %q", got)
+	}
+	if w.Header().Get("X-Movie") != "1" || w.Header().Get("ETag") != `"1-1"` {
+		t.Errorf("expected the headers of the handler to be replayed, got %v", w.Header())
+	}
+}
+
+func TestCacheMiddleware_SkipsErrors(t *testing.T) {
+	h, next := setup(t, http.StatusNotFound)
+
+	serve(h, http.MethodGet, nil)
+	second := serve(h, http.MethodGet, nil)
+
+	if next.calls != 2 || second.Code != http.StatusNotFound {
+		t.Errorf("expected error responses not to be cached, handler ran %d times", next.calls)
+	}
+}
+
+func TestCacheMiddleware_RequestCacheControl(t *testing.T) {
+	h, next := setup(t, http.StatusOK)
+
+	serve(h, http.MethodGet, http.Header{"Cache-Control": {"no-store"}})
+	serve(h, http.MethodGet, nil)
+	if next.calls != 2 {
+		t.Fatalf("expected no-store to bypass the cache, handler ran %d times", next.calls)
+	}
+
+	refreshed := serve(h, http.MethodGet, http.Header{"Cache-Control": {"no-cache"}})
+	if next.calls != 3 || refreshed.Header().Get("X-Cache") != "MISS" {
+		t.Fatalf("expected no-cache to skip the lookup, handler ran %d times", next.calls)
+	}
+
+	if hit := serve(h, http.MethodGet, nil); hit.Header().Get("X-Cache") != "HIT" || next.calls != 3 {
+		t.Errorf("expected a cache hit after refreshing, handler ran %d times", next.calls)
+	}
+}
+
+// slowHandler answers after delay and counts its calls.
+type slowHandler struct {
+	delay time.Duration
+	calls atomic.Int32
+}
+
+func (h *slowHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.calls.Add(1)
+	time.Sleep(h.delay)
+	helpers.WriteJSON(w, http.StatusOK, map[string]any{"id": 1, "title": "Alien"})
+}
+
+// lockedCache is a cache whose fill locks are always held by another replica.
+type lockedCache struct {
+	*cache.Memory
+}
+
+func (lockedCache) Lock(context.Context, string, time.Duration) (func(), bool, error) {
+	return func() {}, false, nil
+}
+
+func TestCacheMiddleware_CoalescesMisses(t *testing.T) {
+	next := &slowHandler{delay: 50 * time.Millisecond}
+	h := middleware.CacheMiddleware(cache.NewMemory(100), time.Minute, 0)(next)
+
+	var wg sync.WaitGroup
+	responses := make([]*httptest.ResponseRecorder, 10)
+	for i := range responses {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			responses[i] = serve(h, http.MethodGet, nil)
+		}()
+	}
+	wg.Wait()
+
+	if calls := next.calls.Load(); calls != 1 {
+		t.Errorf("expected concurrent misses to run the handler once, ran %d times", calls)
+	}
+	for _, w := range responses {
+		if w.Code != http.StatusOK || w.Body.String() != responses[0].Body.String() {
+			t.Errorf("unexpected response %d %q", w.Code, w.Body)
+		}
+	}
+}
+
+func TestCacheMiddleware_WaitsForOtherReplica(t *testing.T) {
+	c := lockedCache{cache.NewMemory(100)}
+	next := &slowHandler{}
+	h := middleware.CacheMiddleware(c, time.Minute, 0)(next)
+
+	// Another replica holds the lock and stores the response shortly after.
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		response := middleware.CachedResponse{Status: http.StatusOK, Body: []byte(`{"id":1}`), StoredAt: time.Now()}
+		_ = middleware.SaveToCache(context.Background(), c, middleware.PrepareRouteKey(httptest.NewRequest(http.MethodGet, "/movies/1", nil)), response, time.Minute)
+	}()
+
+	w := serve(h, http.MethodGet, nil)
+	if next.calls.Load() != 0 || w.Header().Get("X-Cache") != "HIT" || w.Body.String() != `{"id":1}` {
+		t.Errorf("expected the other replica's response, got %v %q after %d handler calls", w.Header(), w.Body, next.calls.Load())
+	}
+}
+
+func TestCacheMiddleware_StaleWhileRevalidate(t *testing.T) {
+	c := cache.NewMemory(100)
+	next := &slowHandler{}
+	h := middleware.CacheMiddleware(c, 20*time.Millisecond, time.Minute)(next)
+
+	serve(h, http.MethodGet, nil)
+	time.Sleep(30 * time.Millisecond)
+
+	expired := time.Now()
+	stale := serve(h, http.MethodGet, nil)
+	if stale.Header().Get("X-Cache") != "STALE" || stale.Code != http.StatusOK {
+		t.Fatalf("expected the expired entry to be served stale, got %d %v", stale.Code, stale.Header())
+	}
+
+	key := middleware.PrepareRouteKey(httptest.NewRequest(http.MethodGet, "/movies/1", nil))
+	deadline := time.Now().Add(time.Second)
+	for {
+		cached, _ := middleware.LoadFromCache(context.Background(), c, key)
+		if cached != nil && cached.StoredAt.After(expired) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the stale entry to be refreshed in the background")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if calls := next.calls.Load(); calls != 2 {
+		t.Errorf("expected a single background refresh, handler ran %d times", calls)
+	}
+}
+
+func TestCacheMiddleware_ConditionalHit(t *testing.T) {
+	h, next := setup(t, http.StatusOK)
+	serve(h, http.MethodGet, nil)
+
+	w := serve(h, http.MethodGet, http.Header{"If-None-Match": {`"1-1"`}})
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 || next.calls != 1 {
+		t.Fatalf("expected a 304 from the cache, got %d %q after %d handler calls", w.Code, w.Body, next.calls)
+	}
+	if w.Header().Get("ETag") != `"1-1"` || w.Header().Get("X-Cache") != "HIT" {
+		t.Errorf("expected the cached validators on the 304, got %v", w.Header())
+	}
+
+	if w := serve(h, http.MethodGet, http.Header{"If-None-Match": {`"1-0"`}}); w.Code != http.StatusOK || w.Body.Len() == 0 {
+		t.Errorf("expected the full response for a stale ETag, got %d", w.Code)
+	}
+}
@@ -0,0 +1,182 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/mexirica/chi-template/internal/auth"
+	"github.com/mexirica/chi-template/internal/cache"
+	"github.com/mexirica/chi-template/internal/errs"
+	"github.com/mexirica/chi-template/internal/helpers"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// maxIdempotencyKey bounds the length of an Idempotency-Key header.
+	maxIdempotencyKey = 255
+	// maxIdempotentBody bounds the request body of an idempotent request, which is read in
+	// full to fingerprint it.
+	maxIdempotentBody = 1 << 20
+	// idempotencyLockTTL bounds how long a request holds its key. A request still running
+	// past it no longer blocks retries.
+	idempotencyLockTTL = 30 * time.Second
+)
+
+// idempotencyRecord is the outcome of a request stored under its Idempotency-Key.
+type idempotencyRecord struct {
+	// Fingerprint identifies the request the key was first used with.
+	Fingerprint string         `json:"fingerprint"`
+	Response    CachedResponse `json:"response"`
+}
+
+// Idempotency makes retries of unsafe requests safe. The first request sent with an
+// Idempotency-Key header runs normally and its response is stored for ttl, together with a
+// fingerprint of the request; retries with the same key get that response back, marked with
+// Idempotent-Replayed: true. A retry arriving while the first request still runs gets 409
+// Conflict, and a key reused for a different request gets 422 Unprocessable Entity. Requests
+// without the header, and server errors, which the client should be able to retry, are not
+// recorded.
+//
+// Keys are scoped to the caller, the method and the path, so that callers cannot replay each
+// other's responses by guessing their keys. When the cache cannot be reached, requests run
+// without protection rather than failing.
+func Idempotency(c cache.Cache, ttl time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get("Idempotency-Key")
+			if key == "" || r.Method == http.MethodGet || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxIdempotencyKey {
+				helpers.ErrorJSON(w, r, errs.Validation("Idempotency-Key must be at most %d characters", maxIdempotencyKey))
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBody))
+			if err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					helpers.ErrorJSON(w, r, errs.Validation("request body is too large"), http.StatusRequestEntityTooLarge)
+					return
+				}
+				helpers.ErrorJSON(w, r, errs.Wrap(errs.ErrValidation, err, "failed to read request body"))
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			storeKey := "idempotency:" + idempotencyCaller(r) + ":" + r.Method + "." + r.URL.Path + "." + key
+			fingerprint := requestFingerprint(r, body)
+
+			if replayIdempotent(w, r, c, storeKey, fingerprint) {
+				return
+			}
+
+			if locker, ok := c.(cache.Locker); ok {
+				unlock, acquired, err := locker.Lock(r.Context(), lockPrefix+storeKey, idempotencyLockTTL)
+				switch {
+				case err != nil:
+					log.Warn().Err(err).Str("key", storeKey).Msg("Error locking idempotency key; running the request unprotected")
+				case !acquired:
+					// The first request may have completed since the lookup.
+					if replayIdempotent(w, r, c, storeKey, fingerprint) {
+						return
+					}
+					w.Header().Set("Retry-After", "1")
+					helpers.ErrorJSON(w, r, errs.Conflict("a request with this Idempotency-Key is still being processed"))
+					return
+				default:
+					defer unlock()
+					if replayIdempotent(w, r, c, storeKey, fingerprint) {
+						return
+					}
+				}
+			}
+
+			rec := newResponseRecorder(w)
+			next.ServeHTTP(rec, r)
+
+			if !rec.wroteHeader || rec.tooLarge || rec.status >= 500 {
+				return
+			}
+			record := idempotencyRecord{
+				Fingerprint: fingerprint,
+				Response: CachedResponse{
+					Status:   rec.status,
+					Header:   rec.header,
+					Body:     rec.body.Bytes(),
+					StoredAt: time.Now(),
+				},
+			}
+			if err := saveIdempotencyRecord(context.WithoutCancel(r.Context()), c, storeKey, record, ttl); err != nil {
+				log.Error().Err(err).Str("key", storeKey).Msg("Error saving idempotent response")
+			}
+		})
+	}
+}
+
+// idempotencyCaller identifies the caller owning the idempotency keys of the request: its
+// subject, "user:<id>" or "apikey:<id>", or "anonymous".
+func idempotencyCaller(r *http.Request) string {
+	if claims, ok := auth.ClaimsFromContext(r.Context()); ok && claims.Subject != "" {
+		return claims.Subject
+	}
+	return "anonymous"
+}
+
+// replayIdempotent answers the request from the record stored under key, if any. A record of a
+// different request is answered with 422. Only the headers of the handler are replayed, next to
+// those the outer middleware set for this retry.
+func replayIdempotent(w http.ResponseWriter, r *http.Request, c cache.Cache, key, fingerprint string) bool {
+	data, err := c.Get(r.Context(), key)
+	if err != nil {
+		if !errors.Is(err, cache.ErrMiss) {
+			log.Error().Err(err).Str("key", key).Msg("Error reading idempotency record")
+		}
+		return false
+	}
+	var record idempotencyRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		log.Error().Err(err).Str("key", key).Msg("Error decoding idempotency record")
+		return false
+	}
+
+	if record.Fingerprint != fingerprint {
+		helpers.ErrorJSON(w, r, errs.Validation("Idempotency-Key was already used for a different request"), http.StatusUnprocessableEntity)
+		return true
+	}
+
+	header := w.Header()
+	replayHeader(header, record.Response.Header)
+	header.Set("Idempotent-Replayed", "true")
+	w.WriteHeader(record.Response.Status)
+	_, _ = w.Write(record.Response.Body)
+	return true
+}
+
+func saveIdempotencyRecord(ctx context.Context, c cache.Cache, key string, record idempotencyRecord, ttl time.Duration) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return c.Set(ctx, key, data, ttl)
+}
+
+// requestFingerprint digests what makes two requests the same: the method, the path and
+// query, the content type and the body.
+func requestFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	for _, part := range []string{r.Method, r.URL.RequestURI(), r.Header.Get("Content-Type")} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
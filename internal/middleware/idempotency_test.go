@@ -0,0 +1,161 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/mexirica/chi-template/internal/auth"
+	"github.com/mexirica/chi-template/internal/cache"
+	"github.com/mexirica/chi-template/internal/helpers"
+	"github.com/mexirica/chi-template/internal/middleware"
+)
+
+// createHandler answers like POST /movies, numbering the movies it creates.
+type createHandler struct {
+	calls   atomic.Int32
+	status  int
+	release chan struct{}
+}
+
+func (h *createHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	n := h.calls.Add(1)
+	if h.release != nil {
+		<-h.release
+	}
+	w.Header().Set("Location", "/movies/"+strconv.Itoa(int(n)))
+	helpers.WriteJSON(w, h.status, map[string]any{"id": n})
+}
+
+func post(h http.Handler, key, body string) *httptest.ResponseRecorder {
+	return postAs(h, "", key, body)
+}
+
+// postAs posts like post, authenticated as subject unless it is empty.
+func postAs(h http.Handler, subject, key, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "/movies", strings.NewReader(body))
+	if subject != "" {
+		r = r.WithContext(auth.WithClaims(r.Context(), &auth.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: subject}}))
+	}
+	r.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		r.Header.Set("Idempotency-Key", key)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestIdempotency_ReplaysRetries(t *testing.T) {
+	next := &createHandler{status: http.StatusCreated}
+	h := middleware.Idempotency(cache.NewMemory(100), time.Hour)(next)
+
+	first := post(h, "key-1", `{"title":"Alien"}`)
+	retry := post(h, "key-1", `{"title":"Alien"}`)
+
+	if calls := next.calls.Load(); calls != 1 {
+		t.Fatalf("expected the handler to run once, ran %d times", calls)
+	}
+	if retry.Code != http.StatusCreated || retry.Body.String() != first.Body.String() ||
+		retry.Header().Get("Location") != first.Header().Get("Location") {
+		t.Errorf("retry differs: %d %q %v, want %d %q %v", retry.Code, retry.Body, retry.Header(), first.Code, first.Body, first.Header())
+	}
+	if retry.Header().Get("Idempotent-Replayed") != "true" || first.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("expected only the retry to be marked as replayed")
+	}
+
+	post(h, "", `{"title":"Alien"}`)
+	post(h, "key-2", `{"title":"Alien"}`)
+	if calls := next.calls.Load(); calls != 3 {
+		t.Errorf("expected requests without the key or with a new key to run, handler ran %d times", calls)
+	}
+}
+
+func TestIdempotency_KeepsRequestHeaders(t *testing.T) {
+	next := &createHandler{status: http.StatusCreated}
+	idempotent := middleware.Idempotency(cache.NewMemory(100), time.Hour)(next)
+	var requests atomic.Int32
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(int(100-requests.Add(1))))
+		idempotent.ServeHTTP(w, r)
+	})
+
+	post(h, "key-1", `{"title":"Alien"}`)
+	retry := post(h, "key-1", `{"title":"Alien"}`)
+
+	if retry.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("expected the retry to be replayed, got %v", retry.Header())
+	}
+	if got := retry.Header().Get("RateLimit-Remaining"); got != "98" {
+		t.Errorf("expected the rate limit of the retry, got %q", got)
+	}
+	if retry.Header().Get("Location") != "/movies/1" {
+		t.Errorf("expected the Location of the first response, got %q", retry.Header().Get("Location"))
+	}
+}
+
+func TestIdempotency_KeysAreScopedToTheCaller(t *testing.T) {
+	next := &createHandler{status: http.StatusCreated}
+	h := middleware.Idempotency(cache.NewMemory(100), time.Hour)(next)
+
+	postAs(h, "user:1", "key-1", `{"title":"Alien"}`)
+	w := postAs(h, "user:2", "key-1", `{"title":"Alien"}`)
+
+	if next.calls.Load() != 2 || w.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("expected another caller's request with the same key to run, handler ran %d times", next.calls.Load())
+	}
+	if w := postAs(h, "user:1", "key-1", `{"title":"Alien"}`); w.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("expected the retry of the first caller to be replayed")
+	}
+}
+
+func TestIdempotency_KeyReusedForDifferentRequest(t *testing.T) {
+	next := &createHandler{status: http.StatusCreated}
+	h := middleware.Idempotency(cache.NewMemory(100), time.Hour)(next)
+
+	post(h, "key-1", `{"title":"Alien"}`)
+	w := post(h, "key-1", `{"title":"Aliens"}`)
+
+	if w.Code != http.StatusUnprocessableEntity || next.calls.Load() != 1 {
+		t.Errorf("expected 422 without running the handler, got %d after %d calls", w.Code, next.calls.Load())
+	}
+}
+
+func TestIdempotency_InFlight(t *testing.T) {
+	next := &createHandler{status: http.StatusCreated, release: make(chan struct{})}
+	h := middleware.Idempotency(cache.NewMemory(100), time.Hour)(next)
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- post(h, "key-1", `{"title":"Alien"}`) }()
+	for next.calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if w := post(h, "key-1", `{"title":"Alien"}`); w.Code != http.StatusConflict || w.Header().Get("Retry-After") == "" {
+		t.Errorf("expected 409 with Retry-After while the first request runs, got %d %v", w.Code, w.Header())
+	}
+
+	close(next.release)
+	if first := <-done; first.Code != http.StatusCreated {
+		t.Fatalf("unexpected status %d for the first request", first.Code)
+	}
+	if w := post(h, "key-1", `{"title":"Alien"}`); w.Code != http.StatusCreated || w.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("expected the stored response once the first request completed, got %d %v", w.Code, w.Header())
+	}
+}
+
+func TestIdempotency_ServerErrorsAreRetried(t *testing.T) {
+	next := &createHandler{status: http.StatusInternalServerError}
+	h := middleware.Idempotency(cache.NewMemory(100), time.Hour)(next)
+
+	post(h, "key-1", `{"title":"Alien"}`)
+	next.status = http.StatusCreated
+	if w := post(h, "key-1", `{"title":"Alien"}`); w.Code != http.StatusCreated || next.calls.Load() != 2 {
+		t.Errorf("expected the retry of a failed request to run, got %d after %d calls", w.Code, next.calls.Load())
+	}
+}
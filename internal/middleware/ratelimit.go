@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mexirica/chi-template/internal/auth"
+	"github.com/mexirica/chi-template/internal/errs"
+	"github.com/mexirica/chi-template/internal/helpers"
+	"github.com/mexirica/chi-template/internal/ratelimit"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/rs/zerolog/log"
+)
+
+var rateLimitRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "ratelimit_requests_total",
+	Help: "Rate-limited requests by route group and result: allowed or limited.",
+}, []string{"group", "result"})
+
+// RateLimit limits the requests of each client of the route group to limit. Clients are
+// told where they stand in the RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset and
+// RateLimit-Policy headers; requests over the limit get 429 Too Many Requests with
+// Retry-After. Clients are told apart by key, and each group counts its requests apart
+// from the others.
+//
+// A disabled limit lets every request through, and so does a failing limiter rather than
+// failing the request.
+func RateLimit(limiter ratelimit.Limiter, group string, limit ratelimit.Limit, key func(*http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !limit.Enabled() {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			result, err := limiter.Allow(r.Context(), group+":"+key(r), limit)
+			if err != nil {
+				log.Error().Err(err).Str("group", group).Msg("Error checking rate limit")
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h := w.Header()
+			h.Set("RateLimit-Limit", strconv.Itoa(limit.Requests))
+			h.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			h.Set("RateLimit-Reset", strconv.Itoa(seconds(result.ResetAfter)))
+			h.Set("RateLimit-Policy", limit.String())
+
+			if !result.Allowed {
+				rateLimitRequests.WithLabelValues(group, "limited").Inc()
+				helpers.ErrorJSON(w, r, errs.TooManyRequests(result.RetryAfter, "rate limit exceeded, try again later"))
+				return
+			}
+			rateLimitRequests.WithLabelValues(group, "allowed").Inc()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ClientKey identifies the client of a request for rate limiting: by subject when the caller
+// presented a verified token or API key, "user:<id>" or "apikey:<id>", and by IP address
+// otherwise. Subjects set by a gateway header are not used, as every new subject would get a
+// fresh limit.
+func ClientKey(trustedProxies []netip.Prefix) func(*http.Request) string {
+	return func(r *http.Request) string {
+		if claims, ok := auth.ClaimsFromContext(r.Context()); ok && claims.Subject != "" && !claims.FromGateway {
+			return claims.Subject
+		}
+		return IPKey(trustedProxies)(r)
+	}
+}
+
+// IPKey identifies the client of a request for rate limiting by IP address, whoever the
+// caller claims to be. It limits requests before their credentials are checked.
+func IPKey(trustedProxies []netip.Prefix) func(*http.Request) string {
+	return func(r *http.Request) string {
+		return "ip:" + ClientIP(r, trustedProxies).String()
+	}
+}
+
+// ClientIP returns the address of the client of the request. When the request comes from a
+// trusted proxy, X-Forwarded-For is read from the right, each proxy appending the address
+// it received the request from, and the first address that is not a trusted proxy is the
+// client: the addresses on its left are set by the client and cannot be trusted.
+func ClientIP(r *http.Request, trustedProxies []netip.Prefix) netip.Addr {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}
+	}
+	addr = addr.Unmap()
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0 && trusted(addr, trustedProxies); i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		addr = hop.Unmap()
+	}
+	return addr
+}
+
+func trusted(addr netip.Addr, proxies []netip.Prefix) bool {
+	for _, proxy := range proxies {
+		if proxy.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// seconds rounds d up to whole seconds, as the rate limit headers count.
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,125 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/mexirica/chi-template/internal/auth"
+	"github.com/mexirica/chi-template/internal/middleware"
+	"github.com/mexirica/chi-template/internal/ratelimit"
+)
+
+func TestRateLimit(t *testing.T) {
+	limit := ratelimit.Limit{Requests: 2, Period: time.Minute}
+	handler := middleware.RateLimit(ratelimit.NewMemory(10), "api", limit, middleware.ClientKey(nil))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	serve := func(remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/movies/list", nil)
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve("192.0.2.1:1234")
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+	h := rec.Header()
+	if h.Get("RateLimit-Limit") != "2" || h.Get("RateLimit-Remaining") != "1" || h.Get("RateLimit-Reset") != "30" || h.Get("RateLimit-Policy") != "2;w=60" {
+		t.Errorf("unexpected rate limit headers %v", h)
+	}
+
+	serve("192.0.2.1:1234")
+	rec = serve("192.0.2.1:5678")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "30" || rec.Header().Get("RateLimit-Remaining") != "0" {
+		t.Errorf("expected 429 with Retry-After 30, got %d %v", rec.Code, rec.Header())
+	}
+
+	if rec := serve("192.0.2.2:1234"); rec.Code != http.StatusNoContent {
+		t.Errorf("other client: status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+}
+
+func TestRateLimit_Disabled(t *testing.T) {
+	handler := middleware.RateLimit(ratelimit.NewMemory(10), "api", ratelimit.Limit{}, middleware.ClientKey(nil))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/movies/list", nil))
+	if rec.Code != http.StatusNoContent || rec.Header().Get("RateLimit-Limit") != "" {
+		t.Errorf("expected an unlimited request, got %d %v", rec.Code, rec.Header())
+	}
+}
+
+func TestClientKey(t *testing.T) {
+	key := middleware.ClientKey(nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/movies/list", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	if got := key(req); got != "ip:192.0.2.1" {
+		t.Errorf("anonymous key = %q, want ip:192.0.2.1", got)
+	}
+
+	req = req.WithContext(auth.WithClaims(req.Context(), &auth.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "user:42"}}))
+	if got := key(req); got != "user:42" {
+		t.Errorf("authenticated key = %q, want user:42", got)
+	}
+
+	req = req.WithContext(auth.WithClaims(req.Context(), &auth.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "user:43"}, FromGateway: true}))
+	if got := key(req); got != "ip:192.0.2.1" {
+		t.Errorf("gateway key = %q, want ip:192.0.2.1", got)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	proxies := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		want       string
+	}{
+		{"direct", "192.0.2.1:1234", nil, "192.0.2.1"},
+		{"untrusted proxy", "192.0.2.1:1234", []string{"198.51.100.7"}, "192.0.2.1"},
+		{"trusted proxy", "10.0.0.1:1234", []string{"198.51.100.7"}, "198.51.100.7"},
+		{"proxy chain", "10.0.0.1:1234", []string{"198.51.100.7, 10.0.0.2"}, "198.51.100.7"},
+		{"spoofed by the client", "10.0.0.1:1234", []string{"203.0.113.9, 198.51.100.7"}, "198.51.100.7"},
+		{"several headers", "10.0.0.1:1234", []string{"203.0.113.9", "198.51.100.7"}, "198.51.100.7"},
+		{"malformed entry", "10.0.0.1:1234", []string{"198.51.100.7, garbage"}, "10.0.0.1"},
+		{"only proxies", "10.0.0.1:1234", []string{"10.0.0.3, 10.0.0.2"}, "10.0.0.3"},
+		{"mapped IPv4", "[::ffff:192.0.2.1]:1234", nil, "192.0.2.1"},
+		{"IPv6", "[2001:db8::1]:1234", nil, "2001:db8::1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.forwarded {
+				req.Header.Add("X-Forwarded-For", value)
+			}
+			if got := middleware.ClientIP(req, proxies); got.String() != tt.want {
+				t.Errorf("ClientIP() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIPKey(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/movies/list", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	req = req.WithContext(auth.WithClaims(req.Context(), &auth.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "user:42"}}))
+
+	if got := middleware.IPKey(nil)(req); got != "ip:192.0.2.1" {
+		t.Errorf("IPKey() = %q, want ip:192.0.2.1", got)
+	}
+}
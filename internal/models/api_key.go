@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// APIKey is the API key of a machine client. The key itself is never stored, only its hash.
+type APIKey struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	// Prefix is the beginning of the key, enough to recognise it.
+	Prefix string `json:"prefix"`
+	// Scopes are the permissions granted to the key's requests, e.g. "movies:create".
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Active reports whether the key is neither revoked nor expired at now.
+func (k APIKey) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+type CreateAPIKeyRequest struct {
+	Name   string   `json:"name" validate:"required,max=255"`
+	Scopes []string `json:"scopes" validate:"required,min=1"`
+	// ExpiresAt is when the key stops working. Keys without it never expire.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// NewAPIKey is an API key about to be stored, identified by the hash of its key.
+type NewAPIKey struct {
+	Name      string
+	Prefix    string
+	Hash      []byte
+	Scopes    []string
+	ExpiresAt *time.Time
+}
+
+// CreatedAPIKey is a newly created API key along with the key itself, which is shown only
+// once.
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
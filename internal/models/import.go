@@ -0,0 +1,20 @@
+package models
+
+import "github.com/mexirica/chi-template/internal/validation"
+
+// ImportReport summarises a bulk movie import. Rows are numbered from 1, not counting a CSV header.
+type ImportReport struct {
+	DryRun   bool  `json:"dry_run"`
+	Total    int   `json:"total"`
+	Imported int64 `json:"imported"`
+	Failed   int   `json:"failed"`
+	// Errors lists the rejected rows. It is truncated for very large imports; Failed is always exact.
+	Errors []ImportRowError `json:"errors"`
+}
+
+// ImportRowError explains why a row was not imported.
+type ImportRowError struct {
+	Row    int                                  `json:"row"`
+	Detail string                               `json:"detail"`
+	Errors []validation.ValidationErrorResponse `json:"errors,omitempty"`
+}
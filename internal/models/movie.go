@@ -1,6 +1,19 @@
 // Package models defines the application's data structures and domain models.
 package models
 
+import (
+	"strconv"
+	"time"
+)
+
+// MovieListCacheTag tags every cached response that lists or suggests several movies.
+const MovieListCacheTag = "movies:list"
+
+// MovieCacheTag tags the cached responses that show the movie with the given id.
+func MovieCacheTag(id int64) string {
+	return "movie:" + strconv.FormatInt(id, 10)
+}
+
 type Movie struct {
 	ID          int64    `json:"id"`
 	Title       string   `json:"title"`
@@ -9,19 +22,68 @@ type Movie struct {
 	Genre       []string `json:"genre"`
 	Director    string   `json:"director"`
 	Rating      float64  `json:"rating"`
+	// UpdatedAt and Version change on every update; they back the ETag and
+	// Last-Modified validators.
+	UpdatedAt time.Time `json:"updated_at"`
+	Version   int64     `json:"version"`
 }
 
 type GetMovieList struct {
 	Movies []GetMovieResponse `json:"movies"`
+	// Total is the number of movies matching the filter. It is an approximation when
+	// TotalEstimated is set.
+	Total          int64 `json:"total"`
+	TotalEstimated bool  `json:"total_estimated,omitempty"`
+	// NextCursor and PrevCursor are opaque tokens for keyset pagination; pass one as the
+	// cursor query parameter to fetch the adjacent page.
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
 }
 
+// MovieSortFields is the allow-list of fields a movie listing can be sorted by.
+var MovieSortFields = []string{"id", "title", "release_year", "rating", "director"}
+
+// SortField is one key of a multi-key sort; Desc reverses its order.
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// MovieFilter holds the filters, sort order and pagination of a movie listing.
+type MovieFilter struct {
+	Genres    []string `json:"genre"`
+	Director  string   `json:"director"`
+	Query     string   `json:"q" validate:"max=255"`
+	YearFrom  int      `json:"year_from" validate:"omitempty,gte=1800,lte=3000"`
+	YearTo    int      `json:"year_to" validate:"omitempty,gte=1800,lte=3000,gtefield=YearFrom"`
+	MinRating float64  `json:"min_rating" validate:"gte=0,lte=10"`
+	Sort      []SortField
+	// Cursor switches the listing to keyset pagination; Page is ignored when it is set.
+	Cursor string `json:"cursor"`
+	Page   int    `json:"page" validate:"gte=1"`
+	Limit  int    `json:"limit" validate:"gte=1,lte=100"`
+}
+
+// CreateMovieRequest is a new movie. Its limits are those of the movies table, so that
+// imports report oversized rows one by one rather than failing the whole batch.
 type CreateMovieRequest struct {
-	Title       string   `json:"title" validate:"required"`
+	Title       string   `json:"title" validate:"required,max=255"`
 	Description string   `json:"description" validate:"required"`
 	ReleaseYear int      `json:"release_year" validate:"required"`
-	Genre       []string `json:"genre" validate:"required"`
-	Director    string   `json:"director" validate:"required"`
-	Rating      float64  `json:"rating" validate:"required"`
+	Genre       []string `json:"genre" validate:"required,dive,max=50"`
+	Director    string   `json:"director" validate:"required,max=255"`
+	Rating      float64  `json:"rating" validate:"gte=0,lte=10"`
+}
+
+// UpdateMovieRequest is the full representation of a movie accepted by PUT.
+// PATCH requests are merged onto the current movie and validated as one of these.
+type UpdateMovieRequest struct {
+	Title       string   `json:"title" validate:"required,max=255"`
+	Description string   `json:"description" validate:"required"`
+	ReleaseYear int      `json:"release_year" validate:"required"`
+	Genre       []string `json:"genre" validate:"required,dive,max=50"`
+	Director    string   `json:"director" validate:"required,max=255"`
+	Rating      float64  `json:"rating" validate:"gte=0,lte=10"`
 }
 
 type GetMovieResponse struct {
@@ -32,4 +94,58 @@ type GetMovieResponse struct {
 	Genre       []string `json:"genre"`
 	Director    string   `json:"director"`
 	Rating      float64  `json:"rating"`
+	// UpdatedAt and Version change on every update; they back the ETag and
+	// Last-Modified validators.
+	UpdatedAt time.Time `json:"updated_at"`
+	Version   int64     `json:"version"`
+}
+
+// TrashedMovie is a deleted movie, which can be restored until it is purged.
+type TrashedMovie struct {
+	GetMovieResponse
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+type MovieTrash struct {
+	Movies []TrashedMovie `json:"movies"`
+	Total  int64          `json:"total"`
+}
+
+// MovieSearch is a full-text search over title, director and description.
+type MovieSearch struct {
+	// Query uses web search syntax: quoted phrases, "or" and -excluded terms.
+	Query string `json:"q" validate:"required,max=255"`
+	Page  int    `json:"page" validate:"gte=1"`
+	Limit int    `json:"limit" validate:"gte=1,lte=100"`
+}
+
+// MovieSearchResult is a movie matching a search, with its relevance and a highlighted snippet.
+type MovieSearchResult struct {
+	GetMovieResponse
+	Rank float64 `json:"rank"`
+	// Headline is an excerpt of the description with matches wrapped in <mark> tags. It is
+	// safe HTML: the description is escaped, so <mark> is the only tag it contains.
+	Headline string `json:"headline"`
+}
+
+type MovieSearchResults struct {
+	Results []MovieSearchResult `json:"results"`
+}
+
+// MovieSuggest asks for autocomplete suggestions for a partially typed title.
+type MovieSuggest struct {
+	Prefix string `json:"prefix" validate:"required,max=100"`
+	Limit  int    `json:"limit" validate:"gte=1,lte=20"`
+}
+
+// MovieSuggestion is a title close to the typed prefix. Similarity is the pg_trgm word
+// similarity between the prefix and the title, from 0 to 1.
+type MovieSuggestion struct {
+	ID         int64   `json:"id"`
+	Title      string  `json:"title"`
+	Similarity float64 `json:"similarity"`
+}
+
+type MovieSuggestions struct {
+	Suggestions []MovieSuggestion `json:"suggestions"`
 }
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// User is a user account. The password is never stored, only its hash.
+type User struct {
+	ID        int64     `json:"id"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// UserCredentials are the user's account along with the hash of its password.
+type UserCredentials struct {
+	User
+	PasswordHash string
+}
+
+type RegisterRequest struct {
+	Email    string `json:"email" validate:"required,email,max=255"`
+	Password string `json:"password" validate:"required,min=8,max=128"`
+}
+
+type LoginRequest struct {
+	Email    string `json:"email" validate:"required,max=255"`
+	Password string `json:"password" validate:"required,max=128"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// TokenResponse is the pair of tokens of a session (RFC 6749, section 5.1). The access token is
+// sent as a bearer token; the refresh token obtains the next pair once, from POST /auth/refresh.
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+}
@@ -0,0 +1,84 @@
+// Package pagination implements the opaque, signed cursor tokens used for keyset pagination.
+package pagination
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"github.com/mexirica/chi-template/internal/errs"
+	"github.com/rs/zerolog/log"
+)
+
+var secret = randomSecret()
+
+// Init sets the key used to sign cursors. Cursors signed with a different key are rejected,
+// so every replica must share the same secret. An empty key keeps a random per-process key.
+func Init(key string) {
+	if key == "" {
+		log.Warn().Msg("CURSOR_SECRET is not set, cursors will not survive restarts or work across replicas")
+		return
+	}
+	secret = []byte(key)
+}
+
+// Cursor is the position of a row in a sorted listing.
+type Cursor struct {
+	// Sort is the canonical sort expression the cursor was issued for.
+	Sort string `json:"s"`
+	// Values holds the sort key values of the boundary row, in sort order.
+	Values []any `json:"v"`
+	// Before selects the rows preceding the boundary row instead of the ones following it.
+	Before bool `json:"b,omitempty"`
+}
+
+// Encode serialises and signs the cursor into an opaque URL-safe token.
+func Encode(c Cursor) (string, error) {
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(sign(payload)), nil
+}
+
+// Decode verifies and parses a token produced by Encode. Numbers are decoded as json.Number.
+// Malformed or tampered tokens are reported as errs.ErrValidation.
+func Decode(token string) (Cursor, error) {
+	var c Cursor
+
+	encodedPayload, encodedSig, ok := strings.Cut(token, ".")
+	if !ok {
+		return c, errs.Validation("invalid cursor")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return c, errs.Validation("invalid cursor")
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(encodedSig)
+	if err != nil || !hmac.Equal(sig, sign(payload)) {
+		return c, errs.Validation("invalid cursor")
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.UseNumber()
+	if err := dec.Decode(&c); err != nil {
+		return c, errs.Validation("invalid cursor")
+	}
+	return c, nil
+}
+
+func sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+func randomSecret() []byte {
+	key := make([]byte, 32)
+	_, _ = rand.Read(key)
+	return key
+}
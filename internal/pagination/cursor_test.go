@@ -0,0 +1,48 @@
+// cursor_test.go
+// Unit tests for cursor token signing and verification.
+package pagination_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/mexirica/chi-template/internal/errs"
+	"github.com/mexirica/chi-template/internal/pagination"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	pagination.Init("test-secret")
+
+	token, err := pagination.Encode(pagination.Cursor{Sort: "title,-id", Values: []any{"Alien", 42}, Before: true})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	cursor, err := pagination.Decode(token)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if cursor.Sort != "title,-id" || !cursor.Before || len(cursor.Values) != 2 {
+		t.Fatalf("unexpected cursor %+v", cursor)
+	}
+	if cursor.Values[0] != "Alien" || cursor.Values[1] != json.Number("42") {
+		t.Errorf("unexpected values %v", cursor.Values)
+	}
+}
+
+func TestDecode_RejectsTamperedTokens(t *testing.T) {
+	pagination.Init("test-secret")
+
+	token, err := pagination.Encode(pagination.Cursor{Sort: "-id", Values: []any{42}})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	pagination.Init("another-secret")
+	for _, tampered := range []string{token, "garbage", token[:len(token)-2], "e30." + token[len(token)-10:]} {
+		if _, err := pagination.Decode(tampered); !errors.Is(err, errs.ErrValidation) {
+			t.Errorf("expected validation error for %q, got %v", tampered, err)
+		}
+	}
+}
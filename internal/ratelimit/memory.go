@@ -0,0 +1,40 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+)
+
+// Memory is an in-process Limiter remembering at most a number of clients, forgetting the
+// least recently seen. It limits each replica on its own, so it is meant for tests, local
+// development and as the fallback of a shared limiter.
+type Memory struct {
+	mu  sync.Mutex
+	tat *lru.Cache[string, time.Time]
+}
+
+var _ Limiter = (*Memory)(nil)
+
+// NewMemory returns an in-memory limiter remembering at most size clients.
+func NewMemory(size int) *Memory {
+	c, err := lru.New[string, time.Time](max(size, 1))
+	if err != nil {
+		panic(err)
+	}
+	return &Memory{tat: c}
+}
+
+func (m *Memory) Allow(_ context.Context, key string, limit Limit) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tat, _ := m.tat.Get(key)
+	result, next := GCRA(time.Now(), tat, limit)
+	if result.Allowed {
+		m.tat.Add(key, next)
+	}
+	return result, nil
+}
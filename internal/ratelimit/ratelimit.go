@@ -0,0 +1,131 @@
+// Package ratelimit limits the rate of requests per client with the generic cell rate
+// algorithm (GCRA), with an in-memory implementation. The Redis implementation, shared by
+// every replica, lives in internal/redis.
+//
+// GCRA keeps a single timestamp per client, the theoretical arrival time (TAT) of its next
+// request. A limit of N requests per period lets a client send N requests at once, then one
+// every period/N: unlike fixed windows, it never allows 2N requests around a window edge.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/rs/zerolog/log"
+)
+
+// Limit allows Requests per Period, all of which may be sent at once.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// Enabled reports whether the limit limits anything.
+func (l Limit) Enabled() bool {
+	return l.Requests > 0 && l.Period > 0
+}
+
+// Interval is the time it takes for one request of the limit to be replenished.
+func (l Limit) Interval() time.Duration {
+	return l.Period / time.Duration(l.Requests)
+}
+
+// String formats the limit as a RateLimit-Policy header: "100;w=60".
+func (l Limit) String() string {
+	return fmt.Sprintf("%d;w=%d", l.Requests, int(l.Period.Seconds()))
+}
+
+// ParseLimit parses a limit written as "<requests>/<period>", e.g. "100/1m" or "10/s". "off"
+// and the empty string parse as the zero Limit, which limits nothing.
+func ParseLimit(s string) (Limit, error) {
+	if s == "" || s == "off" {
+		return Limit{}, nil
+	}
+
+	requests, period, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: want <requests>/<period>", s)
+	}
+	n, err := strconv.Atoi(requests)
+	if err != nil || n <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: the number of requests must be positive", s)
+	}
+	if period != "" && (period[0] < '0' || period[0] > '9') {
+		period = "1" + period
+	}
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: the period must be a positive duration", s)
+	}
+	return Limit{Requests: n, Period: d}, nil
+}
+
+// Result is the outcome of a request against a limit.
+type Result struct {
+	Allowed bool
+	// Remaining is the number of requests that could be sent right now.
+	Remaining int
+	// ResetAfter is how long until the full limit is available again.
+	ResetAfter time.Duration
+	// RetryAfter is how long until the next request is allowed, zero when it is allowed.
+	RetryAfter time.Duration
+}
+
+// Limiter counts the requests of clients against limits.
+type Limiter interface {
+	// Allow records a request of key against limit and reports whether it is allowed.
+	// Denied requests are not recorded.
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// GCRA decides on a request arriving at now for a client whose theoretical arrival time is
+// tat, zero for a new client. It returns the result and the new TAT, unchanged when the
+// request is denied. Limiters store the TAT and call GCRA, or run it in their backend.
+func GCRA(now, tat time.Time, limit Limit) (Result, time.Time) {
+	interval := limit.Interval()
+	if tat.Before(now) {
+		tat = now
+	}
+	next := tat.Add(interval)
+	allowAt := next.Add(-limit.Period)
+	if now.Before(allowAt) {
+		return Result{RetryAfter: allowAt.Sub(now), ResetAfter: tat.Sub(now)}, tat
+	}
+	return Result{
+		Allowed:    true,
+		Remaining:  int((limit.Period - next.Sub(now)) / interval),
+		ResetAfter: next.Sub(now),
+	}, next
+}
+
+var fallbacks = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "ratelimit_fallback_total",
+	Help: "Rate limit decisions taken by the fallback limiter because the primary one failed.",
+})
+
+type fallbackLimiter struct {
+	primary, fallback Limiter
+}
+
+// WithFallback returns a limiter deciding with primary, or with fallback when primary
+// fails. With an in-memory fallback, every replica then limits clients on its own until the
+// primary is back.
+func WithFallback(primary, fallback Limiter) Limiter {
+	return &fallbackLimiter{primary: primary, fallback: fallback}
+}
+
+func (l *fallbackLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	result, err := l.primary.Allow(ctx, key, limit)
+	if err == nil || errors.Is(err, context.Canceled) {
+		return result, err
+	}
+	fallbacks.Inc()
+	log.Debug().Err(err).Msg("Rate limiter unavailable; using the fallback")
+	return l.fallback.Allow(ctx, key, limit)
+}
@@ -0,0 +1,99 @@
+package ratelimit_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mexirica/chi-template/internal/ratelimit"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    ratelimit.Limit
+		wantErr bool
+	}{
+		{"100/1m", ratelimit.Limit{Requests: 100, Period: time.Minute}, false},
+		{"10/s", ratelimit.Limit{Requests: 10, Period: time.Second}, false},
+		{"5/30s", ratelimit.Limit{Requests: 5, Period: 30 * time.Second}, false},
+		{"off", ratelimit.Limit{}, false},
+		{"", ratelimit.Limit{}, false},
+		{"100", ratelimit.Limit{}, true},
+		{"0/1m", ratelimit.Limit{}, true},
+		{"10/fortnight", ratelimit.Limit{}, true},
+		{"10/-1m", ratelimit.Limit{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ratelimit.ParseLimit(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseLimit(%q) = %+v, %v; want %+v, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestGCRA(t *testing.T) {
+	limit := ratelimit.Limit{Requests: 3, Period: 3 * time.Second}
+	now := time.Unix(1_700_000_000, 0)
+
+	// The whole limit may be used at once.
+	var tat time.Time
+	for want := 2; want >= 0; want-- {
+		var result ratelimit.Result
+		result, tat = ratelimit.GCRA(now, tat, limit)
+		if !result.Allowed || result.Remaining != want {
+			t.Fatalf("expected an allowed request with %d remaining, got %+v", want, result)
+		}
+	}
+
+	result, next := ratelimit.GCRA(now, tat, limit)
+	if result.Allowed || result.RetryAfter != time.Second || result.ResetAfter != 3*time.Second || !next.Equal(tat) {
+		t.Fatalf("expected a denied request to retry in 1s, got %+v", result)
+	}
+
+	// Then one request is replenished every second.
+	if result, _ := ratelimit.GCRA(now.Add(time.Second), tat, limit); !result.Allowed || result.Remaining != 0 {
+		t.Errorf("expected a request to be allowed a second later, got %+v", result)
+	}
+	if result, _ := ratelimit.GCRA(now.Add(time.Hour), tat, limit); !result.Allowed || result.Remaining != 2 {
+		t.Errorf("expected the limit to be replenished an hour later, got %+v", result)
+	}
+}
+
+func TestMemory(t *testing.T) {
+	limiter := ratelimit.NewMemory(10)
+	limit := ratelimit.Limit{Requests: 2, Period: time.Hour}
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if result, err := limiter.Allow(ctx, "a", limit); err != nil || !result.Allowed {
+			t.Fatalf("request %d: %+v, %v; want allowed", i, result, err)
+		}
+	}
+	if result, _ := limiter.Allow(ctx, "a", limit); result.Allowed {
+		t.Error("expected the third request to be denied")
+	}
+	if result, _ := limiter.Allow(ctx, "b", limit); !result.Allowed {
+		t.Error("expected other clients to be limited apart")
+	}
+}
+
+type failingLimiter struct{}
+
+func (failingLimiter) Allow(context.Context, string, ratelimit.Limit) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("connection refused")
+}
+
+func TestWithFallback(t *testing.T) {
+	limiter := ratelimit.WithFallback(failingLimiter{}, ratelimit.NewMemory(10))
+	limit := ratelimit.Limit{Requests: 1, Period: time.Hour}
+
+	if result, err := limiter.Allow(context.Background(), "a", limit); err != nil || !result.Allowed {
+		t.Fatalf("expected the fallback to allow the first request, got %+v, %v", result, err)
+	}
+	if result, err := limiter.Allow(context.Background(), "a", limit); err != nil || result.Allowed {
+		t.Errorf("expected the fallback to deny the second request, got %+v, %v", result, err)
+	}
+}
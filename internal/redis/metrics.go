@@ -0,0 +1,18 @@
+package redis
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	cacheErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cache_errors_total",
+		Help: "Failed Redis cache operations by operation.",
+	}, []string{"operation"})
+
+	breakerState = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "cache_redis_breaker_state",
+		Help: "State of the Redis circuit breaker: 0 closed, 1 half-open, 2 open.",
+	})
+)
@@ -0,0 +1,72 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/mexirica/chi-template/internal/ratelimit"
+	"github.com/redis/go-redis/v9"
+)
+
+// rateLimitPrefix namespaces the theoretical arrival times of rate-limited clients.
+const rateLimitPrefix = "ratelimit:"
+
+// Limiter is a ratelimit.Limiter backed by Redis, sharing the client and circuit breaker of
+// a Store, so that the replicas limit clients together.
+type Limiter struct {
+	store *Store
+}
+
+var _ ratelimit.Limiter = (*Limiter)(nil)
+
+// NewLimiter returns a limiter using the Redis of store.
+func NewLimiter(store *Store) *Limiter {
+	return &Limiter{store: store}
+}
+
+// gcra runs ratelimit.GCRA on the TAT stored in KEYS[1], in milliseconds: ARGV holds the
+// current time, the interval and the period of the limit. It replies whether the request is
+// allowed, then the remaining requests or the retry delay, then the reset delay.
+var gcra = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local interval = tonumber(ARGV[2])
+local period = tonumber(ARGV[3])
+local tat = tonumber(redis.call('GET', KEYS[1])) or now
+if tat < now then
+	tat = now
+end
+local new_tat = tat + interval
+local allow_at = new_tat - period
+if now < allow_at then
+	return {0, allow_at - now, tat - now}
+end
+redis.call('SET', KEYS[1], new_tat, 'PX', new_tat - now)
+return {1, math.floor((period - (new_tat - now)) / interval), new_tat - now}
+`)
+
+// Allow runs GCRA in a script, so that concurrent requests of a client on several replicas
+// are counted exactly. The replicas' clocks are assumed to be in sync.
+func (l *Limiter) Allow(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	var reply []int64
+	err := l.store.execute(ctx, "ratelimit", func(ctx context.Context) error {
+		var err error
+		args := []any{time.Now().UnixMilli(), max(limit.Interval().Milliseconds(), 1), limit.Period.Milliseconds()}
+		reply, err = gcra.Run(ctx, l.store.client, []string{rateLimitPrefix + key}, args...).Int64Slice()
+		return err
+	})
+	if err != nil {
+		return ratelimit.Result{}, err
+	}
+
+	if reply[0] == 0 {
+		return ratelimit.Result{
+			RetryAfter: time.Duration(reply[1]) * time.Millisecond,
+			ResetAfter: time.Duration(reply[2]) * time.Millisecond,
+		}, nil
+	}
+	return ratelimit.Result{
+		Allowed:    true,
+		Remaining:  int(reply[1]),
+		ResetAfter: time.Duration(reply[2]) * time.Millisecond,
+	}, nil
+}
@@ -0,0 +1,48 @@
+package redis_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mexirica/chi-template/internal/ratelimit"
+	"github.com/mexirica/chi-template/internal/redis"
+)
+
+func TestLimiter(t *testing.T) {
+	store, mr := newStore(t)
+	limiter := redis.NewLimiter(store)
+	limit := ratelimit.Limit{Requests: 2, Period: time.Hour}
+	ctx := context.Background()
+
+	for want := 1; want >= 0; want-- {
+		result, err := limiter.Allow(ctx, "api:ip:192.0.2.1", limit)
+		if err != nil || !result.Allowed || result.Remaining != want {
+			t.Fatalf("Allow() = %+v, %v; want allowed with %d remaining", result, err, want)
+		}
+	}
+
+	result, err := limiter.Allow(ctx, "api:ip:192.0.2.1", limit)
+	if err != nil || result.Allowed {
+		t.Fatalf("Allow() = %+v, %v; want denied", result, err)
+	}
+	if result.RetryAfter <= 29*time.Minute || result.RetryAfter > 30*time.Minute {
+		t.Errorf("RetryAfter = %v, want about 30m", result.RetryAfter)
+	}
+	if ttl := mr.TTL("ratelimit:api:ip:192.0.2.1"); ttl <= 59*time.Minute || ttl > time.Hour {
+		t.Errorf("expected the TAT to expire once the limit is replenished, TTL = %v", ttl)
+	}
+
+	if result, _ := limiter.Allow(ctx, "api:ip:192.0.2.2", limit); !result.Allowed {
+		t.Error("expected other clients to be limited apart")
+	}
+}
+
+func TestLimiter_Unavailable(t *testing.T) {
+	store, mr := newStore(t)
+	mr.Close()
+
+	if _, err := redis.NewLimiter(store).Allow(context.Background(), "api:ip:192.0.2.1", ratelimit.Limit{Requests: 1, Period: time.Minute}); err == nil {
+		t.Error("expected an error while Redis is down, for the fallback to take over")
+	}
+}
@@ -1,82 +1,397 @@
-// Package redis handles Redis client connection establishment and session management.
+// Package redis handles Redis client connection establishment and provides the Redis
+// implementations of cache.Cache and session.Store.
+//
+// Every call made by the Store goes through a circuit breaker, so an unreachable Redis costs
+// one failed call rather than a timeout per request. A background loop probes Redis until it
+// is back, then replays the tag invalidations it missed.
 package redis
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
+	"github.com/mexirica/chi-template/internal/cache"
 	"github.com/redis/go-redis/v9"
 	"github.com/rs/zerolog/log"
+	"github.com/sony/gobreaker/v2"
 )
 
 var (
-	ctx         = context.Background()
-	redisClient *redis.Client
-	DefaultTTL  = (60 * 60 * 24) * time.Second
+	// OperationTimeout bounds every Redis command.
+	OperationTimeout = 500 * time.Millisecond
+	// BreakerFailures is the number of consecutive failures that opens the circuit breaker.
+	BreakerFailures uint32 = 5
+	// BreakerTimeout is how long the breaker stays open before a probe may close it again.
+	BreakerTimeout = 10 * time.Second
+	// ReconnectInterval is how often Redis is probed while the breaker is not closed.
+	ReconnectInterval = 2 * time.Second
 )
 
-// Initialize Redis Client Connection and return client
+// Initialize Redis Client Connection and return client. A failed ping is returned as an error
+// but the client stays usable: it reconnects by itself once Redis is reachable.
 func InitRedisClient(host, port string) (*redis.Client, error) {
 	// Connect to Redis
 	fmt.Printf("Connecting to Redis at %s:%s\n", host, port)
 	client := redis.NewClient(&redis.Options{
-		Addr:     fmt.Sprintf("%s:%s", host, port),
-		Password: "", // no password set
+		Addr:         fmt.Sprintf("%s:%s", host, port),
+		Password:     "", // no password set
+		DialTimeout:  OperationTimeout,
+		ReadTimeout:  OperationTimeout,
+		WriteTimeout: OperationTimeout,
 		// DB:       0,  // use default DB
 	})
 
+	ctx, cancel := context.WithTimeout(context.Background(), OperationTimeout)
+	defer cancel()
 	if err := client.Ping(ctx).Err(); err != nil {
-		log.Fatal().Err(err).Msg("Error connecting to Redis")
-
-		return nil, err
+		return client, err
 	}
 
 	log.Info().Msgf("Successfully connected to Redis instance: %v", client.String())
 
-	redisClient = client
 	return client, nil
 }
 
-// Set a Key, Value pair in Redis
-func SetCache(key string, value string, ttl time.Duration) error {
-	if ttl == 0 {
-		ttl = DefaultTTL
+// Store is a cache.Cache backed by Redis. Keys are indexed by tag in Redis sets.
+type Store struct {
+	client  *redis.Client
+	breaker *gobreaker.CircuitBreaker[any]
+	stop    chan struct{}
+
+	// pendingTags are the tags invalidated while Redis was unavailable, replayed on recovery.
+	pendingMu   sync.Mutex
+	pendingTags map[string]struct{}
+}
+
+var (
+	_ cache.Cache     = (*Store)(nil)
+	_ cache.TagReader = (*Store)(nil)
+	_ cache.Locker    = (*Store)(nil)
+)
+
+// NewStore returns a Redis cache using the client and starts its reconnection loop.
+// Close stops the loop and closes the client.
+func NewStore(client *redis.Client) *Store {
+	s := &Store{
+		client:      client,
+		stop:        make(chan struct{}),
+		pendingTags: map[string]struct{}{},
 	}
+	s.breaker = gobreaker.NewCircuitBreaker[any](gobreaker.Settings{
+		Name:        "redis",
+		MaxRequests: 1,
+		Timeout:     BreakerTimeout,
+		ReadyToTrip: func(counts gobreaker.Counts) bool {
+			return counts.ConsecutiveFailures >= BreakerFailures
+		},
+		IsSuccessful: func(err error) bool {
+			return err == nil || errors.Is(err, redis.Nil)
+		},
+		OnStateChange: s.onStateChange,
+	})
+	breakerState.Set(float64(gobreaker.StateClosed))
+	go s.reconnect(ReconnectInterval)
+	return s
+}
 
-	set, err := redisClient.SetNX(ctx, key, value, ttl).Result()
-	if err != nil {
-		log.Error().Err(err).Msg("Error setting key")
-		return err
+// Close stops the reconnection loop and closes the client.
+func (s *Store) Close() error {
+	close(s.stop)
+	return s.client.Close()
+}
+
+// execute runs a Redis command through the circuit breaker with OperationTimeout.
+// redis.Nil counts as a success.
+func (s *Store) execute(ctx context.Context, op string, fn func(ctx context.Context) error) error {
+	_, err := s.breaker.Execute(func() (any, error) {
+		ctx, cancel := context.WithTimeout(ctx, OperationTimeout)
+		defer cancel()
+		return nil, fn(ctx)
+	})
+	if err != nil && !errors.Is(err, redis.Nil) &&
+		!errors.Is(err, gobreaker.ErrOpenState) && !errors.Is(err, gobreaker.ErrTooManyRequests) {
+		cacheErrors.WithLabelValues(op).Inc()
+		log.Error().Err(err).Str("operation", op).Msg("Redis command failed")
 	}
+	return err
+}
 
-	log.Info().Msgf("set: %v", set)
+// onStateChange tracks the breaker state. It runs with the breaker locked, so recovery work
+// is handed to a goroutine.
+func (s *Store) onStateChange(_ string, from, to gobreaker.State) {
+	breakerState.Set(float64(to))
+	log.Warn().Str("from", from.String()).Str("to", to.String()).Msg("Redis circuit breaker changed state")
+	if to == gobreaker.StateClosed {
+		go s.replayInvalidations()
+	}
+}
 
-	return nil
+// replayInvalidations runs the invalidations Redis missed while it was unavailable.
+func (s *Store) replayInvalidations() {
+	s.pendingMu.Lock()
+	tags := make([]string, 0, len(s.pendingTags))
+	for tag := range s.pendingTags {
+		tags = append(tags, tag)
+	}
+	s.pendingTags = map[string]struct{}{}
+	s.pendingMu.Unlock()
+
+	if len(tags) > 0 {
+		_ = s.DeleteByTag(context.Background(), tags...)
+	}
 }
 
-// Get a Key, Value pair from Redis
-func GetCache(key string) (string, error) {
-	value, err := redisClient.Get(ctx, key).Result()
-	if err != nil {
-		log.Error().Err(err).Msg("Error getting key")
-		return "", nil
+// hasPendingInvalidations reports whether invalidations are waiting to be replayed.
+func (s *Store) hasPendingInvalidations() bool {
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+	return len(s.pendingTags) > 0
+}
+
+// reconnect probes Redis while the breaker is open or half-open, so that it closes as soon as
+// Redis is back rather than on the next user request. While the breaker is closed, it replays
+// the invalidations that failed without opening it.
+func (s *Store) reconnect(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+		if s.breaker.State() == gobreaker.StateClosed {
+			if s.hasPendingInvalidations() {
+				s.replayInvalidations()
+			}
+			continue
+		}
+		err := s.execute(context.Background(), "ping", func(ctx context.Context) error {
+			return s.client.Ping(ctx).Err()
+		})
+		if err == nil {
+			log.Info().Msg("Reconnected to Redis")
+		}
 	}
+}
+
+const (
+	// tagPrefix namespaces the sets that index cache keys by tag.
+	tagPrefix = "tag:"
+	// keyTagsPrefix namespaces the sets of the tags of each tagged key.
+	keyTagsPrefix = "keytags:"
+)
+
+// setWithTags stores KEYS[1], records its tags ARGV[3..] in the set KEYS[2] and adds it to the
+// matching tag sets KEYS[3..]. A tag set never expires before the keys it indexes.
+var setWithTags = redis.NewScript(`
+redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+redis.call('DEL', KEYS[2])
+for i = 3, #KEYS do
+	redis.call('SADD', KEYS[i], KEYS[1])
+	if redis.call('PTTL', KEYS[i]) < tonumber(ARGV[2]) then
+		redis.call('PEXPIRE', KEYS[i], ARGV[2])
+	end
+	redis.call('SADD', KEYS[2], ARGV[i])
+end
+if #KEYS > 2 then
+	redis.call('PEXPIRE', KEYS[2], ARGV[2])
+end
+return 1
+`)
+
+// getTagged replies the value of KEYS[1] and the members of its tag set KEYS[2], or nil.
+var getTagged = redis.NewScript(`
+local value = redis.call('GET', KEYS[1])
+if not value then
+	return false
+end
+return {value, redis.call('SMEMBERS', KEYS[2])}
+`)
 
-	// log.Info().Msgf("value: %v", value)
+// invalidateTags deletes every key indexed by the tag sets KEYS, with its own tag set named
+// with the prefix ARGV[1], and the tag sets themselves.
+var invalidateTags = redis.NewScript(`
+local deleted = 0
+for i = 1, #KEYS do
+	local members = redis.call('SMEMBERS', KEYS[i])
+	for _, key in ipairs(members) do
+		deleted = deleted + redis.call('DEL', key)
+		redis.call('DEL', ARGV[1] .. key)
+	end
+	redis.call('DEL', KEYS[i])
+end
+return deleted
+`)
 
+func (s *Store) Get(ctx context.Context, key string) ([]byte, error) {
+	var value []byte
+	err := s.execute(ctx, "get", func(ctx context.Context) error {
+		var err error
+		value, err = s.client.Get(ctx, key).Bytes()
+		return err
+	})
+	if errors.Is(err, redis.Nil) {
+		cache.RecordLookup("redis", false)
+		return nil, cache.ErrMiss
+	}
+	if err != nil {
+		return nil, err
+	}
+	cache.RecordLookup("redis", true)
 	return value, nil
 }
 
-// Delete a Key, Value pair from Redis
-func DeleteCache(key string) error {
-	deleted, err := redisClient.Del(ctx, key).Result()
+// GetTagged returns the value of key with the tags it was set with, in a single script call.
+func (s *Store) GetTagged(ctx context.Context, key string) ([]byte, []string, error) {
+	var reply []any
+	err := s.execute(ctx, "get", func(ctx context.Context) error {
+		var err error
+		reply, err = getTagged.Run(ctx, s.client, []string{key, keyTagsPrefix + key}).Slice()
+		return err
+	})
+	if errors.Is(err, redis.Nil) {
+		cache.RecordLookup("redis", false)
+		return nil, nil, cache.ErrMiss
+	}
 	if err != nil {
-		log.Error().Err(err).Msg("Error deleting key")
+		return nil, nil, err
+	}
+	cache.RecordLookup("redis", true)
+
+	value, _ := reply[0].(string)
+	members, _ := reply[1].([]any)
+	tags := make([]string, 0, len(members))
+	for _, member := range members {
+		if tag, ok := member.(string); ok {
+			tags = append(tags, tag)
+		}
+	}
+	return []byte(value), tags, nil
+}
+
+// Set stores the value and indexes the key under each tag in a single script call.
+func (s *Store) Set(ctx context.Context, key string, value []byte, ttl time.Duration, tags ...string) error {
+	if ttl == 0 {
+		ttl = cache.DefaultTTL
+	}
+
+	keys := make([]string, 0, len(tags)+2)
+	keys = append(keys, key, keyTagsPrefix+key)
+	args := make([]any, 0, len(tags)+2)
+	args = append(args, value, ttl.Milliseconds())
+	for _, tag := range tags {
+		keys = append(keys, tagPrefix+tag)
+		args = append(args, tag)
+	}
+
+	return s.execute(ctx, "set", func(ctx context.Context) error {
+		return setWithTags.Run(ctx, s.client, keys, args...).Err()
+	})
+}
+
+func (s *Store) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	all := make([]string, 0, 2*len(keys))
+	for _, key := range keys {
+		all = append(all, key, keyTagsPrefix+key)
+	}
+	return s.execute(ctx, "delete", func(ctx context.Context) error {
+		return s.client.Del(ctx, all...).Err()
+	})
+}
+
+// DeleteByTag deletes every key stored with any of the tags. Invalidations that cannot
+// reach Redis are queued and replayed once it is back; their error is still returned, as the
+// entries stay stale until then.
+func (s *Store) DeleteByTag(ctx context.Context, tags ...string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		keys = append(keys, tagPrefix+tag)
+	}
+
+	var deleted int64
+	err := s.execute(ctx, "invalidate", func(ctx context.Context) error {
+		var err error
+		deleted, err = invalidateTags.Run(ctx, s.client, keys, keyTagsPrefix).Int64()
+		return err
+	})
+	if err != nil {
+		s.pendingMu.Lock()
+		for _, tag := range tags {
+			s.pendingTags[tag] = struct{}{}
+		}
+		s.pendingMu.Unlock()
+		log.Warn().Err(err).Strs("tags", tags).Msg("Redis unavailable; invalidation queued")
 		return err
 	}
 
-	log.Info().Msgf("deleted: %v", deleted)
+	log.Info().Msgf("invalidated %v: %d keys", tags, deleted)
 
 	return nil
 }
+
+// TTL returns the remaining lifetime of key; zero means it does not expire.
+func (s *Store) TTL(ctx context.Context, key string) (time.Duration, error) {
+	var ttl time.Duration
+	err := s.execute(ctx, "ttl", func(ctx context.Context) error {
+		var err error
+		ttl, err = s.client.PTTL(ctx, key).Result()
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	// PTTL reports -2 for a missing key and -1 for a key without expiry.
+	switch ttl {
+	case -2:
+		return 0, cache.ErrMiss
+	case -1:
+		return 0, nil
+	}
+	return ttl, nil
+}
+
+// releaseLock deletes the lock KEYS[1] if it still holds the token ARGV[1], so that a holder
+// whose lock expired cannot release the lock of the next holder.
+var releaseLock = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// Lock takes the lock with SET NX under a random token.
+func (s *Store) Lock(ctx context.Context, key string, ttl time.Duration) (func(), bool, error) {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	token := hex.EncodeToString(b)
+
+	var acquired bool
+	err := s.execute(ctx, "lock", func(ctx context.Context) error {
+		var err error
+		acquired, err = s.client.SetNX(ctx, key, token, ttl).Result()
+		return err
+	})
+	if err != nil || !acquired {
+		return func() {}, false, err
+	}
+
+	unlock := func() {
+		_ = s.execute(context.Background(), "unlock", func(ctx context.Context) error {
+			return releaseLock.Run(ctx, s.client, []string{key}, token).Err()
+		})
+	}
+	return unlock, true, nil
+}
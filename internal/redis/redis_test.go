@@ -0,0 +1,212 @@
+package redis_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/mexirica/chi-template/internal/cache"
+	"github.com/mexirica/chi-template/internal/redis"
+)
+
+func newStore(t *testing.T) (*redis.Store, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client, err := redis.InitRedisClient(mr.Host(), mr.Port())
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := redis.NewStore(client)
+	t.Cleanup(func() { store.Close() })
+	return store, mr
+}
+
+func TestStore_GetSetTTL(t *testing.T) {
+	store, _ := newStore(t)
+	ctx := context.Background()
+
+	if _, err := store.Get(ctx, "missing"); !errors.Is(err, cache.ErrMiss) {
+		t.Errorf("expected ErrMiss, got %v", err)
+	}
+	if err := store.Set(ctx, "key", []byte("value"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := store.Get(ctx, "key"); err != nil || string(got) != "value" {
+		t.Errorf("Get = %q, %v", got, err)
+	}
+	if ttl, err := store.TTL(ctx, "key"); err != nil || ttl <= 0 || ttl > time.Minute {
+		t.Errorf("TTL = %v, %v", ttl, err)
+	}
+	if _, err := store.TTL(ctx, "missing"); !errors.Is(err, cache.ErrMiss) {
+		t.Errorf("expected ErrMiss, got %v", err)
+	}
+}
+
+func TestStore_DeleteByTag(t *testing.T) {
+	store, mr := newStore(t)
+	ctx := context.Background()
+
+	entries := map[string][]string{
+		"GET./movies/1.":          {"movie:1"},
+		"GET./movies/2.":          {"movie:2"},
+		"GET./movies/list.page=1": {"movies:list"},
+		"movies:suggest:5:ali":    {"movies:list"},
+	}
+	for key, tags := range entries {
+		if err := store.Set(ctx, key, []byte("{}"), time.Hour, tags...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := store.DeleteByTag(ctx, "movie:1", "movies:list"); err != nil {
+		t.Fatal(err)
+	}
+
+	for key, tags := range entries {
+		want := tags[0] == "movie:2"
+		if got := mr.Exists(key); got != want {
+			t.Errorf("%s exists = %v, want %v", key, got, want)
+		}
+	}
+	if mr.Exists("tag:movies:list") {
+		t.Error("expected the invalidated tag set to be deleted")
+	}
+}
+
+func TestStore_GetTagged(t *testing.T) {
+	store, mr := newStore(t)
+	ctx := context.Background()
+
+	if err := store.Set(ctx, "GET./movies/1.", []byte("{}"), time.Hour, "movie:1", "movies:list"); err != nil {
+		t.Fatal(err)
+	}
+	value, tags, err := store.GetTagged(ctx, "GET./movies/1.")
+	slices.Sort(tags)
+	if err != nil || string(value) != "{}" || !slices.Equal(tags, []string{"movie:1", "movies:list"}) {
+		t.Errorf("GetTagged = %q, %v, %v", value, tags, err)
+	}
+
+	// Setting the key again replaces its tags.
+	if err := store.Set(ctx, "GET./movies/1.", []byte("{}"), time.Hour, "movie:1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, tags, _ := store.GetTagged(ctx, "GET./movies/1."); !slices.Equal(tags, []string{"movie:1"}) {
+		t.Errorf("expected the tags of the last Set, got %v", tags)
+	}
+
+	if err := store.DeleteByTag(ctx, "movie:1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := store.GetTagged(ctx, "GET./movies/1."); !errors.Is(err, cache.ErrMiss) {
+		t.Errorf("expected ErrMiss, got %v", err)
+	}
+	if mr.Exists("keytags:GET./movies/1.") {
+		t.Error("expected the tags of the invalidated key to be deleted")
+	}
+}
+
+func TestStore_TagOutlivesKeys(t *testing.T) {
+	store, mr := newStore(t)
+	ctx := context.Background()
+
+	if err := store.Set(ctx, "long", []byte("{}"), time.Hour, "movies:list"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set(ctx, "short", []byte("{}"), time.Minute, "movies:list"); err != nil {
+		t.Fatal(err)
+	}
+
+	if ttl := mr.TTL("tag:movies:list"); ttl < time.Hour {
+		t.Errorf("tag TTL = %v, want at least %v", ttl, time.Hour)
+	}
+}
+
+func TestStore_Recovery(t *testing.T) {
+	redis.BreakerFailures = 2
+	redis.BreakerTimeout = 20 * time.Millisecond
+	redis.ReconnectInterval = 5 * time.Millisecond
+	store, mr := newStore(t)
+	ctx := context.Background()
+
+	if err := store.Set(ctx, "GET./movies/1.", []byte("stale"), time.Hour, "movie:1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Redis goes down: the breaker opens and invalidations are queued.
+	mr.SetError("LOADING")
+	for range 2 {
+		if _, err := store.Get(ctx, "anything"); err == nil {
+			t.Fatal("expected an error while Redis is down")
+		}
+	}
+	if err := store.DeleteByTag(ctx, "movie:1"); err == nil {
+		t.Fatal("expected the queued invalidation to report the error")
+	}
+
+	// Redis comes back: the reconnection loop closes the breaker and replays the invalidation.
+	mr.SetError("")
+	deadline := time.Now().Add(2 * time.Second)
+	for mr.Exists("GET./movies/1.") {
+		if time.Now().After(deadline) {
+			t.Fatal("queued invalidation was not replayed after Redis recovered")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestStore_ReplaysInvalidationWithoutOpeningBreaker(t *testing.T) {
+	redis.BreakerFailures = 5
+	redis.ReconnectInterval = 5 * time.Millisecond
+	store, mr := newStore(t)
+	ctx := context.Background()
+
+	if err := store.Set(ctx, "GET./movies/1.", []byte("stale"), time.Hour, "movie:1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A single failure leaves the breaker closed.
+	mr.SetError("LOADING")
+	if err := store.DeleteByTag(ctx, "movie:1"); err == nil {
+		t.Fatal("expected the failed invalidation to report the error")
+	}
+	mr.SetError("")
+
+	deadline := time.Now().Add(2 * time.Second)
+	for mr.Exists("GET./movies/1.") {
+		if time.Now().After(deadline) {
+			t.Fatal("queued invalidation was not replayed while the breaker stayed closed")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestStore_Lock(t *testing.T) {
+	store, mr := newStore(t)
+	ctx := context.Background()
+
+	unlock, ok, err := store.Lock(ctx, "lock:key", time.Second)
+	if err != nil || !ok {
+		t.Fatalf("expected to take the lock, got %v, %v", ok, err)
+	}
+	if _, ok, _ := store.Lock(ctx, "lock:key", time.Second); ok {
+		t.Fatal("expected the lock to be held")
+	}
+
+	// A holder whose lock expired must not release the next holder's lock.
+	mr.FastForward(2 * time.Second)
+	next, ok, _ := store.Lock(ctx, "lock:key", time.Second)
+	if !ok {
+		t.Fatal("expected the expired lock to be taken again")
+	}
+	unlock()
+	if !mr.Exists("lock:key") {
+		t.Error("expected the stale unlock to leave the new lock in place")
+	}
+	next()
+	if mr.Exists("lock:key") {
+		t.Error("expected the lock to be released")
+	}
+}
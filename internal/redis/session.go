@@ -0,0 +1,193 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/mexirica/chi-template/internal/errs"
+	"github.com/mexirica/chi-template/internal/session"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// sessionPrefix namespaces the hashes holding each session's user, creation time and
+	// current token.
+	sessionPrefix = "session:"
+	// refreshPrefix namespaces the keys mapping every token of a session, replaced ones
+	// included, to the session.
+	refreshPrefix = "refresh:"
+	// failuresPrefix namespaces the failed login counters.
+	failuresPrefix = "login-failures:"
+)
+
+// SessionStore is a session.Store backed by Redis, sharing the client and circuit breaker of
+// a Store. Its commands fail with an errs.ErrUnavailable error while Redis is unreachable.
+type SessionStore struct {
+	store *Store
+}
+
+var _ session.Store = (*SessionStore)(nil)
+
+// NewSessionStore returns a session store using the Redis of store.
+func NewSessionStore(store *Store) *SessionStore {
+	return &SessionStore{store: store}
+}
+
+// rotateToken replaces the current token of the session of KEYS[1] with the token of KEYS[2]
+// if it is ARGV[1], and ends the session otherwise. The session key is derived from the value
+// of KEYS[1], which is fine on a single Redis but not on a cluster.
+var rotateToken = redis.NewScript(`
+local id = redis.call('GET', KEYS[1])
+if not id then
+	return {0}
+end
+local key = ARGV[3] .. id
+local current = redis.call('HGET', key, 'token')
+if not current then
+	return {0}
+end
+if current ~= ARGV[1] then
+	redis.call('DEL', key)
+	return {-1}
+end
+redis.call('HSET', key, 'token', ARGV[2])
+redis.call('PEXPIRE', key, ARGV[4])
+redis.call('SET', KEYS[2], id, 'PX', ARGV[4])
+return {1, id, redis.call('HGET', key, 'user_id'), redis.call('HGET', key, 'created_at')}
+`)
+
+// deleteSession ends the session of the token KEYS[1].
+var deleteSession = redis.NewScript(`
+local id = redis.call('GET', KEYS[1])
+if id then
+	redis.call('DEL', ARGV[1] .. id)
+end
+return redis.call('DEL', KEYS[1])
+`)
+
+// recordFailure counts a failure under KEYS[1], forgotten ARGV[1] milliseconds after the
+// first one.
+var recordFailure = redis.NewScript(`
+local n = redis.call('INCR', KEYS[1])
+if n == 1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return n
+`)
+
+func (s *SessionStore) Create(ctx context.Context, sess session.Session, token string, ttl time.Duration) error {
+	err := s.store.execute(ctx, "session_create", func(ctx context.Context) error {
+		_, err := s.store.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			key := sessionPrefix + sess.ID
+			pipe.HSet(ctx, key, "user_id", sess.UserID, "created_at", sess.CreatedAt.UnixMilli(), "token", token)
+			pipe.PExpire(ctx, key, ttl)
+			pipe.Set(ctx, refreshPrefix+token, sess.ID, ttl)
+			return nil
+		})
+		return err
+	})
+	return unavailable(err)
+}
+
+func (s *SessionStore) Rotate(ctx context.Context, token, next string, ttl time.Duration) (*session.Session, error) {
+	var reply []any
+	err := s.store.execute(ctx, "session_rotate", func(ctx context.Context) error {
+		var err error
+		keys := []string{refreshPrefix + token, refreshPrefix + next}
+		reply, err = rotateToken.Run(ctx, s.store.client, keys, token, next, sessionPrefix, ttl.Milliseconds()).Slice()
+		return err
+	})
+	if err != nil {
+		return nil, unavailable(err)
+	}
+
+	switch status, _ := reply[0].(int64); status {
+	case 0:
+		return nil, session.ErrNotFound
+	case -1:
+		return nil, session.ErrReused
+	}
+	if len(reply) != 4 {
+		return nil, errors.New("malformed session")
+	}
+	id, _ := reply[1].(string)
+	userID, err := strconv.ParseInt(stringOf(reply[2]), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	createdAt, err := strconv.ParseInt(stringOf(reply[3]), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &session.Session{ID: id, UserID: userID, CreatedAt: time.UnixMilli(createdAt)}, nil
+}
+
+func (s *SessionStore) Delete(ctx context.Context, token string) error {
+	err := s.store.execute(ctx, "session_delete", func(ctx context.Context) error {
+		return deleteSession.Run(ctx, s.store.client, []string{refreshPrefix + token}, sessionPrefix).Err()
+	})
+	return unavailable(err)
+}
+
+func (s *SessionStore) Fail(ctx context.Context, key string, window time.Duration) (int64, error) {
+	var n int64
+	err := s.store.execute(ctx, "login_fail", func(ctx context.Context) error {
+		var err error
+		n, err = recordFailure.Run(ctx, s.store.client, []string{failuresPrefix + key}, window.Milliseconds()).Int64()
+		return err
+	})
+	return n, unavailable(err)
+}
+
+func (s *SessionStore) Failures(ctx context.Context, key string) (int64, time.Duration, error) {
+	var count *redis.StringCmd
+	var ttl *redis.DurationCmd
+	err := s.store.execute(ctx, "login_failures", func(ctx context.Context) error {
+		_, err := s.store.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			count = pipe.Get(ctx, failuresPrefix+key)
+			ttl = pipe.PTTL(ctx, failuresPrefix+key)
+			return nil
+		})
+		return err
+	})
+	if errors.Is(err, redis.Nil) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, unavailable(err)
+	}
+
+	n, err := count.Int64()
+	if err != nil {
+		return 0, 0, err
+	}
+	return n, max(ttl.Val(), 0), nil
+}
+
+func (s *SessionStore) Reset(ctx context.Context, key string) error {
+	err := s.store.execute(ctx, "login_reset", func(ctx context.Context) error {
+		return s.store.client.Del(ctx, failuresPrefix+key).Err()
+	})
+	return unavailable(err)
+}
+
+// unavailable reports a failed command as the session store being unavailable.
+func unavailable(err error) error {
+	if err == nil {
+		return nil
+	}
+	return errs.Unavailable(err, "session store unavailable")
+}
+
+// stringOf returns the string of a script reply, which Redis sends as a string or an integer.
+func stringOf(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	}
+	return ""
+}
@@ -0,0 +1,104 @@
+package redis_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mexirica/chi-template/internal/errs"
+	"github.com/mexirica/chi-template/internal/redis"
+	"github.com/mexirica/chi-template/internal/session"
+)
+
+func TestSessionStore_Rotate(t *testing.T) {
+	store, _ := newStore(t)
+	sessions := redis.NewSessionStore(store)
+	ctx := context.Background()
+
+	created := session.Session{ID: "s1", UserID: 42, CreatedAt: time.UnixMilli(1_700_000_000_000)}
+	if err := sessions.Create(ctx, created, "t1", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := sessions.Rotate(ctx, "t1", "t2", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *got != created {
+		t.Errorf("Rotate() = %+v, want %+v", got, created)
+	}
+	if _, err := sessions.Rotate(ctx, "t2", "t3", time.Hour); err != nil {
+		t.Fatalf("expected the rotated token to work, got %v", err)
+	}
+
+	if _, err := sessions.Rotate(ctx, "t1", "t4", time.Hour); !errors.Is(err, session.ErrReused) {
+		t.Fatalf("expected ErrReused for a replaced token, got %v", err)
+	}
+	if _, err := sessions.Rotate(ctx, "t3", "t5", time.Hour); !errors.Is(err, session.ErrNotFound) {
+		t.Errorf("expected the reuse to end the session, got %v", err)
+	}
+	if _, err := sessions.Rotate(ctx, "unknown", "t6", time.Hour); !errors.Is(err, session.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for an unknown token, got %v", err)
+	}
+}
+
+func TestSessionStore_Delete(t *testing.T) {
+	store, mr := newStore(t)
+	sessions := redis.NewSessionStore(store)
+	ctx := context.Background()
+
+	if err := sessions.Create(ctx, session.Session{ID: "s1", UserID: 42, CreatedAt: time.Now()}, "t1", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := sessions.Delete(ctx, "t1"); err != nil {
+		t.Fatal(err)
+	}
+	if mr.Exists("session:s1") {
+		t.Error("expected the session to be deleted")
+	}
+	if _, err := sessions.Rotate(ctx, "t1", "t2", time.Hour); !errors.Is(err, session.ErrNotFound) {
+		t.Errorf("expected ErrNotFound after Delete, got %v", err)
+	}
+}
+
+func TestSessionStore_Failures(t *testing.T) {
+	store, mr := newStore(t)
+	sessions := redis.NewSessionStore(store)
+	ctx := context.Background()
+
+	if n, ttl, err := sessions.Failures(ctx, "ada@example.com"); err != nil || n != 0 || ttl != 0 {
+		t.Errorf("Failures() = %d, %v, %v; want none", n, ttl, err)
+	}
+	for want := int64(1); want <= 3; want++ {
+		if n, err := sessions.Fail(ctx, "ada@example.com", time.Minute); err != nil || n != want {
+			t.Fatalf("Fail() = %d, %v; want %d", n, err, want)
+		}
+	}
+	if n, ttl, err := sessions.Failures(ctx, "ada@example.com"); err != nil || n != 3 || ttl <= 0 || ttl > time.Minute {
+		t.Errorf("Failures() = %d, %v, %v; want 3 within a minute", n, ttl, err)
+	}
+
+	mr.FastForward(time.Minute)
+	if n, _, err := sessions.Failures(ctx, "ada@example.com"); err != nil || n != 0 {
+		t.Errorf("expected the failures to be forgotten after the window, got %d, %v", n, err)
+	}
+
+	_, _ = sessions.Fail(ctx, "ada@example.com", time.Minute)
+	if err := sessions.Reset(ctx, "ada@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if n, _, _ := sessions.Failures(ctx, "ada@example.com"); n != 0 {
+		t.Errorf("expected Reset to forget the failures, got %d", n)
+	}
+}
+
+func TestSessionStore_Unavailable(t *testing.T) {
+	store, mr := newStore(t)
+	sessions := redis.NewSessionStore(store)
+	mr.Close()
+
+	if _, _, err := sessions.Failures(context.Background(), "ada@example.com"); !errors.Is(err, errs.ErrUnavailable) {
+		t.Errorf("expected ErrUnavailable, got %v", err)
+	}
+}
@@ -0,0 +1,41 @@
+package server
+
+import (
+	"github.com/mexirica/chi-template/internal/auth"
+	"github.com/mexirica/chi-template/internal/configs"
+	"github.com/rs/zerolog/log"
+)
+
+// newVerifier returns the verifier of bearer tokens. JWKS_URL takes precedence over JWKS_FILE.
+// Without any key configured every token is rejected, so the routes requiring a permission
+// stay closed rather than open. With keys, JWT_ISSUER is required: tokens signed by the same
+// keys for another issuer must not be accepted.
+func newVerifier(cfg *configs.Config) *auth.Verifier {
+	opts := []auth.Option{
+		auth.WithAudience(cfg.JWT_AUDIENCE),
+		auth.WithHMACSecret([]byte(cfg.JWT_HS256_SECRET)),
+	}
+	switch {
+	case cfg.JWKS_URL != "":
+		opts = append(opts, auth.WithKeySet(auth.NewRemoteKeySet(cfg.JWKS_URL, cfg.JWKS_REFRESH_INTERVAL)))
+	case cfg.JWKS_FILE != "":
+		opts = append(opts, auth.WithKeySet(auth.NewFileKeySet(cfg.JWKS_FILE, cfg.JWKS_REFRESH_INTERVAL)))
+	case cfg.JWT_HS256_SECRET == "":
+		log.Warn().Msg("No JWKS_URL, JWKS_FILE or JWT_HS256_SECRET configured; only callers identified by AUTH_TRUSTED_HEADER can be authorized")
+	}
+	if cfg.AUTH_TRUSTED_HEADER != "" && len(cfg.AUTH_GATEWAY_ADDRS) == 0 {
+		log.Warn().Msg("AUTH_TRUSTED_HEADER is set without AUTH_GATEWAY_ADDRS; the header is ignored")
+	}
+	if cfg.JWT_ISSUER == "" && (cfg.JWKS_URL != "" || cfg.JWKS_FILE != "" || cfg.JWT_HS256_SECRET != "") {
+		log.Fatal().Msg("JWT_ISSUER is required when verification keys are configured")
+	}
+	return auth.NewVerifier(cfg.JWT_ISSUER, opts...)
+}
+
+// newSigner returns the signer of the access tokens of users, which newVerifier accepts.
+func newSigner(cfg *configs.Config) *auth.Signer {
+	if cfg.JWT_HS256_SECRET == "" {
+		log.Warn().Msg("JWT_HS256_SECRET is not configured; users cannot log in")
+	}
+	return auth.NewSigner(cfg.JWT_ISSUER, cfg.JWT_AUDIENCE, []byte(cfg.JWT_HS256_SECRET), cfg.ACCESS_TOKEN_TTL)
+}
@@ -0,0 +1,35 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// purgeInterval is how often the trash is purged of the movies past their retention.
+const purgeInterval = time.Hour
+
+// purgeTrash permanently deletes the movies that have been in the trash for longer than
+// TRASH_RETENTION, once at startup and then every purgeInterval, until ctx is cancelled.
+// Every replica runs it; purges are idempotent.
+func (app *App) purgeTrash(ctx context.Context) {
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := app.movies.Purge(ctx, app.cfg.TRASH_RETENTION)
+		switch {
+		case err != nil && ctx.Err() == nil:
+			log.Error().Err(err).Msg("Error purging deleted movies")
+		case purged > 0:
+			log.Info().Int64("purged", purged).Msg("Purged deleted movies")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
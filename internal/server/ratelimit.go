@@ -0,0 +1,58 @@
+package server
+
+import (
+	"net/netip"
+	"strings"
+
+	"github.com/mexirica/chi-template/internal/ratelimit"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// defaultRateLimit limits the API routes of each client when RATE_LIMIT is not set.
+	defaultRateLimit = "600/1m"
+	// defaultAuthRateLimit limits the /auth routes of each client when RATE_LIMIT_AUTH is
+	// not set. It is low, as these routes are where passwords are guessed.
+	defaultAuthRateLimit = "20/1m"
+	// defaultIPRateLimit limits the API routes of each IP address, before credentials are
+	// checked, when RATE_LIMIT_IP is not set. It leaves room for several clients behind one
+	// address.
+	defaultIPRateLimit = "1200/1m"
+)
+
+// parseRateLimit parses the rate limit configured in the variable name, or fallback when it
+// is not set. An invalid limit stops the server rather than leaving the routes unprotected.
+func parseRateLimit(name, value, fallback string) ratelimit.Limit {
+	if value == "" {
+		value = fallback
+	}
+	limit, err := ratelimit.ParseLimit(value)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("Invalid %s", name)
+	}
+	if !limit.Enabled() {
+		log.Warn().Msgf("%s is off; the routes it covers are not rate limited", name)
+	}
+	return limit
+}
+
+// parsePrefixes parses the variable name, a list of IP addresses and CIDR prefixes.
+func parsePrefixes(name string, values []string) []netip.Prefix {
+	proxies := make([]netip.Prefix, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(value)
+			if addrErr != nil {
+				log.Fatal().Err(err).Msgf("Invalid %s", name)
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		proxies = append(proxies, prefix.Masked())
+	}
+	return proxies
+}
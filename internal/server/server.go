@@ -6,16 +6,23 @@ import (
 	"fmt"
 
 	"net/http"
+	"net/netip"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mexirica/chi-template/internal/auth"
+	"github.com/mexirica/chi-template/internal/authz"
+	"github.com/mexirica/chi-template/internal/cache"
 	"github.com/mexirica/chi-template/internal/configs"
 	"github.com/mexirica/chi-template/internal/db/repository"
 	"github.com/mexirica/chi-template/internal/handler"
 	"github.com/mexirica/chi-template/internal/helpers"
 	"github.com/mexirica/chi-template/internal/middleware"
+	"github.com/mexirica/chi-template/internal/models"
+	"github.com/mexirica/chi-template/internal/o11y"
+	"github.com/mexirica/chi-template/internal/ratelimit"
 	"github.com/mexirica/chi-template/internal/service"
-	"github.com/redis/go-redis/v9"
+	"github.com/mexirica/chi-template/internal/session"
 	"github.com/rs/zerolog/log"
 
 	"github.com/go-chi/chi/v5"
@@ -32,25 +39,80 @@ import (
 	httpSwagger "github.com/swaggo/http-swagger/v2"
 )
 
+// idempotencyTTL is how long the response to a request sent with an Idempotency-Key is kept
+// for its retries.
+const idempotencyTTL = 24 * time.Hour
+
 type App struct {
 	cfg         *configs.Config
-	redis       *redis.Client
+	cache       cache.Cache
 	db          *pgxpool.Pool
 	srv         *http.Server
+	movies      service.Service
 	userHandler *handler.MovieHandler
+	apiKeys     service.APIKeys
+	keyHandler  *handler.APIKeyHandler
+	authHandler *handler.AuthHandler
+	verifier    *auth.Verifier
+	gateways    []netip.Prefix
+	authorizer  *authz.Authorizer
+	limiter     ratelimit.Limiter
+	apiLimit    ratelimit.Limit
+	authLimit   ratelimit.Limit
+	ipLimit     ratelimit.Limit
+	clientKey   func(*http.Request) string
+	ipKey       func(*http.Request) string
+
+	// jobs is the context of the background jobs, cancelled by Shutdown.
+	jobs     context.Context
+	stopJobs context.CancelFunc
 }
 
-func New(cfg *configs.Config, redis *redis.Client, db *pgxpool.Pool) *App {
-	userRepo := repository.NewMovieRepository(db)
-	userService := service.NewMovieService(userRepo)
-	userHandler := handler.NewMovieHandler(userService)
+func New(cfg *configs.Config, appCache cache.Cache, sessions session.Store, limiter ratelimit.Limiter, db *pgxpool.Pool) *App {
+	userRepo := repository.NewMovieRepository(db,
+		repository.WithCountEstimate(cfg.COUNT_ESTIMATE_THRESHOLD),
+		repository.WithSearchLanguage(cfg.SEARCH_LANGUAGE),
+	)
+	if err := userRepo.CheckSearchLanguage(context.Background()); err != nil {
+		log.Fatal().Err(err).Msg("Invalid SEARCH_LANGUAGE")
+	}
+	userService := service.NewMovieService(userRepo, appCache)
+	userHandler := handler.NewMovieHandler(userService,
+		handler.WithRequireIfMatch(cfg.REQUIRE_IF_MATCH),
+	)
+
+	scopes := make([]string, 0, len(authz.KeyScopes))
+	for _, perm := range authz.KeyScopes {
+		scopes = append(scopes, string(perm))
+	}
+	proxies := parsePrefixes("TRUSTED_PROXIES", cfg.TRUSTED_PROXIES)
+	authorizer := authz.NewAuthorizer(authz.DefaultPolicy, repository.NewRoleBindingRepository(db))
+	apiKeys := service.NewAPIKeyService(repository.NewAPIKeyRepository(db), scopes, authorizer)
+	accounts := service.NewAccountService(repository.NewUserRepository(db), sessions, newSigner(cfg),
+		service.WithRefreshTTL(cfg.REFRESH_TOKEN_TTL),
+		service.WithLockout(cfg.LOGIN_MAX_FAILURES, cfg.LOGIN_LOCKOUT),
+	)
 
 	app := &App{
 		cfg:         cfg,
-		redis:       redis,
+		cache:       appCache,
 		db:          db,
+		movies:      userService,
 		userHandler: userHandler,
+		apiKeys:     apiKeys,
+		keyHandler:  handler.NewAPIKeyHandler(apiKeys),
+		authHandler: handler.NewAuthHandler(accounts, proxies),
+		verifier:    newVerifier(cfg),
+		gateways:    parsePrefixes("AUTH_GATEWAY_ADDRS", cfg.AUTH_GATEWAY_ADDRS),
+		authorizer:  authorizer,
+		limiter:     limiter,
+		apiLimit:    parseRateLimit("RATE_LIMIT", cfg.RATE_LIMIT, defaultRateLimit),
+		authLimit:   parseRateLimit("RATE_LIMIT_AUTH", cfg.RATE_LIMIT_AUTH, defaultAuthRateLimit),
+		ipLimit:     parseRateLimit("RATE_LIMIT_IP", cfg.RATE_LIMIT_IP, defaultIPRateLimit),
+		clientKey:   middleware.ClientKey(proxies),
+		ipKey:       middleware.IPKey(proxies),
 	}
+	app.jobs, app.stopJobs = context.WithCancel(context.Background())
 
 	app.srv = &http.Server{
 		Addr:         fmt.Sprintf(":%s", cfg.PORT),
@@ -64,6 +126,10 @@ func New(cfg *configs.Config, redis *redis.Client, db *pgxpool.Pool) *App {
 }
 
 func (app *App) Serve() {
+	if app.cfg.TRASH_RETENTION > 0 {
+		go app.purgeTrash(app.jobs)
+	}
+
 	log.Info().Msgf("Starting server on port %s", app.cfg.PORT)
 	if err := app.srv.ListenAndServe(); err != http.ErrServerClosed {
 		log.Fatal().Msgf("Erro inesperado no servidor: %v", err)
@@ -71,6 +137,7 @@ func (app *App) Serve() {
 }
 
 func (app *App) Shutdown(ctx context.Context) error {
+	app.stopJobs()
 	return app.srv.Shutdown(ctx)
 }
 
@@ -79,6 +146,7 @@ func (app *App) routes() http.Handler {
 
 	r.Use(chimiddleware.Recoverer)
 	r.Use(chimiddleware.Logger)
+	r.Use(o11y.Middleware)
 	r.Use(std.HandlerProvider("", stdmiddleware.New(stdmiddleware.Config{
 		Recorder: metrics.NewRecorder(metrics.Config{}),
 	})))
@@ -86,12 +154,19 @@ func (app *App) routes() http.Handler {
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"http://*", "https://*"},
 		AllowedMethods:   []string{"GET", "POST", "PATCH", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-API-Key", "X-CSRF-Token"},
+		ExposedHeaders:   []string{"Link", "X-Total-Count", "WWW-Authenticate", "Retry-After", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
 
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		helpers.WriteProblem(w, helpers.NewProblem(r, http.StatusNotFound, "No route matches the requested path."))
+	})
+	r.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		helpers.WriteProblem(w, helpers.NewProblem(r, http.StatusMethodNotAllowed, "The method is not allowed for the requested path."))
+	})
+
 	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
 		helpers.WriteJSON(w, http.StatusOK, "API is up and running")
 	})
@@ -102,11 +177,43 @@ func (app *App) routes() http.Handler {
 		httpSwagger.URL(fmt.Sprintf("http://localhost:%s/swagger/doc.json", app.cfg.PORT))),
 	)
 
-	r.Route("/movies", func(r chi.Router) {
-		r.Post("/", app.userHandler.Create)
-		r.Delete("/{id}", app.userHandler.Delete)
-		r.With(middleware.CacheMiddleware(time.Hour*24)).Get("/{id}", app.userHandler.GetById)
-		r.With(middleware.CacheMiddleware(time.Hour*24)).Get("/list", app.userHandler.GetList)
+	r.Route("/auth", func(r chi.Router) {
+		r.Use(middleware.RateLimit(app.limiter, "auth", app.authLimit, app.clientKey))
+		r.Post("/register", app.authHandler.Register)
+		r.Post("/login", app.authHandler.Login)
+		r.Post("/refresh", app.authHandler.Refresh)
+		r.Post("/logout", app.authHandler.Logout)
+	})
+
+	r.Group(func(r chi.Router) {
+		// Credentials are checked per IP address first, so that guessing them is limited too.
+		r.Use(middleware.RateLimit(app.limiter, "ip", app.ipLimit, app.ipKey))
+		r.Use(auth.Authenticate(app.verifier, app.cfg.AUTH_TRUSTED_HEADER, app.gateways))
+		r.Use(middleware.APIKey(app.apiKeys))
+		r.Use(app.authorizer.Middleware)
+		r.Use(middleware.RateLimit(app.limiter, "api", app.apiLimit, app.clientKey))
+
+		r.Route("/movies", func(r chi.Router) {
+			r.With(authz.Require(authz.MoviesCreate), middleware.Idempotency(app.cache, idempotencyTTL)).Post("/", app.userHandler.Create)
+			r.With(authz.Require(authz.MoviesCreate)).Post("/import", app.userHandler.Import)
+			r.With(authz.Require(authz.MoviesUpdate)).Put("/{id}", app.userHandler.Update)
+			r.With(authz.Require(authz.MoviesUpdate)).Patch("/{id}", app.userHandler.Patch)
+			r.With(authz.Require(authz.MoviesDelete)).Delete("/{id}", app.userHandler.Delete)
+			r.With(authz.Require(authz.MoviesRestore)).Post("/{id}/restore", app.userHandler.Restore)
+			r.With(authz.Require(authz.MoviesRestore)).Get("/trash", app.userHandler.Trash)
+			r.With(middleware.CacheMiddleware(app.cache, time.Hour*24, app.cfg.CACHE_STALE_WHILE_REVALIDATE, handler.MovieCacheTags)).Get("/{id}", app.userHandler.GetById)
+			r.With(middleware.CacheMiddleware(app.cache, time.Hour*24, app.cfg.CACHE_STALE_WHILE_REVALIDATE, middleware.StaticTags(models.MovieListCacheTag))).Get("/list", app.userHandler.GetList)
+			r.Get("/search", app.userHandler.Search)
+			r.Get("/suggest", app.userHandler.Suggest)
+			r.Get("/export", app.userHandler.Export)
+		})
+
+		r.Route("/admin/api-keys", func(r chi.Router) {
+			r.Use(authz.Require(authz.APIKeysManage))
+			r.Post("/", app.keyHandler.Create)
+			r.Get("/", app.keyHandler.List)
+			r.Delete("/{id}", app.keyHandler.Revoke)
+		})
 	})
 
 	return r
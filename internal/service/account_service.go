@@ -0,0 +1,282 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mexirica/chi-template/internal/auth"
+	"github.com/mexirica/chi-template/internal/db/repository"
+	"github.com/mexirica/chi-template/internal/errs"
+	"github.com/mexirica/chi-template/internal/models"
+	"github.com/mexirica/chi-template/internal/o11y"
+	"github.com/mexirica/chi-template/internal/session"
+	"github.com/rs/zerolog/log"
+)
+
+// Accounts manages the accounts of users and their sessions.
+type Accounts interface {
+	Register(ctx context.Context, payload models.RegisterRequest) (*models.User, error)
+	// Login starts a session, or returns an errs.ErrUnauthorized error for wrong credentials
+	// and an errs.ErrTooManyRequests one while the client at clientIP is locked out.
+	Login(ctx context.Context, payload models.LoginRequest, clientIP string) (*models.TokenResponse, error)
+	// Refresh exchanges a refresh token for a new pair of tokens; the refresh token stops
+	// working.
+	Refresh(ctx context.Context, refreshToken string) (*models.TokenResponse, error)
+	// Logout ends the session of the refresh token.
+	Logout(ctx context.Context, refreshToken string) error
+}
+
+const (
+	// DefaultRefreshTTL is how long a session lasts without being refreshed.
+	DefaultRefreshTTL = 30 * 24 * time.Hour
+	// DefaultMaxLoginFailures is the number of failed logins that locks a client out of an
+	// account.
+	DefaultMaxLoginFailures = 5
+	// DefaultLockout is how long failed logins are counted, from the first one, and so the
+	// longest an account stays locked out.
+	DefaultLockout = 15 * time.Minute
+
+	// refreshTokenPrefix starts every refresh token, so that leaked tokens are easy to
+	// recognise.
+	refreshTokenPrefix = "rt_"
+	// refreshTokenBytes is the entropy of a refresh token.
+	refreshTokenBytes = 32
+)
+
+// UserSubject is the subject of a user's access tokens, which role bindings refer to.
+func UserSubject(id int64) string {
+	return "user:" + strconv.FormatInt(id, 10)
+}
+
+// dummyHash is checked against the password of unknown emails, so that they take as long to
+// reject as wrong passwords and cannot be told apart.
+var dummyHash = sync.OnceValue(func() string {
+	hash, err := auth.HashPassword("password of an unknown email")
+	if err != nil {
+		panic(err)
+	}
+	return hash
+})
+
+type AccountService struct {
+	users       repository.UserRepository
+	sessions    session.Store
+	signer      *auth.Signer
+	refreshTTL  time.Duration
+	maxFailures int64
+	lockout     time.Duration
+}
+
+// AccountOption configures an AccountService.
+type AccountOption func(*AccountService)
+
+// WithRefreshTTL sets how long a session lasts without being refreshed. Zero keeps
+// DefaultRefreshTTL.
+func WithRefreshTTL(ttl time.Duration) AccountOption {
+	return func(s *AccountService) {
+		if ttl > 0 {
+			s.refreshTTL = ttl
+		}
+	}
+}
+
+// WithLockout locks an account out once maxFailures logins failed within window of the first
+// one, until window has passed. Zero values keep the defaults.
+func WithLockout(maxFailures int, window time.Duration) AccountOption {
+	return func(s *AccountService) {
+		if maxFailures > 0 {
+			s.maxFailures = int64(maxFailures)
+		}
+		if window > 0 {
+			s.lockout = window
+		}
+	}
+}
+
+// NewAccountService returns the account service. Sessions and failed logins are kept in
+// sessions; access tokens are issued by signer.
+func NewAccountService(users repository.UserRepository, sessions session.Store, signer *auth.Signer, opts ...AccountOption) *AccountService {
+	s := &AccountService{
+		users:       users,
+		sessions:    sessions,
+		signer:      signer,
+		refreshTTL:  DefaultRefreshTTL,
+		maxFailures: DefaultMaxLoginFailures,
+		lockout:     DefaultLockout,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *AccountService) Register(ctx context.Context, payload models.RegisterRequest) (*models.User, error) {
+	ctx, span := o11y.Tracer().Start(ctx, "AccountService.Register")
+	defer span.End()
+
+	hash, err := auth.HashPassword(payload.Password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+	user, err := s.users.Create(ctx, normalizeEmail(payload.Email), hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to register user: %w", err)
+	}
+	return user, nil
+}
+
+// Login checks the credentials. Attempts are counted by email, known or not, and client IP
+// address: once there are too many, that client is locked out of that email, while other
+// clients can still log in. An attempt is counted before the password is checked and
+// forgotten when it succeeds, so that concurrent attempts cannot check more passwords than
+// the lockout allows.
+func (s *AccountService) Login(ctx context.Context, payload models.LoginRequest, clientIP string) (*models.TokenResponse, error) {
+	ctx, span := o11y.Tracer().Start(ctx, "AccountService.Login")
+	defer span.End()
+
+	email := normalizeEmail(payload.Email)
+	key := email + "|" + clientIP
+	attempts, err := s.sessions.Fail(ctx, key, s.lockout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to log in: %w", err)
+	}
+	if attempts > s.maxFailures {
+		_, retryAfter, err := s.sessions.Failures(ctx, key)
+		if err != nil || retryAfter <= 0 {
+			retryAfter = s.lockout
+		}
+		return nil, errs.TooManyRequests(retryAfter, "too many failed logins, try again later")
+	}
+
+	user, err := s.users.GetByEmail(ctx, email)
+	if err != nil && !errors.Is(err, errs.ErrNotFound) {
+		return nil, fmt.Errorf("failed to log in: %w", err)
+	}
+	hash := dummyHash()
+	if user != nil {
+		hash = user.PasswordHash
+	}
+	ok, err := auth.CheckPassword(hash, payload.Password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check password: %w", err)
+	}
+
+	if user == nil || !ok {
+		if attempts == s.maxFailures {
+			return nil, errs.TooManyRequests(s.lockout, "too many failed logins, try again later")
+		}
+		return nil, errs.Unauthorized("the email or password is incorrect")
+	}
+
+	if err := s.sessions.Reset(ctx, key); err != nil {
+		log.Warn().Err(err).Int64("user_id", user.ID).Msg("Error resetting failed logins")
+	}
+
+	access, err := s.accessToken(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	sess := session.Session{ID: newSessionID(), UserID: user.ID, CreatedAt: time.Now()}
+	if err := s.sessions.Create(ctx, sess, digestRefreshToken(refresh), s.refreshTTL); err != nil {
+		return nil, fmt.Errorf("failed to start session: %w", err)
+	}
+	return s.tokens(access, refresh), nil
+}
+
+// Refresh rotates the refresh token. A refresh token presented after it was rotated ends its
+// session: either it or its successor leaked, and the session can no longer be trusted.
+func (s *AccountService) Refresh(ctx context.Context, refreshToken string) (*models.TokenResponse, error) {
+	ctx, span := o11y.Tracer().Start(ctx, "AccountService.Refresh")
+	defer span.End()
+
+	if !strings.HasPrefix(refreshToken, refreshTokenPrefix) {
+		return nil, errs.Unauthorized("the refresh token is invalid")
+	}
+	next, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	sess, err := s.sessions.Rotate(ctx, digestRefreshToken(refreshToken), digestRefreshToken(next), s.refreshTTL)
+	switch {
+	case errors.Is(err, session.ErrReused):
+		log.Warn().Msg("Rotated refresh token presented again; its session was ended")
+		return nil, errs.Unauthorized("the refresh token is invalid")
+	case errors.Is(err, session.ErrNotFound):
+		return nil, errs.Unauthorized("the refresh token is invalid")
+	case err != nil:
+		return nil, fmt.Errorf("failed to refresh session: %w", err)
+	}
+
+	access, err := s.accessToken(sess.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return s.tokens(access, next), nil
+}
+
+// Logout ends the session. Unknown tokens are ignored, so that logging out twice succeeds.
+func (s *AccountService) Logout(ctx context.Context, refreshToken string) error {
+	ctx, span := o11y.Tracer().Start(ctx, "AccountService.Logout")
+	defer span.End()
+
+	if err := s.sessions.Delete(ctx, digestRefreshToken(refreshToken)); err != nil {
+		return fmt.Errorf("failed to end session: %w", err)
+	}
+	return nil
+}
+
+func (s *AccountService) accessToken(userID int64) (string, error) {
+	token, err := s.signer.Sign(UserSubject(userID))
+	if err != nil {
+		return "", errs.Unavailable(err, "login is not configured")
+	}
+	return token, nil
+}
+
+func (s *AccountService) tokens(access, refresh string) *models.TokenResponse {
+	return &models.TokenResponse{
+		AccessToken:  access,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(s.signer.TTL().Seconds()),
+		RefreshToken: refresh,
+	}
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func newRefreshToken() (string, error) {
+	secret := make([]byte, refreshTokenBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	return refreshTokenPrefix + base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+func newSessionID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// digestRefreshToken is what the session store keeps of a refresh token, so that reading the
+// store does not give away live tokens.
+func digestRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
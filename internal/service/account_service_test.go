@@ -0,0 +1,180 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/mexirica/chi-template/internal/auth"
+	"github.com/mexirica/chi-template/internal/db/repository"
+	"github.com/mexirica/chi-template/internal/errs"
+	"github.com/mexirica/chi-template/internal/models"
+	"github.com/mexirica/chi-template/internal/service"
+	"github.com/mexirica/chi-template/internal/session"
+)
+
+var accountSecret = []byte("a-shared-secret-of-enough-length")
+
+const clientIP = "192.0.2.1"
+
+func newAccountService(t *testing.T, opts ...service.AccountOption) (*service.AccountService, *repository.MockUserRepository) {
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	users := repository.NewMockUserRepository(ctrl)
+	signer := auth.NewSigner("movies-api", "", accountSecret, time.Minute)
+	return service.NewAccountService(users, session.NewMemory(), signer, opts...), users
+}
+
+func credentials(t *testing.T, id int64, email, password string) *models.UserCredentials {
+	t.Helper()
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &models.UserCredentials{User: models.User{ID: id, Email: email}, PasswordHash: hash}
+}
+
+func TestAccountService_Register(t *testing.T) {
+	svc, users := newAccountService(t)
+
+	var hash string
+	users.EXPECT().Create(gomock.Any(), "ada@example.com", gomock.Any()).DoAndReturn(func(_ context.Context, email, passwordHash string) (*models.User, error) {
+		hash = passwordHash
+		return &models.User{ID: 1, Email: email}, nil
+	})
+
+	user, err := svc.Register(context.Background(), models.RegisterRequest{Email: " Ada@Example.com", Password: "hunter22"})
+	if err != nil || user.ID != 1 {
+		t.Fatalf("Register() = %+v, %v", user, err)
+	}
+	if ok, err := auth.CheckPassword(hash, "hunter22"); err != nil || !ok {
+		t.Error("expected the hash of the password to be stored")
+	}
+}
+
+func TestAccountService_LoginRefreshLogout(t *testing.T) {
+	svc, users := newAccountService(t)
+	ctx := context.Background()
+
+	users.EXPECT().GetByEmail(gomock.Any(), "ada@example.com").Return(credentials(t, 42, "ada@example.com", "hunter22"), nil)
+	tokens, err := svc.Login(ctx, models.LoginRequest{Email: "ADA@example.com", Password: "hunter22"}, clientIP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tokens.TokenType != "Bearer" || tokens.ExpiresIn != 60 || tokens.RefreshToken == "" {
+		t.Errorf("unexpected tokens %+v", tokens)
+	}
+	claims, err := auth.NewVerifier("movies-api", auth.WithHMACSecret(accountSecret)).Verify(ctx, tokens.AccessToken)
+	if err != nil || claims.Subject != "user:42" {
+		t.Fatalf("expected an access token for user:42, got %+v, %v", claims, err)
+	}
+
+	refreshed, err := svc.Refresh(ctx, tokens.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refreshed.RefreshToken == tokens.RefreshToken {
+		t.Error("expected the refresh token to be rotated")
+	}
+
+	if err := svc.Logout(ctx, refreshed.RefreshToken); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Refresh(ctx, refreshed.RefreshToken); !errors.Is(err, errs.ErrUnauthorized) {
+		t.Errorf("expected ErrUnauthorized after logout, got %v", err)
+	}
+}
+
+func TestAccountService_Refresh_ReuseEndsSession(t *testing.T) {
+	svc, users := newAccountService(t)
+	ctx := context.Background()
+
+	users.EXPECT().GetByEmail(gomock.Any(), "ada@example.com").Return(credentials(t, 42, "ada@example.com", "hunter22"), nil)
+	tokens, err := svc.Login(ctx, models.LoginRequest{Email: "ada@example.com", Password: "hunter22"}, clientIP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	refreshed, err := svc.Refresh(ctx, tokens.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := svc.Refresh(ctx, tokens.RefreshToken); !errors.Is(err, errs.ErrUnauthorized) {
+		t.Fatalf("expected the replayed token to be rejected, got %v", err)
+	}
+	if _, err := svc.Refresh(ctx, refreshed.RefreshToken); !errors.Is(err, errs.ErrUnauthorized) {
+		t.Errorf("expected the replay to end the session, got %v", err)
+	}
+}
+
+func TestAccountService_Login_Lockout(t *testing.T) {
+	svc, users := newAccountService(t, service.WithLockout(3, time.Hour))
+	ctx := context.Background()
+	user := credentials(t, 42, "ada@example.com", "hunter22")
+
+	users.EXPECT().GetByEmail(gomock.Any(), "ada@example.com").Return(user, nil).Times(3)
+	for i := 1; i <= 2; i++ {
+		if _, err := svc.Login(ctx, models.LoginRequest{Email: "ada@example.com", Password: "wrong"}, clientIP); !errors.Is(err, errs.ErrUnauthorized) {
+			t.Fatalf("failure %d: expected ErrUnauthorized, got %v", i, err)
+		}
+	}
+	_, err := svc.Login(ctx, models.LoginRequest{Email: "ada@example.com", Password: "wrong"}, clientIP)
+	if !errors.Is(err, errs.ErrTooManyRequests) || errs.RetryAfter(err) != time.Hour {
+		t.Fatalf("expected the third failure to lock the account out for an hour, got %v", err)
+	}
+
+	// Even the right password is refused, without a lookup, while the client is locked out.
+	if _, err := svc.Login(ctx, models.LoginRequest{Email: "ada@example.com", Password: "hunter22"}, clientIP); !errors.Is(err, errs.ErrTooManyRequests) {
+		t.Errorf("expected ErrTooManyRequests while locked out, got %v", err)
+	}
+
+	// Other clients are not locked out of the account.
+	users.EXPECT().GetByEmail(gomock.Any(), "ada@example.com").Return(user, nil)
+	if _, err := svc.Login(ctx, models.LoginRequest{Email: "ada@example.com", Password: "hunter22"}, "198.51.100.7"); err != nil {
+		t.Errorf("expected another client to log in, got %v", err)
+	}
+}
+
+func TestAccountService_Login_ConcurrentAttempts(t *testing.T) {
+	svc, users := newAccountService(t, service.WithLockout(3, time.Hour))
+	user := credentials(t, 42, "ada@example.com", "hunter22")
+
+	// However many attempts run at once, at most the lockout's number check a password.
+	users.EXPECT().GetByEmail(gomock.Any(), "ada@example.com").Return(user, nil).MaxTimes(3)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = svc.Login(context.Background(), models.LoginRequest{Email: "ada@example.com", Password: "wrong"}, clientIP)
+		}()
+	}
+	wg.Wait()
+}
+
+func TestAccountService_Login_UnknownEmail(t *testing.T) {
+	svc, users := newAccountService(t, service.WithLockout(2, time.Hour))
+	ctx := context.Background()
+
+	users.EXPECT().GetByEmail(gomock.Any(), "nobody@example.com").Return(nil, errs.NotFound("user not found")).Times(2)
+	if _, err := svc.Login(ctx, models.LoginRequest{Email: "nobody@example.com", Password: "hunter22"}, clientIP); !errors.Is(err, errs.ErrUnauthorized) {
+		t.Errorf("expected ErrUnauthorized, got %v", err)
+	}
+	if _, err := svc.Login(ctx, models.LoginRequest{Email: "nobody@example.com", Password: "hunter22"}, clientIP); !errors.Is(err, errs.ErrTooManyRequests) {
+		t.Errorf("expected unknown emails to be locked out like known ones, got %v", err)
+	}
+}
+
+func TestAccountService_Refresh_UnknownToken(t *testing.T) {
+	svc, _ := newAccountService(t)
+
+	for _, token := range []string{"rt_unknown", "not-a-token"} {
+		if _, err := svc.Refresh(context.Background(), token); !errors.Is(err, errs.ErrUnauthorized) {
+			t.Errorf("Refresh(%q): expected ErrUnauthorized, got %v", token, err)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mexirica/chi-template/internal/auth"
+	"github.com/mexirica/chi-template/internal/authz"
+	"github.com/mexirica/chi-template/internal/db/repository"
+	"github.com/mexirica/chi-template/internal/errs"
+	"github.com/mexirica/chi-template/internal/models"
+	"github.com/mexirica/chi-template/internal/o11y"
+	"github.com/mexirica/chi-template/internal/validation"
+	"github.com/rs/zerolog/log"
+)
+
+// APIKeys manages the API keys of machine clients.
+type APIKeys interface {
+	Create(ctx context.Context, payload models.CreateAPIKeyRequest) (*models.CreatedAPIKey, error)
+	List(ctx context.Context) ([]models.APIKey, error)
+	Revoke(ctx context.Context, id int64) error
+	// Authenticate returns the active API key matching key, or an errs.ErrUnauthorized error.
+	Authenticate(ctx context.Context, key string) (*models.APIKey, error)
+}
+
+const (
+	// apiKeyPrefix starts every API key, so that leaked keys are easy to recognise.
+	apiKeyPrefix = "ck_"
+	// apiKeyBytes is the entropy of an API key.
+	apiKeyBytes = 32
+	// apiKeyShownPrefix is the length of the prefix kept to recognise a key in listings.
+	apiKeyShownPrefix = len(apiKeyPrefix) + 8
+	// lastUsedResolution bounds how often the last use of a key is written, so that busy keys
+	// do not cost a write per request.
+	lastUsedResolution = time.Minute
+)
+
+type APIKeyService struct {
+	repo repository.APIKeyRepository
+	// scopes are the scopes a key may be granted.
+	scopes []string
+	// authorizer resolves the permissions of the caller creating a key.
+	authorizer *authz.Authorizer
+}
+
+// NewAPIKeyService returns the API key service. Keys can only be granted the given scopes,
+// and only those the caller creating them holds, as resolved by authorizer.
+func NewAPIKeyService(repo repository.APIKeyRepository, scopes []string, authorizer *authz.Authorizer) *APIKeyService {
+	return &APIKeyService{repo: repo, scopes: scopes, authorizer: authorizer}
+}
+
+// Create generates a new key for the caller in ctx, who must hold every scope it grants, so
+// that managing keys does not give access to more than the caller already has. Only the
+// key's hash is stored: the key is returned once, here.
+func (s *APIKeyService) Create(ctx context.Context, payload models.CreateAPIKeyRequest) (*models.CreatedAPIKey, error) {
+	ctx, span := o11y.Tracer().Start(ctx, "APIKeyService.Create")
+	defer span.End()
+
+	var errList validation.Errors
+	for _, scope := range payload.Scopes {
+		if !slices.Contains(s.scopes, scope) {
+			errList = append(errList, validation.ValidationErrorResponse{FailedField: "scopes", Tag: "oneof", Value: scope})
+		}
+	}
+	if payload.ExpiresAt != nil && !payload.ExpiresAt.After(time.Now()) {
+		errList = append(errList, validation.ValidationErrorResponse{FailedField: "expires_at", Tag: "future"})
+	}
+	if len(errList) > 0 {
+		return nil, errList
+	}
+
+	caller, ok := auth.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, errs.Unauthorized("authentication is required")
+	}
+	perms := make([]authz.Permission, 0, len(payload.Scopes))
+	for _, scope := range payload.Scopes {
+		perms = append(perms, authz.Permission(scope))
+	}
+	missing, err := s.authorizer.Missing(ctx, caller, perms...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve caller permissions: %w", err)
+	}
+	if len(missing) > 0 {
+		return nil, errs.Forbidden("the %s scope cannot be granted by a caller without it", missing[0])
+	}
+
+	secret := make([]byte, apiKeyBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate API key: %w", err)
+	}
+	key := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	created, err := s.repo.Create(ctx, models.NewAPIKey{
+		Name:      payload.Name,
+		Prefix:    key[:apiKeyShownPrefix],
+		Hash:      hashAPIKey(key),
+		Scopes:    payload.Scopes,
+		ExpiresAt: payload.ExpiresAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create API key: %w", err)
+	}
+	return &models.CreatedAPIKey{APIKey: *created, Key: key}, nil
+}
+
+func (s *APIKeyService) List(ctx context.Context) ([]models.APIKey, error) {
+	ctx, span := o11y.Tracer().Start(ctx, "APIKeyService.List")
+	defer span.End()
+
+	keys, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+	return keys, nil
+}
+
+func (s *APIKeyService) Revoke(ctx context.Context, id int64) error {
+	ctx, span := o11y.Tracer().Start(ctx, "APIKeyService.Revoke")
+	defer span.End()
+
+	if err := s.repo.Revoke(ctx, id); err != nil {
+		return fmt.Errorf("failed to revoke API key: %w", err)
+	}
+	return nil
+}
+
+// Authenticate looks the key up by its hash. Unknown, revoked and expired keys are rejected
+// alike, so that the answer reveals nothing about the key.
+func (s *APIKeyService) Authenticate(ctx context.Context, key string) (*models.APIKey, error) {
+	ctx, span := o11y.Tracer().Start(ctx, "APIKeyService.Authenticate")
+	defer span.End()
+
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return nil, errs.Unauthorized("the API key is invalid")
+	}
+	found, err := s.repo.GetByHash(ctx, hashAPIKey(key))
+	if errors.Is(err, errs.ErrNotFound) {
+		return nil, errs.Unauthorized("the API key is invalid")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to authenticate API key: %w", err)
+	}
+
+	now := time.Now()
+	if !found.Active(now) {
+		return nil, errs.Unauthorized("the API key is invalid")
+	}
+
+	if found.LastUsedAt == nil || now.Sub(*found.LastUsedAt) >= lastUsedResolution {
+		if err := s.repo.Touch(ctx, found.ID); err != nil {
+			log.Warn().Err(err).Int64("api_key_id", found.ID).Msg("Error recording API key use")
+		}
+	}
+	return found, nil
+}
+
+// hashAPIKey digests a key for storage. API keys are long random strings, so a fast hash is
+// enough: there is nothing to brute-force.
+func hashAPIKey(key string) []byte {
+	sum := sha256.Sum256([]byte(key))
+	return sum[:]
+}
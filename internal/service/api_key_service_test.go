@@ -0,0 +1,176 @@
+package service_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/golang/mock/gomock"
+	"github.com/mexirica/chi-template/internal/auth"
+	"github.com/mexirica/chi-template/internal/authz"
+	"github.com/mexirica/chi-template/internal/db/repository"
+	"github.com/mexirica/chi-template/internal/errs"
+	"github.com/mexirica/chi-template/internal/models"
+	"github.com/mexirica/chi-template/internal/service"
+	"github.com/mexirica/chi-template/internal/validation"
+)
+
+var apiKeyScopes = []string{"movies:create", "movies:update", "movies:delete"}
+
+var keyAuthorizer = authz.NewAuthorizer(authz.DefaultPolicy, nil)
+
+// asCaller returns a context whose caller holds the roles.
+func asCaller(roles ...string) context.Context {
+	return auth.WithClaims(context.Background(), &auth.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "u1"}, Roles: roles})
+}
+
+func TestAPIKeyService_Create(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockAPIKeyRepository(ctrl)
+	svc := service.NewAPIKeyService(mockRepo, apiKeyScopes, keyAuthorizer)
+
+	var stored models.NewAPIKey
+	mockRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, key models.NewAPIKey) (*models.APIKey, error) {
+		stored = key
+		return &models.APIKey{ID: 7, Name: key.Name, Prefix: key.Prefix, Scopes: key.Scopes}, nil
+	})
+
+	created, err := svc.Create(asCaller(authz.RoleEditor), models.CreateAPIKeyRequest{Name: "partner", Scopes: []string{"movies:create"}})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if !strings.HasPrefix(created.Key, "ck_") || !strings.HasPrefix(created.Key, stored.Prefix) {
+		t.Errorf("expected a ck_ key starting with the stored prefix %q, got %q", stored.Prefix, created.Key)
+	}
+	if sum := sha256.Sum256([]byte(created.Key)); !bytes.Equal(stored.Hash, sum[:]) {
+		t.Error("expected the SHA-256 hash of the key to be stored")
+	}
+}
+
+func TestAPIKeyService_Create_Invalid(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockAPIKeyRepository(ctrl)
+	svc := service.NewAPIKeyService(mockRepo, apiKeyScopes, keyAuthorizer)
+
+	past := time.Now().Add(-time.Hour)
+	_, err := svc.Create(asCaller(authz.RoleEditor), models.CreateAPIKeyRequest{
+		Name:      "partner",
+		Scopes:    []string{"movies:create", "movies:destroy"},
+		ExpiresAt: &past,
+	})
+
+	var errList validation.Errors
+	if !errors.As(err, &errList) || len(errList) != 2 {
+		t.Fatalf("expected the unknown scope and the past expiry to be reported, got %v", err)
+	}
+	if errList[0].Value != "movies:destroy" || errList[1].FailedField != "expires_at" {
+		t.Errorf("unexpected validation errors %+v", errList)
+	}
+}
+
+func TestAPIKeyService_Create_ScopesNotHeld(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	svc := service.NewAPIKeyService(repository.NewMockAPIKeyRepository(ctrl), apiKeyScopes, keyAuthorizer)
+
+	// An admin manages keys but cannot create movies, so cannot grant a key that either.
+	_, err := svc.Create(asCaller(authz.RoleAdmin), models.CreateAPIKeyRequest{Name: "partner", Scopes: []string{"movies:delete", "movies:create"}})
+	if !errors.Is(err, errs.ErrForbidden) {
+		t.Errorf("expected ErrForbidden, got %v", err)
+	}
+
+	// A key managing keys holds no movie permission, so can grant none.
+	key := auth.WithClaims(context.Background(), &auth.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{Subject: "apikey:3"},
+		Permissions:      []string{string(authz.APIKeysManage)},
+	})
+	if _, err := svc.Create(key, models.CreateAPIKeyRequest{Name: "partner", Scopes: []string{"movies:delete"}}); !errors.Is(err, errs.ErrForbidden) {
+		t.Errorf("expected ErrForbidden for a key minting keys, got %v", err)
+	}
+}
+
+func TestAPIKeyService_Authenticate(t *testing.T) {
+	const key = "ck_secret"
+	sum := sha256.Sum256([]byte(key))
+	now := time.Now()
+	past, future, recent := now.Add(-time.Hour), now.Add(time.Hour), now.Add(-time.Second)
+
+	tests := []struct {
+		name    string
+		key     string
+		found   *models.APIKey
+		lookup  error
+		touch   bool
+		wantErr error
+	}{
+		{"active key", key, &models.APIKey{ID: 1}, nil, true, nil},
+		{"recently used key", key, &models.APIKey{ID: 1, LastUsedAt: &recent}, nil, false, nil},
+		{"not yet expired key", key, &models.APIKey{ID: 1, ExpiresAt: &future, LastUsedAt: &past}, nil, true, nil},
+		{"expired key", key, &models.APIKey{ID: 1, ExpiresAt: &past}, nil, false, errs.ErrUnauthorized},
+		{"revoked key", key, &models.APIKey{ID: 1, RevokedAt: &past}, nil, false, errs.ErrUnauthorized},
+		{"unknown key", key, nil, errs.NotFound("API key not found"), false, errs.ErrUnauthorized},
+		{"database down", key, nil, errs.Unavailable(errors.New("dial tcp"), "database unavailable"), false, errs.ErrUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockRepo := repository.NewMockAPIKeyRepository(ctrl)
+			svc := service.NewAPIKeyService(mockRepo, apiKeyScopes, keyAuthorizer)
+
+			mockRepo.EXPECT().GetByHash(gomock.Any(), sum[:]).Return(tt.found, tt.lookup)
+			if tt.touch {
+				mockRepo.EXPECT().Touch(gomock.Any(), tt.found.ID).Return(nil)
+			}
+
+			found, err := svc.Authenticate(context.Background(), tt.key)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil || found.ID != tt.found.ID {
+				t.Errorf("expected key %d, got %+v, %v", tt.found.ID, found, err)
+			}
+		})
+	}
+}
+
+func TestAPIKeyService_Authenticate_Malformed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	svc := service.NewAPIKeyService(repository.NewMockAPIKeyRepository(ctrl), apiKeyScopes, keyAuthorizer)
+
+	if _, err := svc.Authenticate(context.Background(), "not-a-key"); !errors.Is(err, errs.ErrUnauthorized) {
+		t.Errorf("expected ErrUnauthorized without a lookup, got %v", err)
+	}
+}
+
+func TestAPIKeyService_Revoke_NotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockAPIKeyRepository(ctrl)
+	svc := service.NewAPIKeyService(mockRepo, apiKeyScopes, keyAuthorizer)
+
+	mockRepo.EXPECT().Revoke(gomock.Any(), int64(9)).Return(errs.NotFound("API key %d not found", 9))
+
+	if err := svc.Revoke(context.Background(), 9); !errors.Is(err, errs.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
@@ -7,6 +7,7 @@ package service
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 	models "github.com/mexirica/chi-template/internal/models"
@@ -36,11 +37,12 @@ func (m *MockService) EXPECT() *MockServiceMockRecorder {
 }
 
 // Create mocks base method.
-func (m *MockService) Create(ctx context.Context, payload models.CreateMovieRequest) error {
+func (m *MockService) Create(ctx context.Context, payload models.CreateMovieRequest) (*models.Movie, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Create", ctx, payload)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret0, _ := ret[0].(*models.Movie)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // Create indicates an expected call of Create.
@@ -50,17 +52,31 @@ func (mr *MockServiceMockRecorder) Create(ctx, payload interface{}) *gomock.Call
 }
 
 // Delete mocks base method.
-func (m *MockService) Delete(ctx context.Context, id int) error {
+func (m *MockService) Delete(ctx context.Context, id int, version int64) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Delete", ctx, id)
+	ret := m.ctrl.Call(m, "Delete", ctx, id, version)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Delete indicates an expected call of Delete.
-func (mr *MockServiceMockRecorder) Delete(ctx, id interface{}) *gomock.Call {
+func (mr *MockServiceMockRecorder) Delete(ctx, id, version interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockService)(nil).Delete), ctx, id, version)
+}
+
+// Export mocks base method.
+func (m *MockService) Export(ctx context.Context, fn func(models.Movie) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Export", ctx, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Export indicates an expected call of Export.
+func (mr *MockServiceMockRecorder) Export(ctx, fn interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockService)(nil).Delete), ctx, id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Export", reflect.TypeOf((*MockService)(nil).Export), ctx, fn)
 }
 
 // GetById mocks base method.
@@ -79,16 +95,174 @@ func (mr *MockServiceMockRecorder) GetById(ctx, id interface{}) *gomock.Call {
 }
 
 // GetList mocks base method.
-func (m *MockService) GetList(ctx context.Context, page, limit int) (*models.GetMovieList, error) {
+func (m *MockService) GetList(ctx context.Context, filter models.MovieFilter) (*models.GetMovieList, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetList", ctx, page, limit)
+	ret := m.ctrl.Call(m, "GetList", ctx, filter)
 	ret0, _ := ret[0].(*models.GetMovieList)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetList indicates an expected call of GetList.
-func (mr *MockServiceMockRecorder) GetList(ctx, page, limit interface{}) *gomock.Call {
+func (mr *MockServiceMockRecorder) GetList(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetList", reflect.TypeOf((*MockService)(nil).GetList), ctx, filter)
+}
+
+// Import mocks base method.
+func (m *MockService) Import(ctx context.Context, src MovieSource, dryRun bool) (*models.ImportReport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Import", ctx, src, dryRun)
+	ret0, _ := ret[0].(*models.ImportReport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Import indicates an expected call of Import.
+func (mr *MockServiceMockRecorder) Import(ctx, src, dryRun interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Import", reflect.TypeOf((*MockService)(nil).Import), ctx, src, dryRun)
+}
+
+// Patch mocks base method.
+func (m *MockService) Patch(ctx context.Context, id int, patch []byte, version int64) (*models.Movie, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Patch", ctx, id, patch, version)
+	ret0, _ := ret[0].(*models.Movie)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Patch indicates an expected call of Patch.
+func (mr *MockServiceMockRecorder) Patch(ctx, id, patch, version interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Patch", reflect.TypeOf((*MockService)(nil).Patch), ctx, id, patch, version)
+}
+
+// Purge mocks base method.
+func (m *MockService) Purge(ctx context.Context, retention time.Duration) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Purge", ctx, retention)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Purge indicates an expected call of Purge.
+func (mr *MockServiceMockRecorder) Purge(ctx, retention interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Purge", reflect.TypeOf((*MockService)(nil).Purge), ctx, retention)
+}
+
+// Restore mocks base method.
+func (m *MockService) Restore(ctx context.Context, id int) (*models.Movie, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Restore", ctx, id)
+	ret0, _ := ret[0].(*models.Movie)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Restore indicates an expected call of Restore.
+func (mr *MockServiceMockRecorder) Restore(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Restore", reflect.TypeOf((*MockService)(nil).Restore), ctx, id)
+}
+
+// Search mocks base method.
+func (m *MockService) Search(ctx context.Context, search models.MovieSearch) (*models.MovieSearchResults, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Search", ctx, search)
+	ret0, _ := ret[0].(*models.MovieSearchResults)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Search indicates an expected call of Search.
+func (mr *MockServiceMockRecorder) Search(ctx, search interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Search", reflect.TypeOf((*MockService)(nil).Search), ctx, search)
+}
+
+// Suggest mocks base method.
+func (m *MockService) Suggest(ctx context.Context, suggest models.MovieSuggest) (*models.MovieSuggestions, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Suggest", ctx, suggest)
+	ret0, _ := ret[0].(*models.MovieSuggestions)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Suggest indicates an expected call of Suggest.
+func (mr *MockServiceMockRecorder) Suggest(ctx, suggest interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Suggest", reflect.TypeOf((*MockService)(nil).Suggest), ctx, suggest)
+}
+
+// Trash mocks base method.
+func (m *MockService) Trash(ctx context.Context, page, limit int) (*models.MovieTrash, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Trash", ctx, page, limit)
+	ret0, _ := ret[0].(*models.MovieTrash)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Trash indicates an expected call of Trash.
+func (mr *MockServiceMockRecorder) Trash(ctx, page, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Trash", reflect.TypeOf((*MockService)(nil).Trash), ctx, page, limit)
+}
+
+// Update mocks base method.
+func (m *MockService) Update(ctx context.Context, id int, payload models.UpdateMovieRequest, version int64) (*models.Movie, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", ctx, id, payload, version)
+	ret0, _ := ret[0].(*models.Movie)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockServiceMockRecorder) Update(ctx, id, payload, version interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockService)(nil).Update), ctx, id, payload, version)
+}
+
+// MockMovieSource is a mock of MovieSource interface.
+type MockMovieSource struct {
+	ctrl     *gomock.Controller
+	recorder *MockMovieSourceMockRecorder
+}
+
+// MockMovieSourceMockRecorder is the mock recorder for MockMovieSource.
+type MockMovieSourceMockRecorder struct {
+	mock *MockMovieSource
+}
+
+// NewMockMovieSource creates a new mock instance.
+func NewMockMovieSource(ctrl *gomock.Controller) *MockMovieSource {
+	mock := &MockMovieSource{ctrl: ctrl}
+	mock.recorder = &MockMovieSourceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockMovieSource) EXPECT() *MockMovieSourceMockRecorder {
+	return m.recorder
+}
+
+// Next mocks base method.
+func (m *MockMovieSource) Next() (models.CreateMovieRequest, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Next")
+	ret0, _ := ret[0].(models.CreateMovieRequest)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Next indicates an expected call of Next.
+func (mr *MockMovieSourceMockRecorder) Next() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetList", reflect.TypeOf((*MockService)(nil).GetList), ctx, page, limit)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Next", reflect.TypeOf((*MockMovieSource)(nil).Next))
 }
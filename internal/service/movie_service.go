@@ -3,39 +3,82 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"strings"
+	"time"
 
+	"github.com/mexirica/chi-template/internal/cache"
 	"github.com/mexirica/chi-template/internal/db/repository"
+	"github.com/mexirica/chi-template/internal/errs"
+	"github.com/mexirica/chi-template/internal/helpers"
 	"github.com/mexirica/chi-template/internal/models"
 	"github.com/mexirica/chi-template/internal/o11y"
+	"github.com/mexirica/chi-template/internal/validation"
+	"github.com/rs/zerolog/log"
 )
 
 type Service interface {
-	Create(ctx context.Context, payload models.CreateMovieRequest) error
+	Create(ctx context.Context, payload models.CreateMovieRequest) (*models.Movie, error)
 	GetById(ctx context.Context, id int) (*models.Movie, error)
-	GetList(ctx context.Context, page, limit int) (*models.GetMovieList, error)
-	Delete(ctx context.Context, id int) error
+	GetList(ctx context.Context, filter models.MovieFilter) (*models.GetMovieList, error)
+	Search(ctx context.Context, search models.MovieSearch) (*models.MovieSearchResults, error)
+	Suggest(ctx context.Context, suggest models.MovieSuggest) (*models.MovieSuggestions, error)
+	Update(ctx context.Context, id int, payload models.UpdateMovieRequest, version int64) (*models.Movie, error)
+	Patch(ctx context.Context, id int, patch []byte, version int64) (*models.Movie, error)
+	Delete(ctx context.Context, id int, version int64) error
+	Trash(ctx context.Context, page, limit int) (*models.MovieTrash, error)
+	Restore(ctx context.Context, id int) (*models.Movie, error)
+	Purge(ctx context.Context, retention time.Duration) (int64, error)
+	Import(ctx context.Context, src MovieSource, dryRun bool) (*models.ImportReport, error)
+	Export(ctx context.Context, fn func(models.Movie) error) error
 }
 
+// MovieSource yields the movies of a bulk import one at a time. Next returns io.EOF after the
+// last movie; an errs.ErrValidation error rejects only the current movie.
+type MovieSource interface {
+	Next() (models.CreateMovieRequest, error)
+}
+
+const (
+	// importBatchSize is the number of rows sent per COPY.
+	importBatchSize = 1000
+	// maxImportErrors caps the row errors listed in an import report.
+	maxImportErrors = 1000
+)
+
+// maxPatchAttempts bounds how many times an unconditional patch is merged again after losing
+// a race with another write.
+const maxPatchAttempts = 3
+
+// suggestTTL bounds how stale autocomplete suggestions can get. Suggestions are requested on
+// every keystroke, so even a short TTL absorbs most of the load.
+const suggestTTL = time.Minute
+
 type MovieService struct {
-	repo repository.MovieRepository
+	repo  repository.MovieRepository
+	cache cache.Cache
 }
 
-func NewMovieService(repo repository.MovieRepository) *MovieService {
+func NewMovieService(repo repository.MovieRepository, cache cache.Cache) *MovieService {
 	return &MovieService{
-		repo: repo,
+		repo:  repo,
+		cache: cache,
 	}
 }
 
-func (s *MovieService) Create(ctx context.Context, payload models.CreateMovieRequest) error {
+func (s *MovieService) Create(ctx context.Context, payload models.CreateMovieRequest) (*models.Movie, error) {
 	ctx, span := o11y.Tracer().Start(ctx, "MovieService.Create")
 	defer span.End()
 
-	err := s.repo.Create(ctx, payload)
+	movie, err := s.repo.Create(ctx, payload)
 	if err != nil {
-		return fmt.Errorf("failed to create movie: %w", err)
+		return nil, fmt.Errorf("failed to create movie: %w", err)
 	}
-	return nil
+	s.invalidate(ctx, models.MovieListCacheTag)
+	return movie, nil
 }
 
 func (s *MovieService) GetById(ctx context.Context, id int) (*models.Movie, error) {
@@ -49,24 +92,281 @@ func (s *MovieService) GetById(ctx context.Context, id int) (*models.Movie, erro
 	return movie, nil
 }
 
-func (s *MovieService) GetList(ctx context.Context, page, limit int) (*models.GetMovieList, error) {
+func (s *MovieService) GetList(ctx context.Context, filter models.MovieFilter) (*models.GetMovieList, error) {
 	ctx, span := o11y.Tracer().Start(ctx, "MovieService.GetList")
 	defer span.End()
 
-	movies, err := s.repo.GetList(ctx, page, limit)
+	movies, err := s.repo.GetList(ctx, filter)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get movie list: %w", err)
 	}
 	return movies, nil
 }
 
-func (s *MovieService) Delete(ctx context.Context, id int) error {
+func (s *MovieService) Search(ctx context.Context, search models.MovieSearch) (*models.MovieSearchResults, error) {
+	ctx, span := o11y.Tracer().Start(ctx, "MovieService.Search")
+	defer span.End()
+
+	results, err := s.repo.Search(ctx, search)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search movies: %w", err)
+	}
+	return results, nil
+}
+
+// Suggest returns autocomplete suggestions for a title prefix, served from the cache when a
+// recent answer for the same prefix is cached. Cache failures fall through to the database.
+func (s *MovieService) Suggest(ctx context.Context, suggest models.MovieSuggest) (*models.MovieSuggestions, error) {
+	ctx, span := o11y.Tracer().Start(ctx, "MovieService.Suggest")
+	defer span.End()
+
+	key := fmt.Sprintf("movies:suggest:%d:%s", suggest.Limit, strings.ToLower(suggest.Prefix))
+	if cached, err := s.cache.Get(ctx, key); err == nil {
+		var suggestions models.MovieSuggestions
+		if err := json.Unmarshal(cached, &suggestions); err == nil {
+			return &suggestions, nil
+		}
+	}
+
+	suggestions, err := s.repo.Suggest(ctx, suggest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to suggest movies: %w", err)
+	}
+
+	if data, err := json.Marshal(suggestions); err == nil {
+		_ = s.cache.Set(ctx, key, data, suggestTTL, models.MovieListCacheTag)
+	}
+	return suggestions, nil
+}
+
+// Update replaces the movie. A non-zero version must match the movie's current version.
+func (s *MovieService) Update(ctx context.Context, id int, payload models.UpdateMovieRequest, version int64) (*models.Movie, error) {
+	ctx, span := o11y.Tracer().Start(ctx, "MovieService.Update")
+	defer span.End()
+
+	movie, err := s.repo.Update(ctx, id, payload, version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update movie: %w", err)
+	}
+	s.invalidate(ctx, models.MovieCacheTag(int64(id)), models.MovieListCacheTag)
+	return movie, nil
+}
+
+// Patch applies a JSON Merge Patch (RFC 7396) to the movie and stores the result.
+// The merged document must be a valid UpdateMovieRequest; failures are returned as validation.Errors.
+// A non-zero version must match the movie's current version. The result is stored only if the
+// movie has not changed since it was read; without a version, the patch is then applied again
+// to the newer movie, so concurrent patches never undo each other. An unconditional patch that
+// keeps losing the race fails with errs.ErrConflict, as the caller sent no precondition.
+func (s *MovieService) Patch(ctx context.Context, id int, patch []byte, version int64) (*models.Movie, error) {
+	ctx, span := o11y.Tracer().Start(ctx, "MovieService.Patch")
+	defer span.End()
+
+	for attempt := 1; ; attempt++ {
+		movie, err := s.patch(ctx, id, patch, version)
+		if version == 0 && errors.Is(err, errs.ErrPreconditionFailed) {
+			if attempt < maxPatchAttempts {
+				continue
+			}
+			return nil, errs.Conflict("movie %d kept changing while it was patched, try again", id)
+		}
+		if err != nil {
+			return nil, err
+		}
+		s.invalidate(ctx, models.MovieCacheTag(int64(id)), models.MovieListCacheTag)
+		return movie, nil
+	}
+}
+
+// patch makes one read-merge-write attempt of Patch.
+func (s *MovieService) patch(ctx context.Context, id int, patch []byte, version int64) (*models.Movie, error) {
+	current, err := s.repo.GetById(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get movie by id: %w", err)
+	}
+	if version != 0 && current.Version != version {
+		return nil, errs.PreconditionFailed("movie %d is at version %d, not %d", id, current.Version, version)
+	}
+
+	doc, err := json.Marshal(models.UpdateMovieRequest{
+		Title:       current.Title,
+		Description: current.Description,
+		ReleaseYear: current.ReleaseYear,
+		Genre:       current.Genre,
+		Director:    current.Director,
+		Rating:      current.Rating,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode movie: %w", err)
+	}
+
+	merged, err := helpers.MergePatch(doc, patch)
+	if err != nil {
+		return nil, errs.Wrap(errs.ErrValidation, err, "invalid merge patch")
+	}
+
+	var payload models.UpdateMovieRequest
+	if _, err := validation.Decode(merged, &payload); err != nil {
+		return nil, err
+	}
+
+	movie, err := s.repo.Update(ctx, id, payload, current.Version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update movie: %w", err)
+	}
+	return movie, nil
+}
+
+// Delete moves the movie to the trash. A non-zero version must match the movie's current version.
+func (s *MovieService) Delete(ctx context.Context, id int, version int64) error {
 	ctx, span := o11y.Tracer().Start(ctx, "MovieService.Delete")
 	defer span.End()
 
-	err := s.repo.Delete(ctx, id)
+	err := s.repo.Delete(ctx, id, version)
 	if err != nil {
 		return fmt.Errorf("failed to delete movie: %w", err)
 	}
+	s.invalidate(ctx, models.MovieCacheTag(int64(id)), models.MovieListCacheTag)
 	return nil
 }
+
+// Trash lists the deleted movies, most recently deleted first.
+func (s *MovieService) Trash(ctx context.Context, page, limit int) (*models.MovieTrash, error) {
+	ctx, span := o11y.Tracer().Start(ctx, "MovieService.Trash")
+	defer span.End()
+
+	trash, err := s.repo.Trash(ctx, page, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deleted movies: %w", err)
+	}
+	return trash, nil
+}
+
+// Restore takes a deleted movie out of the trash.
+func (s *MovieService) Restore(ctx context.Context, id int) (*models.Movie, error) {
+	ctx, span := o11y.Tracer().Start(ctx, "MovieService.Restore")
+	defer span.End()
+
+	movie, err := s.repo.Restore(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore movie: %w", err)
+	}
+	s.invalidate(ctx, models.MovieCacheTag(int64(id)), models.MovieListCacheTag)
+	return movie, nil
+}
+
+// Purge permanently deletes the movies that have been in the trash for longer than retention.
+func (s *MovieService) Purge(ctx context.Context, retention time.Duration) (int64, error) {
+	ctx, span := o11y.Tracer().Start(ctx, "MovieService.Purge")
+	defer span.End()
+
+	purged, err := s.repo.Purge(ctx, time.Now().Add(-retention))
+	if err != nil {
+		return purged, fmt.Errorf("failed to purge deleted movies: %w", err)
+	}
+	return purged, nil
+}
+
+// Import validates every movie of src and bulk inserts the valid ones in a single transaction.
+// Invalid rows are skipped and listed in the report. A dry run goes through the same inserts so
+// database constraints are checked too, then rolls the transaction back.
+func (s *MovieService) Import(ctx context.Context, src MovieSource, dryRun bool) (*models.ImportReport, error) {
+	ctx, span := o11y.Tracer().Start(ctx, "MovieService.Import")
+	defer span.End()
+
+	imp, err := s.repo.BeginImport(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to import movies: %w", err)
+	}
+	defer imp.Rollback(ctx)
+
+	report := &models.ImportReport{DryRun: dryRun, Errors: []models.ImportRowError{}}
+	batch := make([]models.CreateMovieRequest, 0, importBatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		n, err := imp.Copy(ctx, batch)
+		if err != nil {
+			return fmt.Errorf("failed to import movies: %w", err)
+		}
+		report.Imported += n
+		batch = batch[:0]
+		return nil
+	}
+
+	for row := 1; ; row++ {
+		movie, err := src.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		report.Total = row
+		if err == nil {
+			_, err = validation.Validate(&movie)
+		}
+		if err != nil {
+			if !errors.Is(err, errs.ErrValidation) {
+				return nil, fmt.Errorf("failed to read import row %d: %w", row, err)
+			}
+			report.Failed++
+			if len(report.Errors) < maxImportErrors {
+				report.Errors = append(report.Errors, importRowError(row, err))
+			}
+			continue
+		}
+
+		batch = append(batch, movie)
+		if len(batch) == importBatchSize {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	if dryRun {
+		return report, nil
+	}
+	if err := imp.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to import movies: %w", err)
+	}
+	if report.Imported > 0 {
+		s.invalidate(ctx, models.MovieListCacheTag)
+	}
+	return report, nil
+}
+
+// Export streams every movie to fn in id order.
+func (s *MovieService) Export(ctx context.Context, fn func(models.Movie) error) error {
+	ctx, span := o11y.Tracer().Start(ctx, "MovieService.Export")
+	defer span.End()
+
+	if err := s.repo.Export(ctx, fn); err != nil {
+		return fmt.Errorf("failed to export movies: %w", err)
+	}
+	return nil
+}
+
+// invalidate evicts the cached responses tagged with any of the tags. It runs after the
+// write has been committed; a failure only leaves stale entries until their TTL, so it is
+// logged rather than returned.
+func (s *MovieService) invalidate(ctx context.Context, tags ...string) {
+	if err := s.cache.DeleteByTag(ctx, tags...); err != nil {
+		log.Warn().Err(err).Strs("tags", tags).Msg("failed to invalidate cached movies")
+	}
+}
+
+// importRowError describes a rejected import row.
+func importRowError(row int, err error) models.ImportRowError {
+	var fieldErrs validation.Errors
+	if errors.As(err, &fieldErrs) {
+		return models.ImportRowError{Row: row, Detail: "The row is invalid.", Errors: fieldErrs}
+	}
+	detail := errs.Message(err)
+	if detail == "" {
+		detail = "The row is invalid."
+	}
+	return models.ImportRowError{Row: row, Detail: detail}
+}
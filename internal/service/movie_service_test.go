@@ -5,20 +5,26 @@ package service_test
 import (
 	"context"
 	"errors"
+	"io"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
+	"github.com/mexirica/chi-template/internal/cache"
+	"github.com/mexirica/chi-template/internal/db/repository"
+	"github.com/mexirica/chi-template/internal/errs"
 	"github.com/mexirica/chi-template/internal/models"
 	"github.com/mexirica/chi-template/internal/service"
-	mock_service "github.com/mexirica/chi-template/internal/service"
+	"github.com/mexirica/chi-template/internal/validation"
 )
 
 func TestMovieService_Create(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
-	mockRepo := mock_service.NewMockService(ctrl)
-	svc := service.NewMovieService(mockRepo)
+	mockRepo := repository.NewMockMovieRepository(ctrl)
+	svc := service.NewMovieService(mockRepo, cache.NewMemory(100))
 
 	payload := models.CreateMovieRequest{
 		Title:       "Test Movie",
@@ -29,11 +35,14 @@ func TestMovieService_Create(t *testing.T) {
 		Rating:      8.5,
 	}
 
-	mockRepo.EXPECT().Create(gomock.Any(), payload).Return(nil)
+	mockRepo.EXPECT().Create(gomock.Any(), payload).Return(&models.Movie{ID: 42, Title: payload.Title, Version: 1}, nil)
 
-	err := svc.Create(context.Background(), payload)
+	movie, err := svc.Create(context.Background(), payload)
 	if err != nil {
-		t.Errorf("expected no error, got %v", err)
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if movie.ID != 42 {
+		t.Errorf("expected the created movie, got %+v", movie)
 	}
 }
 
@@ -41,8 +50,8 @@ func TestMovieService_GetById(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
-	mockRepo := mock_service.NewMockService(ctrl)
-	svc := service.NewMovieService(mockRepo)
+	mockRepo := repository.NewMockMovieRepository(ctrl)
+	svc := service.NewMovieService(mockRepo, cache.NewMemory(100))
 
 	movie := &models.Movie{ID: 1, Title: "Test Movie"}
 	mockRepo.EXPECT().GetById(gomock.Any(), 1).Return(movie, nil)
@@ -60,13 +69,14 @@ func TestMovieService_GetList(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
-	mockRepo := mock_service.NewMockService(ctrl)
-	svc := service.NewMovieService(mockRepo)
+	mockRepo := repository.NewMockMovieRepository(ctrl)
+	svc := service.NewMovieService(mockRepo, cache.NewMemory(100))
 
 	movieList := &models.GetMovieList{Movies: []models.GetMovieResponse{{ID: 1, Title: "Test Movie"}}}
-	mockRepo.EXPECT().GetList(gomock.Any(), 1, 10).Return(movieList, nil)
+	filter := models.MovieFilter{Genres: []string{"Action"}, Sort: []models.SortField{{Field: "rating", Desc: true}}, Page: 1, Limit: 10}
+	mockRepo.EXPECT().GetList(gomock.Any(), filter).Return(movieList, nil)
 
-	result, err := svc.GetList(context.Background(), 1, 10)
+	result, err := svc.GetList(context.Background(), filter)
 	if err != nil {
 		t.Errorf("expected no error, got %v", err)
 	}
@@ -75,16 +85,91 @@ func TestMovieService_GetList(t *testing.T) {
 	}
 }
 
+func TestMovieService_Search(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockMovieRepository(ctrl)
+	svc := service.NewMovieService(mockRepo, cache.NewMemory(100))
+
+	search := models.MovieSearch{Query: "space opera", Page: 1, Limit: 10}
+	results := &models.MovieSearchResults{Results: []models.MovieSearchResult{{
+		GetMovieResponse: models.GetMovieResponse{ID: 1, Title: "Test Movie"},
+		Rank:             0.6,
+		Headline:         "a <mark>space</mark> <mark>opera</mark>",
+	}}}
+	mockRepo.EXPECT().Search(gomock.Any(), search).Return(results, nil)
+
+	result, err := svc.Search(context.Background(), search)
+	if err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	if len(result.Results) != 1 || result.Results[0].Rank != 0.6 {
+		t.Errorf("unexpected results %+v", result.Results)
+	}
+}
+
+func TestMovieService_Suggest(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockMovieRepository(ctrl)
+	svc := service.NewMovieService(mockRepo, cache.NewMemory(100))
+
+	suggest := models.MovieSuggest{Prefix: "matrx", Limit: 5}
+	suggestions := &models.MovieSuggestions{Suggestions: []models.MovieSuggestion{{ID: 1, Title: "The Matrix", Similarity: 0.5}}}
+	mockRepo.EXPECT().Suggest(gomock.Any(), suggest).Return(suggestions, nil)
+
+	result, err := svc.Suggest(context.Background(), suggest)
+	if err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	if len(result.Suggestions) != 1 || result.Suggestions[0].Title != "The Matrix" {
+		t.Errorf("unexpected suggestions %+v", result.Suggestions)
+	}
+
+	// The repository expects a single call: the second one is served from the cache.
+	cached, err := svc.Suggest(context.Background(), models.MovieSuggest{Prefix: "MATRX", Limit: 5})
+	if err != nil || len(cached.Suggestions) != 1 {
+		t.Errorf("expected cached suggestions, got %+v, %v", cached, err)
+	}
+}
+
+func TestMovieService_DeleteInvalidatesCache(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockMovieRepository(ctrl)
+	c := cache.NewMemory(100)
+	svc := service.NewMovieService(mockRepo, c)
+
+	ctx := context.Background()
+	_ = c.Set(ctx, "GET./movies/1.", []byte("{}"), time.Hour, models.MovieCacheTag(1))
+	_ = c.Set(ctx, "GET./movies/2.", []byte("{}"), time.Hour, models.MovieCacheTag(2))
+	_ = c.Set(ctx, "GET./movies/list.", []byte("[]"), time.Hour, models.MovieListCacheTag)
+
+	mockRepo.EXPECT().Delete(gomock.Any(), 1, int64(0)).Return(nil)
+	if err := svc.Delete(ctx, 1, 0); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	for key, want := range map[string]bool{"GET./movies/1.": false, "GET./movies/2.": true, "GET./movies/list.": false} {
+		if _, err := c.Get(ctx, key); (err == nil) != want {
+			t.Errorf("%s cached = %v, want %v", key, err == nil, want)
+		}
+	}
+}
+
 func TestMovieService_Delete(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
-	mockRepo := mock_service.NewMockService(ctrl)
-	svc := service.NewMovieService(mockRepo)
+	mockRepo := repository.NewMockMovieRepository(ctrl)
+	svc := service.NewMovieService(mockRepo, cache.NewMemory(100))
 
-	mockRepo.EXPECT().Delete(gomock.Any(), 1).Return(nil)
+	mockRepo.EXPECT().Delete(gomock.Any(), 1, int64(0)).Return(nil)
 
-	err := svc.Delete(context.Background(), 1)
+	err := svc.Delete(context.Background(), 1, 0)
 	if err != nil {
 		t.Errorf("expected no error, got %v", err)
 	}
@@ -94,14 +179,354 @@ func TestMovieService_Create_Error(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
-	mockRepo := mock_service.NewMockService(ctrl)
-	svc := service.NewMovieService(mockRepo)
+	mockRepo := repository.NewMockMovieRepository(ctrl)
+	svc := service.NewMovieService(mockRepo, cache.NewMemory(100))
 
 	payload := models.CreateMovieRequest{Title: "Error Movie"}
-	mockRepo.EXPECT().Create(gomock.Any(), payload).Return(errors.New("db error"))
+	mockRepo.EXPECT().Create(gomock.Any(), payload).Return(nil, errors.New("db error"))
 
-	err := svc.Create(context.Background(), payload)
+	_, err := svc.Create(context.Background(), payload)
 	if err == nil {
 		t.Error("expected error, got nil")
 	}
 }
+
+func TestMovieService_Update(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockMovieRepository(ctrl)
+	svc := service.NewMovieService(mockRepo, cache.NewMemory(100))
+
+	payload := models.UpdateMovieRequest{
+		Title:       "Updated Movie",
+		Description: "An updated movie",
+		ReleaseYear: 2024,
+		Genre:       []string{"Drama"},
+		Director:    "Jane Doe",
+		Rating:      7.5,
+	}
+	mockRepo.EXPECT().Update(gomock.Any(), 1, payload, int64(3)).Return(&models.Movie{ID: 1, Title: "Updated Movie"}, nil)
+
+	result, err := svc.Update(context.Background(), 1, payload, 3)
+	if err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	if result.Title != "Updated Movie" {
+		t.Errorf("expected title Updated Movie, got %s", result.Title)
+	}
+}
+
+func TestMovieService_Patch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockMovieRepository(ctrl)
+	svc := service.NewMovieService(mockRepo, cache.NewMemory(100))
+
+	current := &models.Movie{
+		ID:          1,
+		Title:       "Test Movie",
+		Description: "A test movie",
+		ReleaseYear: 2024,
+		Genre:       []string{"Action"},
+		Director:    "John Doe",
+		Rating:      8.5,
+		Version:     4,
+	}
+	expected := models.UpdateMovieRequest{
+		Title:       "Test Movie",
+		Description: "A test movie",
+		ReleaseYear: 2024,
+		Genre:       []string{"Action", "Comedy"},
+		Director:    "John Doe",
+		Rating:      9,
+	}
+	mockRepo.EXPECT().GetById(gomock.Any(), 1).Return(current, nil)
+	mockRepo.EXPECT().Update(gomock.Any(), 1, expected, int64(4)).Return(&models.Movie{ID: 1, Rating: 9}, nil)
+
+	result, err := svc.Patch(context.Background(), 1, []byte(`{"genre":["Action","Comedy"],"rating":9}`), 0)
+	if err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	if result.Rating != 9 {
+		t.Errorf("expected rating 9, got %v", result.Rating)
+	}
+}
+
+func TestMovieService_Patch_NotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockMovieRepository(ctrl)
+	svc := service.NewMovieService(mockRepo, cache.NewMemory(100))
+
+	mockRepo.EXPECT().GetById(gomock.Any(), 1).Return(nil, errs.NotFound("movie %d not found", 1))
+
+	_, err := svc.Patch(context.Background(), 1, []byte(`{"rating":9}`), 0)
+	if !errors.Is(err, errs.ErrNotFound) {
+		t.Errorf("expected not found error, got %v", err)
+	}
+}
+
+func TestMovieService_Patch_ValidationError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockMovieRepository(ctrl)
+	svc := service.NewMovieService(mockRepo, cache.NewMemory(100))
+
+	mockRepo.EXPECT().GetById(gomock.Any(), 1).Return(&models.Movie{ID: 1, Title: "Test Movie"}, nil)
+
+	_, err := svc.Patch(context.Background(), 1, []byte(`{"title":null}`), 0)
+	var errList validation.Errors
+	if !errors.As(err, &errList) {
+		t.Fatalf("expected validation errors, got %v", err)
+	}
+	if !errors.Is(err, errs.ErrValidation) {
+		t.Errorf("expected validation error kind, got %v", err)
+	}
+}
+
+func TestMovieService_Delete_NotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockMovieRepository(ctrl)
+	svc := service.NewMovieService(mockRepo, cache.NewMemory(100))
+
+	mockRepo.EXPECT().Delete(gomock.Any(), 1, int64(0)).Return(errs.NotFound("movie %d not found", 1))
+
+	err := svc.Delete(context.Background(), 1, 0)
+	if !errors.Is(err, errs.ErrNotFound) {
+		t.Errorf("expected not found error, got %v", err)
+	}
+}
+
+// sliceSource is a service.MovieSource over fixed rows.
+type sliceSource struct {
+	rows []models.CreateMovieRequest
+	errs []error
+}
+
+func (s *sliceSource) Next() (models.CreateMovieRequest, error) {
+	if len(s.rows) == 0 {
+		return models.CreateMovieRequest{}, io.EOF
+	}
+	row, err := s.rows[0], s.errs[0]
+	s.rows, s.errs = s.rows[1:], s.errs[1:]
+	return row, err
+}
+
+func TestMovieService_Import(t *testing.T) {
+	valid := models.CreateMovieRequest{
+		Title:       "Test Movie",
+		Description: "A test movie",
+		ReleaseYear: 2024,
+		Genre:       []string{"Action"},
+		Director:    "John Doe",
+		Rating:      8.5,
+	}
+	oversized := valid
+	oversized.Title = strings.Repeat("x", 256)
+	oversized.Genre = []string{strings.Repeat("x", 51)}
+	oversized.Rating = 100
+
+	for _, dryRun := range []bool{false, true} {
+		ctrl := gomock.NewController(t)
+		mockRepo := repository.NewMockMovieRepository(ctrl)
+		mockImport := repository.NewMockMovieImport(ctrl)
+		svc := service.NewMovieService(mockRepo, cache.NewMemory(100))
+
+		src := &sliceSource{
+			rows: []models.CreateMovieRequest{valid, {Title: "Missing fields"}, {}, oversized, valid},
+			errs: []error{nil, nil, errs.Validation("malformed CSV row"), nil, nil},
+		}
+
+		mockRepo.EXPECT().BeginImport(gomock.Any()).Return(mockImport, nil)
+		mockImport.EXPECT().Copy(gomock.Any(), []models.CreateMovieRequest{valid, valid}).Return(int64(2), nil)
+		if !dryRun {
+			mockImport.EXPECT().Commit(gomock.Any()).Return(nil)
+		}
+		mockImport.EXPECT().Rollback(gomock.Any()).Return(nil)
+
+		report, err := svc.Import(context.Background(), src, dryRun)
+		if err != nil {
+			t.Fatalf("dry run %v: expected no error, got %v", dryRun, err)
+		}
+		if report.Total != 5 || report.Imported != 2 || report.Failed != 3 || report.DryRun != dryRun {
+			t.Errorf("dry run %v: unexpected report %+v", dryRun, report)
+		}
+		if len(report.Errors) != 3 || report.Errors[0].Row != 2 || len(report.Errors[0].Errors) == 0 || report.Errors[1].Detail != "malformed CSV row" {
+			t.Errorf("dry run %v: unexpected row errors %+v", dryRun, report.Errors)
+		}
+		if len(report.Errors) == 3 && (report.Errors[2].Row != 4 || len(report.Errors[2].Errors) != 3) {
+			t.Errorf("dry run %v: expected the title, genre and rating of row 4 to be reported, got %+v", dryRun, report.Errors[2])
+		}
+		ctrl.Finish()
+	}
+}
+
+func TestMovieService_Patch_StaleVersion(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockMovieRepository(ctrl)
+	svc := service.NewMovieService(mockRepo, cache.NewMemory(100))
+
+	mockRepo.EXPECT().GetById(gomock.Any(), 1).Return(&models.Movie{ID: 1, Title: "Test Movie", Version: 5}, nil)
+
+	_, err := svc.Patch(context.Background(), 1, []byte(`{"rating":9}`), 4)
+	if !errors.Is(err, errs.ErrPreconditionFailed) {
+		t.Errorf("expected precondition failed error, got %v", err)
+	}
+}
+
+func TestMovieService_Patch_ZeroRating(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockMovieRepository(ctrl)
+	svc := service.NewMovieService(mockRepo, cache.NewMemory(100))
+
+	mockRepo.EXPECT().GetById(gomock.Any(), 1).Return(&models.Movie{
+		ID:          1,
+		Title:       "Test Movie",
+		Description: "A test movie",
+		ReleaseYear: 2024,
+		Genre:       []string{"Action"},
+		Director:    "John Doe",
+		Rating:      0,
+		Version:     1,
+	}, nil)
+	mockRepo.EXPECT().Update(gomock.Any(), 1, gomock.Any(), int64(1)).DoAndReturn(
+		func(_ context.Context, _ int, payload models.UpdateMovieRequest, _ int64) (*models.Movie, error) {
+			if payload.Title != "Renamed" || payload.Rating != 0 {
+				t.Errorf("expected the title patched and the rating kept, got %+v", payload)
+			}
+			return &models.Movie{ID: 1, Title: "Renamed", Version: 2}, nil
+		})
+
+	if _, err := svc.Patch(context.Background(), 1, []byte(`{"title":"Renamed"}`), 0); err != nil {
+		t.Errorf("expected a movie rated 0 to be patchable, got %v", err)
+	}
+}
+
+func TestMovieService_Patch_RetriesLostRace(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockMovieRepository(ctrl)
+	svc := service.NewMovieService(mockRepo, cache.NewMemory(100))
+
+	movie := func(version int64, director string) *models.Movie {
+		return &models.Movie{
+			ID:          1,
+			Title:       "Test Movie",
+			Description: "A test movie",
+			ReleaseYear: 2024,
+			Genre:       []string{"Action"},
+			Director:    director,
+			Rating:      8.5,
+			Version:     version,
+		}
+	}
+	// Another write lands between the first read and write; the patch is merged onto it.
+	gomock.InOrder(
+		mockRepo.EXPECT().GetById(gomock.Any(), 1).Return(movie(1, "John Doe"), nil),
+		mockRepo.EXPECT().Update(gomock.Any(), 1, gomock.Any(), int64(1)).Return(nil, errs.PreconditionFailed("stale")),
+		mockRepo.EXPECT().GetById(gomock.Any(), 1).Return(movie(2, "Jane Doe"), nil),
+		mockRepo.EXPECT().Update(gomock.Any(), 1, gomock.Any(), int64(2)).DoAndReturn(
+			func(_ context.Context, _ int, payload models.UpdateMovieRequest, _ int64) (*models.Movie, error) {
+				if payload.Director != "Jane Doe" || payload.Rating != 9 {
+					t.Errorf("expected the patch merged onto the newer movie, got %+v", payload)
+				}
+				return movie(3, "Jane Doe"), nil
+			}),
+	)
+
+	if _, err := svc.Patch(context.Background(), 1, []byte(`{"rating":9}`), 0); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+}
+
+func TestMovieService_Patch_KeepsLosingRace(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockMovieRepository(ctrl)
+	svc := service.NewMovieService(mockRepo, cache.NewMemory(100))
+
+	mockRepo.EXPECT().GetById(gomock.Any(), 1).Return(&models.Movie{
+		ID:          1,
+		Title:       "Test Movie",
+		Description: "A test movie",
+		ReleaseYear: 2024,
+		Genre:       []string{"Action"},
+		Director:    "John Doe",
+		Rating:      8.5,
+		Version:     1,
+	}, nil).Times(3)
+	mockRepo.EXPECT().Update(gomock.Any(), 1, gomock.Any(), int64(1)).Return(nil, errs.PreconditionFailed("stale")).Times(3)
+
+	// Without If-Match, the caller sent no precondition that could have failed.
+	_, err := svc.Patch(context.Background(), 1, []byte(`{"rating":9}`), 0)
+	if !errors.Is(err, errs.ErrConflict) {
+		t.Errorf("expected conflict error, got %v", err)
+	}
+}
+
+func TestMovieService_Restore(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockMovieRepository(ctrl)
+	c := cache.NewMemory(100)
+	svc := service.NewMovieService(mockRepo, c)
+
+	ctx := context.Background()
+	_ = c.Set(ctx, "GET./movies/list.", []byte("[]"), time.Hour, models.MovieListCacheTag)
+
+	mockRepo.EXPECT().Restore(gomock.Any(), 1).Return(&models.Movie{ID: 1, Title: "Test Movie", Version: 3}, nil)
+	movie, err := svc.Restore(ctx, 1)
+	if err != nil || movie.ID != 1 {
+		t.Fatalf("expected the restored movie, got %+v, %v", movie, err)
+	}
+	if _, err := c.Get(ctx, "GET./movies/list."); !errors.Is(err, cache.ErrMiss) {
+		t.Errorf("expected cached listings to be invalidated, got %v", err)
+	}
+}
+
+func TestMovieService_Restore_NotDeleted(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockMovieRepository(ctrl)
+	svc := service.NewMovieService(mockRepo, cache.NewMemory(100))
+
+	mockRepo.EXPECT().Restore(gomock.Any(), 1).Return(nil, errs.Conflict("movie %d is not deleted", 1))
+	if _, err := svc.Restore(context.Background(), 1); !errors.Is(err, errs.ErrConflict) {
+		t.Errorf("expected conflict error, got %v", err)
+	}
+}
+
+func TestMovieService_Purge(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockMovieRepository(ctrl)
+	svc := service.NewMovieService(mockRepo, cache.NewMemory(100))
+
+	retention := 30 * 24 * time.Hour
+	mockRepo.EXPECT().Purge(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, before time.Time) (int64, error) {
+		if want := time.Now().Add(-retention); before.Sub(want).Abs() > time.Minute {
+			t.Errorf("expected movies deleted before %v to be purged, got %v", want, before)
+		}
+		return 12, nil
+	})
+
+	purged, err := svc.Purge(context.Background(), retention)
+	if err != nil || purged != 12 {
+		t.Errorf("expected 12 purged movies, got %d, %v", purged, err)
+	}
+}
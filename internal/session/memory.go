@@ -0,0 +1,159 @@
+package session
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval bounds how often expired entries are dropped from a Memory store.
+const sweepInterval = time.Minute
+
+type memorySession struct {
+	Session
+	current string
+	expires time.Time
+}
+
+type memoryToken struct {
+	session string
+	expires time.Time
+}
+
+type memoryFailures struct {
+	count   int64
+	expires time.Time
+}
+
+// Memory is an in-process Store. Sessions do not survive a restart and are not shared
+// between replicas, so it is meant for tests and local development.
+type Memory struct {
+	mu        sync.Mutex
+	sessions  map[string]memorySession
+	tokens    map[string]memoryToken
+	failures  map[string]memoryFailures
+	lastSweep time.Time
+}
+
+var _ Store = (*Memory)(nil)
+
+// NewMemory returns an empty in-memory store.
+func NewMemory() *Memory {
+	return &Memory{
+		sessions: map[string]memorySession{},
+		tokens:   map[string]memoryToken{},
+		failures: map[string]memoryFailures{},
+	}
+}
+
+func (m *Memory) Create(_ context.Context, s Session, token string, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.sweep(now)
+	m.sessions[s.ID] = memorySession{Session: s, current: token, expires: now.Add(ttl)}
+	m.tokens[token] = memoryToken{session: s.ID, expires: now.Add(ttl)}
+	return nil
+}
+
+func (m *Memory) Rotate(_ context.Context, token, next string, ttl time.Duration) (*Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	s, ok := m.session(token, now)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if s.current != token {
+		delete(m.sessions, s.ID)
+		return nil, ErrReused
+	}
+
+	s.current, s.expires = next, now.Add(ttl)
+	m.sessions[s.ID] = s
+	m.tokens[next] = memoryToken{session: s.ID, expires: now.Add(ttl)}
+	return &s.Session, nil
+}
+
+func (m *Memory) Delete(_ context.Context, token string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if s, ok := m.session(token, time.Now()); ok {
+		delete(m.sessions, s.ID)
+	}
+	delete(m.tokens, token)
+	return nil
+}
+
+func (m *Memory) Fail(_ context.Context, key string, window time.Duration) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.sweep(now)
+	f, ok := m.failures[key]
+	if !ok || !now.Before(f.expires) {
+		f = memoryFailures{expires: now.Add(window)}
+	}
+	f.count++
+	m.failures[key] = f
+	return f.count, nil
+}
+
+func (m *Memory) Failures(_ context.Context, key string) (int64, time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f, ok := m.failures[key]
+	if !ok || !time.Now().Before(f.expires) {
+		return 0, 0, nil
+	}
+	return f.count, time.Until(f.expires), nil
+}
+
+func (m *Memory) Reset(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.failures, key)
+	return nil
+}
+
+// session returns the live session of the token, which need not be its current token.
+func (m *Memory) session(token string, now time.Time) (memorySession, bool) {
+	t, ok := m.tokens[token]
+	if !ok || !now.Before(t.expires) {
+		return memorySession{}, false
+	}
+	s, ok := m.sessions[t.session]
+	if !ok || !now.Before(s.expires) {
+		return memorySession{}, false
+	}
+	return s, true
+}
+
+// sweep drops the expired entries, at most once per sweepInterval.
+func (m *Memory) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < sweepInterval {
+		return
+	}
+	m.lastSweep = now
+	for id, s := range m.sessions {
+		if !now.Before(s.expires) {
+			delete(m.sessions, id)
+		}
+	}
+	for token, t := range m.tokens {
+		if !now.Before(t.expires) {
+			delete(m.tokens, token)
+		}
+	}
+	for key, f := range m.failures {
+		if !now.Before(f.expires) {
+			delete(m.failures, key)
+		}
+	}
+}
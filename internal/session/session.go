@@ -0,0 +1,50 @@
+// Package session defines the store of user sessions and failed logins, with an in-memory
+// implementation. The Redis implementation lives in internal/redis.
+//
+// A session is a chain of refresh tokens: each refresh replaces the current token with the
+// next one. Presenting a token that was already replaced means it leaked, so the whole
+// session ends and every token of the chain stops working.
+package session
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned for tokens of unknown, expired or ended sessions.
+	ErrNotFound = errors.New("session not found")
+	// ErrReused is returned by Rotate for a token that was already replaced. The session is
+	// ended.
+	ErrReused = errors.New("refresh token reused")
+)
+
+// Session is a user's login, from its first refresh token to its logout.
+type Session struct {
+	ID        string
+	UserID    int64
+	CreatedAt time.Time
+}
+
+// Store keeps the sessions and counts the failed logins of accounts. Tokens are stored as
+// given: callers pass a digest of the refresh token rather than the token itself.
+type Store interface {
+	// Create starts the session with token as its current token, for ttl.
+	Create(ctx context.Context, s Session, token string, ttl time.Duration) error
+	// Rotate replaces the current token of the session with next and extends the session by
+	// ttl. It returns ErrNotFound or ErrReused.
+	Rotate(ctx context.Context, token, next string, ttl time.Duration) (*Session, error)
+	// Delete ends the session of the token, if any.
+	Delete(ctx context.Context, token string) error
+
+	// Fail records a failed login under key and returns the failures recorded since the
+	// first one, which are forgotten window after it. The count is atomic, so callers can
+	// record an attempt before checking it and Reset once it succeeded.
+	Fail(ctx context.Context, key string, window time.Duration) (int64, error)
+	// Failures returns the failures recorded under key and how long until they are
+	// forgotten.
+	Failures(ctx context.Context, key string) (int64, time.Duration, error)
+	// Reset forgets the failures recorded under key.
+	Reset(ctx context.Context, key string) error
+}
@@ -1,8 +1,17 @@
 // Package types defines shared types and response structures for the application.
 package types
 
-type JsonResponse struct {
-	Error   bool   `json:"error"`
-	Message string `json:"message"`
-	Data    any    `json:"data,omitresponse"`
+import "github.com/mexirica/chi-template/internal/validation"
+
+// Problem is an RFC 9457 Problem Details object, served as application/problem+json.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// TraceID is an extension member holding the OpenTelemetry trace of the request.
+	TraceID string `json:"trace_id,omitempty"`
+	// Errors is an extension member listing the fields that failed validation.
+	Errors []validation.ValidationErrorResponse `json:"errors,omitempty"`
 }
@@ -2,14 +2,17 @@
 package validation
 
 import (
+	"bytes"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/mexirica/chi-template/internal/errs"
 )
 
-var validate *validator.Validate
+var validate = validator.New()
 
 // Init initializes the validator instance for struct validation.
 func Init() {
@@ -22,30 +25,74 @@ type ValidationErrorResponse struct {
 	Value       string `json:"value,omitempty"`
 }
 
+// Errors is returned when a payload fails validation and carries every failed field.
+type Errors []ValidationErrorResponse
+
+func (e Errors) Error() string {
+	return "validation failed"
+}
+
+// Is reports Errors as an errs.ErrValidation domain error.
+func (e Errors) Is(target error) bool {
+	return target == errs.ErrValidation
+}
+
 // BindAndValidate decodes the request body into the destination struct and validates it.
 // Returns a slice of validation errors and an error if validation fails.
 func BindAndValidate(r *http.Request, dst any) ([]ValidationErrorResponse, error) {
 	if err := json.NewDecoder(r.Body).Decode(dst); err != nil {
-		return nil, err
+		return nil, errs.Wrap(errs.ErrValidation, err, "malformed request body")
 	}
 
-	err := validate.Struct(dst)
+	return Validate(dst)
+}
+
+// Decode strictly decodes a JSON document into the destination struct and validates it.
+// Unknown fields and type mismatches are reported as validation errors.
+func Decode(data []byte, dst any) ([]ValidationErrorResponse, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(dst); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			errList := Errors{{FailedField: typeErr.Field, Tag: "type", Value: typeErr.Type.String()}}
+			return errList, errList
+		}
+		if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+			errList := Errors{{FailedField: strings.Trim(field, `"`), Tag: "unknown"}}
+			return errList, errList
+		}
+		return nil, errs.Wrap(errs.ErrValidation, err, "malformed request body")
+	}
+
+	return Validate(dst)
+}
+
+// Validate runs the struct validation rules against v.
+// Returns a slice of validation errors and an Errors error if validation fails.
+func Validate(v any) ([]ValidationErrorResponse, error) {
+	err := validate.Struct(v)
 	if err == nil {
 		return nil, nil
 	}
 
-	var errors []ValidationErrorResponse
-	for _, err := range err.(validator.ValidationErrors) {
+	var fieldErrs validator.ValidationErrors
+	if !errors.As(err, &fieldErrs) {
+		return nil, err
+	}
+
+	var errList Errors
+	for _, err := range fieldErrs {
 		var val string
 		if err.Param() != "" {
 			val = err.Param()
 		}
-		errors = append(errors, ValidationErrorResponse{
+		errList = append(errList, ValidationErrorResponse{
 			FailedField: err.StructNamespace(),
 			Tag:         err.Tag(),
 			Value:       val,
 		})
 	}
 
-	return errors, fmt.Errorf("validation failed")
+	return errList, errList
 }